@@ -25,6 +25,7 @@ const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 type Report struct {
 	UserId               *wrappers.Int64Value `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
 	Expences             []*Expence           `protobuf:"bytes,2,rep,name=expences,proto3" json:"expences,omitempty"`
+	RequestId            *wrappers.Int64Value `protobuf:"bytes,3,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
 	XXX_unrecognized     []byte               `json:"-"`
 	XXX_sizecache        int32                `json:"-"`
@@ -69,12 +70,21 @@ func (m *Report) GetExpences() []*Expence {
 	return nil
 }
 
+func (m *Report) GetRequestId() *wrappers.Int64Value {
+	if m != nil {
+		return m.RequestId
+	}
+	return nil
+}
+
 type Expence struct {
 	Id                   *wrappers.Int64Value  `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
 	CategoryId           *wrappers.Int64Value  `protobuf:"bytes,2,opt,name=category_id,json=categoryId,proto3" json:"category_id,omitempty"`
 	CategoryName         *wrappers.StringValue `protobuf:"bytes,3,opt,name=category_name,json=categoryName,proto3" json:"category_name,omitempty"`
 	Ts                   *wrappers.Int64Value  `protobuf:"bytes,4,opt,name=ts,proto3" json:"ts,omitempty"`
 	Total                *wrappers.Int64Value  `protobuf:"bytes,5,opt,name=total,proto3" json:"total,omitempty"`
+	Note                 *wrappers.StringValue `protobuf:"bytes,6,opt,name=note,proto3" json:"note,omitempty"`
+	Tags                 []string              `protobuf:"bytes,7,rep,name=tags,proto3" json:"tags,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}              `json:"-"`
 	XXX_unrecognized     []byte                `json:"-"`
 	XXX_sizecache        int32                 `json:"-"`
@@ -140,6 +150,20 @@ func (m *Expence) GetTotal() *wrappers.Int64Value {
 	return nil
 }
 
+func (m *Expence) GetNote() *wrappers.StringValue {
+	if m != nil {
+		return m.Note
+	}
+	return nil
+}
+
+func (m *Expence) GetTags() []string {
+	if m != nil {
+		return m.Tags
+	}
+	return nil
+}
+
 type ReportResponse struct {
 	ResponseCode         *wrappers.Int64Value `protobuf:"bytes,1,opt,name=responseCode,proto3" json:"responseCode,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
@@ -188,27 +212,30 @@ func init() {
 func init() { proto.RegisterFile("api/report.proto", fileDescriptor_3897b7ab72282a4a) }
 
 var fileDescriptor_3897b7ab72282a4a = []byte{
-	// 347 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x91, 0x41, 0x6b, 0xe2, 0x40,
-	0x14, 0xc7, 0xd7, 0xb8, 0xea, 0xf2, 0xcc, 0x2e, 0xcb, 0xd0, 0x42, 0xb0, 0x52, 0x24, 0x27, 0x41,
-	0x9c, 0x10, 0x2b, 0x3d, 0x15, 0x4a, 0x2d, 0x3d, 0x78, 0x29, 0x34, 0x42, 0x0f, 0xbd, 0xc8, 0xc4,
-	0xbc, 0xa6, 0xc1, 0x38, 0x33, 0x9d, 0x19, 0xdb, 0xda, 0x2f, 0xd2, 0xaf, 0x5b, 0xcc, 0x18, 0xa9,
-	0xb4, 0xa0, 0xa7, 0x3c, 0xfe, 0xf9, 0xfd, 0x5e, 0xfe, 0xe4, 0xc1, 0x7f, 0x26, 0xb3, 0x40, 0xa1,
-	0x14, 0xca, 0x50, 0xa9, 0x84, 0x11, 0xc4, 0x2d, 0x1e, 0x53, 0x9b, 0xb5, 0x4e, 0x53, 0x21, 0xd2,
-	0x1c, 0x83, 0x22, 0x8c, 0x97, 0x8f, 0xc1, 0xab, 0x62, 0x52, 0xa2, 0xd2, 0x96, 0xf6, 0x9f, 0xa1,
-	0x1e, 0x15, 0x24, 0x19, 0x42, 0x63, 0xa9, 0x51, 0x4d, 0xb3, 0xc4, 0xab, 0x74, 0x2a, 0xdd, 0xe6,
-	0xe0, 0x84, 0x5a, 0x97, 0x96, 0x2e, 0x1d, 0x73, 0x73, 0x3e, 0xbc, 0x67, 0xf9, 0x12, 0xa3, 0xfa,
-	0x9a, 0x1d, 0x27, 0x24, 0x84, 0x3f, 0xf8, 0x26, 0x91, 0xcf, 0x50, 0x7b, 0x4e, 0xa7, 0xda, 0x6d,
-	0x0e, 0x8e, 0xe9, 0xd7, 0x02, 0xf4, 0xc6, 0xbe, 0x8d, 0xb6, 0x98, 0xff, 0xe1, 0x40, 0x63, 0x93,
-	0x92, 0x1e, 0x38, 0x87, 0x7d, 0xcf, 0xc9, 0x12, 0x72, 0x01, 0xcd, 0x19, 0x33, 0x98, 0x0a, 0xb5,
-	0x5a, 0xb7, 0x74, 0xf6, 0x5b, 0x50, 0xf2, 0xe3, 0x84, 0x5c, 0xc1, 0xdf, 0xad, 0xcd, 0xd9, 0x02,
-	0xbd, 0x6a, 0xe1, 0xb7, 0xbf, 0xf9, 0x13, 0xa3, 0x32, 0x9e, 0xda, 0x05, 0x6e, 0xa9, 0xdc, 0xb2,
-	0x45, 0xd1, 0xd6, 0x68, 0xef, 0xf7, 0x01, 0x6d, 0x8d, 0x26, 0x21, 0xd4, 0x8c, 0x30, 0x2c, 0xf7,
-	0x6a, 0xfb, 0x79, 0x4b, 0xfa, 0x77, 0xf0, 0xcf, 0x1e, 0x23, 0x42, 0x2d, 0x05, 0xd7, 0x48, 0x2e,
-	0xc1, 0x55, 0x9b, 0xf9, 0x5a, 0x24, 0x78, 0xc8, 0x9f, 0xda, 0x11, 0x06, 0x11, 0xb8, 0x76, 0xe5,
-	0x04, 0x79, 0x82, 0x8a, 0x8c, 0x00, 0xd6, 0xd3, 0xe6, 0xe6, 0x47, 0xbb, 0xb7, 0xb2, 0x69, 0xab,
-	0xfd, 0x53, 0x5a, 0x56, 0xf2, 0x7f, 0x8d, 0xfa, 0x0f, 0xbd, 0x34, 0x33, 0x39, 0x8b, 0xa9, 0x78,
-	0x17, 0x9c, 0x26, 0xf8, 0x12, 0xb0, 0xb9, 0xd0, 0xab, 0xf9, 0x53, 0x18, 0x0e, 0x03, 0x83, 0x39,
-	0xa6, 0x8a, 0x2d, 0xfa, 0xb1, 0x30, 0x01, 0x93, 0x59, 0x5c, 0x2f, 0xb6, 0x9d, 0x7d, 0x06, 0x00,
-	0x00, 0xff, 0xff, 0x1d, 0x55, 0xb4, 0x4e, 0xab, 0x02, 0x00, 0x00,
+	// 389 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x92, 0x51, 0x8b, 0xd3, 0x40,
+	0x14, 0x85, 0x6d, 0xda, 0x4d, 0xdd, 0xdb, 0x28, 0x32, 0x28, 0x84, 0x75, 0x91, 0x92, 0xa7, 0xc2,
+	0xb2, 0x13, 0x53, 0x8b, 0x0f, 0x22, 0x88, 0x2b, 0x3e, 0xe4, 0x45, 0x30, 0x0b, 0x3e, 0xf8, 0x52,
+	0x26, 0x9d, 0x6b, 0x0c, 0x4d, 0x67, 0xc6, 0x99, 0x89, 0x5a, 0xff, 0x93, 0x3f, 0xc9, 0xff, 0x22,
+	0xc9, 0x24, 0xc5, 0xe2, 0x42, 0xf3, 0x94, 0xcb, 0xc9, 0x77, 0xce, 0x3d, 0x0c, 0x17, 0x1e, 0x31,
+	0x55, 0xc6, 0x1a, 0x95, 0xd4, 0x96, 0x2a, 0x2d, 0xad, 0x24, 0x41, 0xfb, 0x59, 0x3b, 0xed, 0xe2,
+	0x59, 0x21, 0x65, 0x51, 0x61, 0xdc, 0x8a, 0x79, 0xfd, 0x25, 0xfe, 0xa1, 0x99, 0x52, 0xa8, 0x8d,
+	0xa3, 0xa3, 0xdf, 0x23, 0xf0, 0xb3, 0x16, 0x25, 0x2b, 0x98, 0xd6, 0x06, 0xf5, 0xba, 0xe4, 0xe1,
+	0x68, 0x3e, 0x5a, 0xcc, 0x96, 0x4f, 0xa9, 0x33, 0xd3, 0xde, 0x4c, 0x53, 0x61, 0x5f, 0xae, 0x3e,
+	0xb1, 0xaa, 0xc6, 0xcc, 0x6f, 0xd8, 0x94, 0x93, 0x04, 0xee, 0xe3, 0x4f, 0x85, 0x62, 0x83, 0x26,
+	0xf4, 0xe6, 0xe3, 0xc5, 0x6c, 0xf9, 0x84, 0xfe, 0xdb, 0x80, 0xbe, 0x77, 0x7f, 0xb3, 0x03, 0x46,
+	0x5e, 0x01, 0x68, 0xfc, 0x56, 0xa3, 0xb1, 0xcd, 0xae, 0xf1, 0xe9, 0x5d, 0xe7, 0x1d, 0x9e, 0xf2,
+	0xe8, 0x8f, 0x07, 0xd3, 0x2e, 0x91, 0x5c, 0x81, 0x37, 0xac, 0xab, 0x57, 0x72, 0xf2, 0x1a, 0x66,
+	0x1b, 0x66, 0xb1, 0x90, 0x7a, 0xdf, 0x6c, 0xf5, 0x4e, 0xbb, 0xa0, 0xe7, 0x53, 0x4e, 0xde, 0xc2,
+	0x83, 0x83, 0x5b, 0xb0, 0x1d, 0x76, 0xad, 0x2f, 0xff, 0xf3, 0xdf, 0x5a, 0x5d, 0x8a, 0xc2, 0x05,
+	0x04, 0xbd, 0xe5, 0x03, 0xdb, 0xb5, 0x6d, 0xad, 0x09, 0x27, 0x03, 0xda, 0x5a, 0x43, 0x12, 0x38,
+	0xb3, 0xd2, 0xb2, 0x2a, 0x3c, 0x3b, 0xcd, 0x3b, 0x92, 0x3c, 0x87, 0x89, 0x90, 0x16, 0x43, 0x7f,
+	0x40, 0xb3, 0x96, 0x24, 0x04, 0x26, 0x96, 0x15, 0x26, 0x9c, 0xce, 0xc7, 0x8b, 0xf3, 0xac, 0x9d,
+	0xa3, 0x8f, 0xf0, 0xd0, 0x9d, 0x43, 0x86, 0x46, 0x49, 0x61, 0x90, 0xbc, 0x81, 0x40, 0x77, 0xf3,
+	0x3b, 0xc9, 0x71, 0xc8, 0x7b, 0x1f, 0x19, 0x96, 0x19, 0x04, 0x2e, 0xf2, 0x16, 0x05, 0x47, 0x4d,
+	0x6e, 0x00, 0x9a, 0xa9, 0xbb, 0xba, 0xc7, 0xc7, 0xd7, 0xe2, 0xd4, 0x8b, 0xcb, 0xbb, 0xd4, 0xbe,
+	0x52, 0x74, 0xef, 0xe6, 0xfa, 0xf3, 0x55, 0x51, 0xda, 0x8a, 0xe5, 0x54, 0xfe, 0x92, 0x82, 0x72,
+	0xfc, 0x1e, 0xb3, 0xad, 0x34, 0xfb, 0xed, 0xd7, 0x24, 0x59, 0xc5, 0x16, 0x2b, 0x2c, 0x34, 0xdb,
+	0x5d, 0xe7, 0xd2, 0xc6, 0x4c, 0x95, 0xb9, 0xdf, 0xa6, 0xbd, 0xf8, 0x3b, 0x00, 0xeb, 0x95, 0x5a,
+	0xfd, 0x2e, 0x03, 0x00, 0x00,
 }
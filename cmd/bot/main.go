@@ -7,6 +7,8 @@ import (
 	"os/signal"
 	"sync"
 	"syscall"
+	// база часовых поясов для /timezone, если в образе нет системной
+	_ "time/tzdata"
 
 	"github.com/go-redis/redis/v8"
 	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/clients/tg"
@@ -18,6 +20,7 @@ import (
 	exchangeratefetcherservice "gitlab.ozon.dev/akosykh114/telegram-bot/internal/services/exchange_rate_fetcher_service"
 	grpcserver "gitlab.ozon.dev/akosykh114/telegram-bot/internal/services/grpc_server"
 	limitupdateservice "gitlab.ozon.dev/akosykh114/telegram-bot/internal/services/limit_update_service"
+	recurringexpenceservice "gitlab.ozon.dev/akosykh114/telegram-bot/internal/services/recurring_expence_service"
 	reportrequestproducer "gitlab.ozon.dev/akosykh114/telegram-bot/internal/services/report_request_producer"
 	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/storage"
 	"go.uber.org/zap"
@@ -53,6 +56,10 @@ func main() {
 	limitService, monthLimitChan := limitupdateservice.New()
 	limitService.StartService(ctx, &wg)
 
+	// Запуск сервиса списания регулярных трат
+	recurringService, recurringChan := recurringexpenceservice.New()
+	recurringService.StartService(ctx, &wg)
+
 	// Запуск gRPC сервера
 	grpcServer := grpcserver.New()
 	err = grpcServer.StartService(ctx, &wg)
@@ -76,6 +83,9 @@ func main() {
 	categoriesDB := database.NewCategoriesDB(db)
 	currenciesDB := database.NewCurrenciesDB(db)
 	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
 
 	// Инициализация клиента Redis
 	rdb := redis.NewClient(&redis.Options{
@@ -86,12 +96,15 @@ func main() {
 	reportDB := database.NewReportCacheDb(rdb)
 
 	// Инициализация хранилища
-	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, reportDB, reportRequestProducer, grpcServer)
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, reportRequestProducer, grpcServer)
 	storageModel.WaitNewExchangeRates(ctx, &wg, exchangeChan)
 	storageModel.WaitNewMonthLimit(ctx, &wg, monthLimitChan)
 
 	// Запуск бота
 	msgModel := messages.New(tgClient, storageModel)
+	msgModel.SetImportFormats(config.ImportFormats())
+	msgModel.SetCurrencies(config.AvailableCurrencies())
+	msgModel.WaitRecurringExpences(ctx, &wg, recurringChan)
 	tgClient.ListenUpdates(ctx, &wg, msgModel)
 
 	wg.Wait()
@@ -7,6 +7,7 @@ import (
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -30,6 +31,7 @@ var (
 	KafkaConsumerGroup = "report-consumer-group"
 	BrokersList        = []string{"localhost:9092"}
 	Assignor           = "range"
+	RequestIDHeader    = "request_id"
 )
 
 var ExpencesDB *database.ExpencesDB
@@ -107,12 +109,16 @@ func processMessage(ctx context.Context, msg *sarama.ConsumerMessage) error {
 	if err != nil {
 		return err
 	}
-	ts, err := strconv.ParseInt(string(msg.Value), 10, 64)
+	startTs, endTs, err := parseReportPeriod(string(msg.Value))
+	if err != nil {
+		return err
+	}
+	requestID, err := getRequestID(msg)
 	if err != nil {
 		return err
 	}
 
-	rv, err := ExpencesDB.GetUserExpences(ctx, domain.User{UserID: userID}, time.Unix(ts, 0))
+	rv, err := ExpencesDB.GetUserExpences(ctx, domain.User{UserID: userID}, startTs, endTs)
 	if err != nil {
 		return err
 	}
@@ -120,7 +126,7 @@ func processMessage(ctx context.Context, msg *sarama.ConsumerMessage) error {
 
 	logger.Info(fmt.Sprintf("Successful to read message: %s", string(msg.Value)))
 
-	err = SendMessage(ctx, rv)
+	err = SendMessage(ctx, domain.ReportRequest{RequestID: requestID, UserID: userID}, rv)
 	if err != nil {
 		return err
 	}
@@ -128,17 +134,40 @@ func processMessage(ctx context.Context, msg *sarama.ConsumerMessage) error {
 	return nil
 }
 
+// период отчёта "<начало>,<конец>" в unix-времени
+func parseReportPeriod(value string) (time.Time, time.Time, error) {
+	bounds := strings.Split(value, ",")
+	if len(bounds) != 2 {
+		return time.Time{}, time.Time{}, fmt.Errorf("wrong report period: %s", value)
+	}
+	startTs, err := strconv.ParseInt(bounds[0], 10, 64)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	endTs, err := strconv.ParseInt(bounds[1], 10, 64)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return time.Unix(startTs, 0), time.Unix(endTs, 0), nil
+}
+
+func getRequestID(msg *sarama.ConsumerMessage) (int64, error) {
+	for _, h := range msg.Headers {
+		if h != nil && string(h.Key) == RequestIDHeader {
+			return strconv.ParseInt(string(h.Value), 10, 64)
+		}
+	}
+	return 0, fmt.Errorf("message has no %s header", RequestIDHeader)
+}
+
 const (
 	timestampFormat = time.StampNano // "Jan _2 15:04:05.000"
 )
 
-func CreateMessage(expences []domain.Expence) *pb.Report {
-	var userID int64 = -1
-	if len(expences) > 0 {
-		userID = expences[0].UserID
-	}
+func CreateMessage(request domain.ReportRequest, expences []domain.Expence) *pb.Report {
 	msg := &pb.Report{
-		UserId: wrapperspb.Int64(userID),
+		UserId:    wrapperspb.Int64(request.UserID),
+		RequestId: wrapperspb.Int64(request.RequestID),
 	}
 
 	var expencesField []*pb.Expence
@@ -149,6 +178,8 @@ func CreateMessage(expences []domain.Expence) *pb.Report {
 			CategoryName: wrapperspb.String(v.CategoryName),
 			Ts:           wrapperspb.Int64(v.Timestamp.Unix()),
 			Total:        wrapperspb.Int64(v.Total),
+			Note:         wrapperspb.String(v.Note),
+			Tags:         v.Tags,
 		}
 		expencesField = append(expencesField, e)
 	}
@@ -157,12 +188,12 @@ func CreateMessage(expences []domain.Expence) *pb.Report {
 	return msg
 }
 
-func SendMessage(ctx context.Context, expences []domain.Expence) error {
+func SendMessage(ctx context.Context, request domain.ReportRequest, expences []domain.Expence) error {
 	md := metadata.Pairs("timestamp", time.Now().Format(timestampFormat))
 	ctx = metadata.NewOutgoingContext(ctx, md)
 
 	var header, trailer metadata.MD
-	msg := CreateMessage(expences)
+	msg := CreateMessage(request, expences)
 	_, err := grpcReportClient.SendReport(ctx, msg, grpc.Header(&header), grpc.Trailer(&trailer))
 	if err != nil {
 		return err
@@ -0,0 +1,168 @@
+package charts
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+)
+
+const (
+	Width  = 640
+	Height = 640
+
+	padding = 24
+
+	// верхняя половина - столбцы по категориям, нижняя - траты по дням
+	barsBottom = Height/2 - padding/2
+	lineTop    = Height/2 + padding/2
+)
+
+// Palette - цвета категорий; в подписи к картинке им соответствуют эмодзи из PaletteEmoji
+var Palette = []color.RGBA{
+	{R: 0xdd, G: 0x2e, B: 0x44, A: 0xff},
+	{R: 0xf4, G: 0x90, B: 0x0c, A: 0xff},
+	{R: 0xfd, G: 0xcb, B: 0x58, A: 0xff},
+	{R: 0x78, G: 0xb1, B: 0x59, A: 0xff},
+	{R: 0x55, G: 0xac, B: 0xee, A: 0xff},
+	{R: 0xaa, G: 0x8e, B: 0xd6, A: 0xff},
+	{R: 0xc1, G: 0x69, B: 0x4f, A: 0xff},
+	{R: 0x31, G: 0x37, B: 0x3d, A: 0xff},
+}
+
+var PaletteEmoji = []string{"🟥", "🟧", "🟨", "🟩", "🟦", "🟪", "🟫", "⬛"}
+
+var (
+	background = color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	axis       = color.RGBA{R: 0x99, G: 0x99, B: 0x99, A: 0xff}
+	line       = color.RGBA{R: 0x55, G: 0xac, B: 0xee, A: 0xff}
+)
+
+// DrawExpences - столбцы categoryTotals (не больше len(Palette), цвет по порядку)
+// и ломаная дневных сумм dailyTotals
+func DrawExpences(categoryTotals []int64, dailyTotals []int64) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, Width, Height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: background}, image.Point{}, draw.Src)
+
+	drawBars(img, categoryTotals)
+	drawLine(img, dailyTotals)
+
+	return img
+}
+
+func EncodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func drawBars(img *image.RGBA, totals []int64) {
+	if len(totals) > len(Palette) {
+		totals = totals[:len(Palette)]
+	}
+
+	fillRect(img, image.Rect(padding, barsBottom, Width-padding, barsBottom+1), axis)
+
+	maxTotal := maxOf(totals)
+	if len(totals) == 0 || maxTotal <= 0 {
+		return
+	}
+
+	slot := (Width - 2*padding) / len(totals)
+	gap := slot / 5
+	chartHeight := barsBottom - padding
+	for i, total := range totals {
+		barHeight := int(total * int64(chartHeight) / maxTotal)
+		x := padding + i*slot + gap/2
+		fillRect(img, image.Rect(x, barsBottom-barHeight, x+slot-gap, barsBottom), Palette[i])
+	}
+}
+
+func drawLine(img *image.RGBA, totals []int64) {
+	bottom := Height - padding
+
+	fillRect(img, image.Rect(padding, bottom, Width-padding, bottom+1), axis)
+	fillRect(img, image.Rect(padding, lineTop, padding+1, bottom), axis)
+
+	maxTotal := maxOf(totals)
+	if len(totals) == 0 || maxTotal <= 0 {
+		return
+	}
+
+	chartWidth := Width - 2*padding
+	chartHeight := bottom - lineTop
+	points := make([]image.Point, 0, len(totals))
+	for i, total := range totals {
+		x := padding + chartWidth/2
+		if len(totals) > 1 {
+			x = padding + i*chartWidth/(len(totals)-1)
+		}
+		y := bottom - int(total*int64(chartHeight)/maxTotal)
+		points = append(points, image.Pt(x, y))
+	}
+
+	for i := 1; i < len(points); i++ {
+		drawSegment(img, points[i-1], points[i], line)
+	}
+	for _, p := range points {
+		fillRect(img, image.Rect(p.X-3, p.Y-3, p.X+4, p.Y+4), line)
+	}
+}
+
+// drawSegment - отрезок толщиной 3 пикселя по алгоритму Брезенхэма
+func drawSegment(img *image.RGBA, from image.Point, to image.Point, c color.RGBA) {
+	dx, dy := abs(to.X-from.X), -abs(to.Y-from.Y)
+	sx, sy := sign(to.X-from.X), sign(to.Y-from.Y)
+	errXY := dx + dy
+
+	p := from
+	for {
+		fillRect(img, image.Rect(p.X-1, p.Y-1, p.X+2, p.Y+2), c)
+		if p == to {
+			return
+		}
+		e2 := 2 * errXY
+		if e2 >= dy {
+			errXY += dy
+			p.X += sx
+		}
+		if e2 <= dx {
+			errXY += dx
+			p.Y += sy
+		}
+	}
+}
+
+func fillRect(img *image.RGBA, r image.Rectangle, c color.RGBA) {
+	draw.Draw(img, r, &image.Uniform{C: c}, image.Point{}, draw.Src)
+}
+
+func maxOf(values []int64) int64 {
+	var rv int64
+	for _, v := range values {
+		if v > rv {
+			rv = v
+		}
+	}
+	return rv
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func sign(v int) int {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+	return 0
+}
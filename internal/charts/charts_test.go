@@ -0,0 +1,72 @@
+package charts
+
+import (
+	"bytes"
+	"flag"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// go test ./internal/charts -update - перегенерация эталонных картинок
+var update = flag.Bool("update", false, "update golden images")
+
+func assertGoldenImage(t *testing.T, name string, img image.Image) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name)
+	if *update {
+		data, err := EncodePNG(img)
+		assert.NoError(t, err)
+		assert.NoError(t, os.WriteFile(path, data, 0o644))
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("golden image %s not found, run with -update: %s", path, err)
+	}
+	golden, err := png.Decode(bytes.NewReader(data))
+	assert.NoError(t, err)
+
+	assert.Equal(t, golden.Bounds(), img.Bounds())
+	for y := img.Bounds().Min.Y; y < img.Bounds().Max.Y; y++ {
+		for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
+			if !assert.Equal(t, golden.At(x, y), img.At(x, y), "pixel (%d, %d)", x, y) {
+				return
+			}
+		}
+	}
+}
+
+func Test_DrawExpences(t *testing.T) {
+	img := DrawExpences(
+		[]int64{100000, 75000, 25000},
+		[]int64{10000, 0, 35000, 20000, 0, 0, 80000, 15000, 40000, 25000},
+	)
+	assertGoldenImage(t, "expences.png", img)
+}
+
+func Test_DrawExpences_SingleDay(t *testing.T) {
+	img := DrawExpences([]int64{5000}, []int64{5000})
+	assertGoldenImage(t, "expences_single_day.png", img)
+}
+
+func Test_DrawExpences_Empty(t *testing.T) {
+	img := DrawExpences(nil, nil)
+	assertGoldenImage(t, "expences_empty.png", img)
+}
+
+func Test_EncodePNG_ShouldDecodeBack(t *testing.T) {
+	img := DrawExpences([]int64{1, 2}, []int64{1, 2})
+
+	data, err := EncodePNG(img)
+	assert.NoError(t, err)
+
+	decoded, err := png.Decode(bytes.NewReader(data))
+	assert.NoError(t, err)
+	assert.Equal(t, img.Bounds(), decoded.Bounds())
+}
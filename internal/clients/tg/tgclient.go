@@ -2,6 +2,10 @@ package tg
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
 	"sync"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -9,6 +13,7 @@ import (
 	"github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
 	"github.com/uber/jaeger-client-go"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/domain"
 	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/logger"
 	metrics "gitlab.ozon.dev/akosykh114/telegram-bot/internal/metrics"
 	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/model/messages"
@@ -32,7 +37,61 @@ func New(token string) (*Client, error) {
 }
 
 func (c *Client) SendMessage(text string, userID int64) error {
-	_, err := c.client.Send(tgbotapi.NewMessage(userID, text))
+	return c.SendFormattedMessage(text, userID, "")
+}
+
+// SendFormattedMessage - отправка сообщения с разметкой parseMode (tgbotapi.ModeHTML, tgbotapi.ModeMarkdownV2)
+func (c *Client) SendFormattedMessage(text string, userID int64, parseMode string) error {
+	msg := tgbotapi.NewMessage(userID, text)
+	msg.ParseMode = parseMode
+
+	_, err := c.client.Send(msg)
+	if err != nil {
+		return errors.Wrap(err, "client.Send")
+	}
+	return nil
+}
+
+// ширина inline-клавиатуры в кнопках
+const buttonsPerRow = 3
+
+func (c *Client) SendMessageWithButtons(text string, userID int64, buttons []domain.InlineButton) error {
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(buttons)/buttonsPerRow+1)
+	for i, button := range buttons {
+		if i%buttonsPerRow == 0 {
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow())
+		}
+		rows[len(rows)-1] = append(rows[len(rows)-1], tgbotapi.NewInlineKeyboardButtonData(button.Text, button.Data))
+	}
+
+	msg := tgbotapi.NewMessage(userID, text)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+
+	_, err := c.client.Send(msg)
+	if err != nil {
+		return errors.Wrap(err, "client.Send")
+	}
+	return nil
+}
+
+// SendPhoto - отправка PNG-картинки с подписью
+func (c *Client) SendPhoto(image []byte, caption string, userID int64) error {
+	msg := tgbotapi.NewPhoto(userID, tgbotapi.FileBytes{Name: "chart.png", Bytes: image})
+	msg.Caption = caption
+
+	_, err := c.client.Send(msg)
+	if err != nil {
+		return errors.Wrap(err, "client.Send")
+	}
+	return nil
+}
+
+// SendDocument - отправка файла fileName с подписью
+func (c *Client) SendDocument(data []byte, fileName string, caption string, userID int64) error {
+	msg := tgbotapi.NewDocument(userID, tgbotapi.FileBytes{Name: fileName, Bytes: data})
+	msg.Caption = caption
+
+	_, err := c.client.Send(msg)
 	if err != nil {
 		return errors.Wrap(err, "client.Send")
 	}
@@ -59,7 +118,7 @@ func (c *Client) ListenUpdates(ctx context.Context, wg *sync.WaitGroup, msgModel
 		for {
 			select {
 			case update := <-updates:
-				processMessage(ctx, update, msgModel)
+				c.processMessage(ctx, update, msgModel)
 
 			case <-ctx.Done():
 				logger.Info("<Bot>: Stopping listening to messages...")
@@ -69,7 +128,7 @@ func (c *Client) ListenUpdates(ctx context.Context, wg *sync.WaitGroup, msgModel
 	}()
 }
 
-func processMessage(ctx context.Context, update tgbotapi.Update, msgModel *messages.Model) {
+func (c *Client) processMessage(ctx context.Context, update tgbotapi.Update, msgModel *messages.Model) {
 	span, ctx := opentracing.StartSpanFromContext(ctx, "process_message")
 	defer span.Finish()
 
@@ -77,7 +136,13 @@ func processMessage(ctx context.Context, update tgbotapi.Update, msgModel *messa
 		logger.Info("trace-info", zap.String("id", sc.TraceID().String()))
 	}
 
-	if update.Message == nil {
+	if update.CallbackQuery != nil {
+		c.processCallback(ctx, update.CallbackQuery, msgModel)
+		return
+	}
+
+	// сообщения от имени канала или анонимного администратора группы без отправителя
+	if update.Message == nil || update.Message.From == nil {
 		return
 	}
 
@@ -94,11 +159,22 @@ func processMessage(ctx context.Context, update tgbotapi.Update, msgModel *messa
 		zap.String("text", update.Message.Text),
 	)
 	msg := messages.Message{
-		UserID: update.Message.From.ID,
+		ChatID:       update.Message.Chat.ID,
+		UserID:       update.Message.From.ID,
+		UserName:     userName(update.Message.From),
+		LanguageCode: update.Message.From.LanguageCode,
 	}
 	var err error
 
 	switch {
+	case update.Message.Document != nil:
+		err = c.processDocument(ctx, update.Message.Document, msg, msgModel)
+	case update.Message.Location != nil:
+		err = msgModel.IncomingLocationMessage(ctx, messages.LocationMessage{
+			Message:   msg,
+			Latitude:  update.Message.Location.Latitude,
+			Longitude: update.Message.Location.Longitude,
+		})
 	case update.Message.IsCommand():
 		func() {
 			storageCtx, cancel := context.WithCancel(ctx)
@@ -111,7 +187,7 @@ func processMessage(ctx context.Context, update tgbotapi.Update, msgModel *messa
 			})
 		}()
 	default:
-		err = msgModel.IncomingPlainTextMessage(messages.PlainTextMessage{
+		err = msgModel.IncomingPlainTextMessage(ctx, messages.PlainTextMessage{
 			Message: msg,
 			Text:    update.Message.Text,
 		})
@@ -121,3 +197,86 @@ func processMessage(ctx context.Context, update tgbotapi.Update, msgModel *messa
 		logger.Error("error processing message:", zap.Error(err))
 	}
 }
+
+func (c *Client) processDocument(ctx context.Context, document *tgbotapi.Document, msg messages.Message, msgModel *messages.Model) error {
+	// слишком большой файл не скачивается, ответ на языке пользователя отправит модель
+	var data []byte
+	if document.FileSize <= messages.MaxDocumentSize {
+		var err error
+		data, err = c.downloadFile(ctx, document.FileID)
+		if err != nil {
+			return errors.Wrap(err, "downloadFile")
+		}
+	}
+
+	return msgModel.IncomingDocumentMessage(ctx, messages.DocumentMessage{
+		Message:  msg,
+		FileName: document.FileName,
+		FileSize: document.FileSize,
+		Data:     data,
+	})
+}
+
+func (c *Client) downloadFile(ctx context.Context, fileID string) ([]byte, error) {
+	url, err := c.client.GetFileDirectURL(fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download file: status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, messages.MaxDocumentSize))
+}
+
+func (c *Client) processCallback(ctx context.Context, query *tgbotapi.CallbackQuery, msgModel *messages.Model) {
+	metrics.MessageReceived.Inc()
+
+	logger.Info(
+		"callback-info",
+		zap.String("username", query.From.UserName),
+		zap.String("data", query.Data),
+	)
+
+	// подтверждение нажатия, иначе клиент телеграма показывает загрузку на кнопке
+	if _, err := c.client.Request(tgbotapi.NewCallback(query.ID, "")); err != nil {
+		logger.Warn("callback answer error:", zap.Error(err))
+	}
+
+	// кнопка нажата под сообщением бота, ответ отправляется в тот же чат
+	var chatID int64
+	if query.Message != nil {
+		chatID = query.Message.Chat.ID
+	}
+
+	err := msgModel.IncomingCallbackMessage(ctx, messages.CallbackMessage{
+		Message: messages.Message{
+			ChatID:       chatID,
+			UserID:       query.From.ID,
+			UserName:     userName(query.From),
+			LanguageCode: query.From.LanguageCode,
+		},
+		Data: query.Data,
+	})
+	if err != nil {
+		logger.Error("error processing callback:", zap.Error(err))
+	}
+}
+
+// userName - имя пользователя для других участников бюджета
+func userName(user *tgbotapi.User) string {
+	if user.UserName != "" {
+		return "@" + user.UserName
+	}
+	return strings.TrimSpace(user.FirstName + " " + user.LastName)
+}
@@ -1,5 +1,38 @@
 package common
 
-type LimitExceededError struct{}
+import (
+	"errors"
+	"fmt"
+)
 
-func (e *LimitExceededError) Error() string { return "Month limit exceeded" }
+// LimitExceededError - превышен лимит месяца пользователя, либо лимит категории Category
+type LimitExceededError struct {
+	Category string
+}
+
+func (e *LimitExceededError) Error() string {
+	if e.Category != "" {
+		return fmt.Sprintf("Month limit for category %s exceeded", e.Category)
+	}
+	return "Month limit exceeded"
+}
+
+var ErrExpenceNotFound = errors.New("expence not found")
+
+var ErrCategoryNotFound = errors.New("category not found")
+
+var ErrCategoryExists = errors.New("category already exists")
+
+var ErrCategoryNotEmpty = errors.New("category has expences")
+
+var ErrCategoryHasRecurring = errors.New("category has recurring expences")
+
+var ErrCurrencyNotFound = errors.New("currency not found")
+
+var ErrRecurringExpenceNotFound = errors.New("recurring expence not found")
+
+var ErrBudgetNotFound = errors.New("budget not found")
+
+var ErrBudgetHasMembers = errors.New("budget has members")
+
+var ErrNothingToUndo = errors.New("nothing to undo")
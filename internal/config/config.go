@@ -12,12 +12,25 @@ import (
 const configFile = "data/config.yaml"
 
 type Config struct {
-	Token                        string   `yaml:"token"`
-	CurrencyApiURL               string   `yaml:"currency_api_url"`
-	ExchangeServiceFetchInterval int      `yaml:"exchange_service_fetch_interval"`
-	RequestTimeout               int      `yaml:"request_timeout"`
-	BaseCurrency                 string   `yaml:"base_currency"`
-	AvailableCurrencies          []string `yaml:"currencies"`
+	Token                        string         `yaml:"token"`
+	CurrencyApiURL               string         `yaml:"currency_api_url"`
+	ExchangeServiceFetchInterval int            `yaml:"exchange_service_fetch_interval"`
+	RequestTimeout               int            `yaml:"request_timeout"`
+	BaseCurrency                 string         `yaml:"base_currency"`
+	AvailableCurrencies          []string       `yaml:"currencies"`
+	ImportFormats                []ImportFormat `yaml:"import_formats"`
+}
+
+// ImportFormat - столбцы CSV-выписки банка, значения - заголовки столбцов
+type ImportFormat struct {
+	Name              string `yaml:"name"`
+	Delimiter         string `yaml:"delimiter"`
+	DateColumn        string `yaml:"date_column"`
+	DateFormat        string `yaml:"date_format"`
+	AmountColumn      string `yaml:"amount_column"`
+	CategoryColumn    string `yaml:"category_column"`
+	DescriptionColumn string `yaml:"description_column"`
+	NegativeExpences  bool   `yaml:"negative_expences"`
 }
 
 type Service struct {
@@ -73,3 +86,24 @@ func (s *Service) AvailableCurrencies() []domain.Currency {
 func (s *Service) BaseCurrency() string {
 	return s.config.BaseCurrency
 }
+
+func (s *Service) ImportFormats() []domain.ImportFormat {
+	rv := make([]domain.ImportFormat, 0, len(s.config.ImportFormats))
+	for _, v := range s.config.ImportFormats {
+		delimiter := ','
+		if v.Delimiter != "" {
+			delimiter = []rune(v.Delimiter)[0]
+		}
+		rv = append(rv, domain.ImportFormat{
+			Name:              v.Name,
+			Delimiter:         delimiter,
+			DateColumn:        v.DateColumn,
+			DateFormat:        v.DateFormat,
+			AmountColumn:      v.AmountColumn,
+			CategoryColumn:    v.CategoryColumn,
+			DescriptionColumn: v.DescriptionColumn,
+			NegativeExpences:  v.NegativeExpences,
+		})
+	}
+	return rv
+}
@@ -18,11 +18,11 @@ func NewReportCacheDb(rdb *redis.Client) *ReportCacheDb {
 	return &ReportCacheDb{rdb}
 }
 
-func (db *ReportCacheDb) GetUserExpences(ctx context.Context, user domain.User, limitTs time.Time) (map[string]int64, error) {
+func (db *ReportCacheDb) GetUserExpences(ctx context.Context, user domain.User, startTs time.Time, endTs time.Time) (map[string]int64, error) {
 	span, ctx := opentracing.StartSpanFromContext(ctx, "get_user_expences_cache")
 	defer span.Finish()
 
-	key := strconv.FormatInt(user.UserID, 10) + strconv.FormatInt(limitTs.Unix(), 10)
+	key := reportCacheKey(user, startTs, endTs)
 	cmd := db.rdb.HGetAll(ctx, key)
 	if cmd.Err() != nil {
 		return nil, cmd.Err()
@@ -44,11 +44,11 @@ func (db *ReportCacheDb) GetUserExpences(ctx context.Context, user domain.User,
 	return rv, nil
 }
 
-func (db *ReportCacheDb) SetUserExpences(ctx context.Context, user domain.User, expencesMap map[string]string, limitTs time.Time) error {
+func (db *ReportCacheDb) SetUserExpences(ctx context.Context, user domain.User, expencesMap map[string]string, startTs time.Time, endTs time.Time) error {
 	span, ctx := opentracing.StartSpanFromContext(ctx, "set_user_expences_cache")
 	defer span.Finish()
 
-	key := strconv.FormatInt(user.UserID, 10) + strconv.FormatInt(limitTs.Unix(), 10)
+	key := reportCacheKey(user, startTs, endTs)
 	cmd := db.rdb.HSet(ctx, key, expencesMap)
 	if cmd.Err() != nil {
 		return cmd.Err()
@@ -62,6 +62,11 @@ func (db *ReportCacheDb) SetUserExpences(ctx context.Context, user domain.User,
 	return nil
 }
 
+// ключ отчёта начинается с id пользователя, по этому префиксу DeleteUserReports сбрасывает кэш
+func reportCacheKey(user domain.User, startTs time.Time, endTs time.Time) string {
+	return strconv.FormatInt(user.UserID, 10) + strconv.FormatInt(startTs.Unix(), 10) + "_" + strconv.FormatInt(endTs.Unix(), 10)
+}
+
 func (db *ReportCacheDb) DeleteUserReports(ctx context.Context, user domain.User) error {
 	span, ctx := opentracing.StartSpanFromContext(ctx, "clear_report_cache")
 	defer span.Finish()
@@ -6,7 +6,9 @@ import (
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/opentracing/opentracing-go"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/common"
 	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/domain"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/helpers"
 )
 
 type CategoriesDB struct {
@@ -40,13 +42,97 @@ func (db *CategoriesDB) IsCategoryExists(ctx context.Context, category domain.Ex
 	return categoryID, nil
 }
 
-func (db *CategoriesDB) AddCategory(ctx context.Context, category domain.ExpenceCategory) error {
+// GetCategory - название категории бюджета по id; common.ErrCategoryNotFound - в бюджете такой категории нет
+func (db *CategoriesDB) GetCategory(ctx context.Context, category domain.ExpenceCategory) (domain.ExpenceCategory, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_category_db")
+	defer span.Finish()
+
+	builder := sq.Select("name").From("expence_category").Where(sq.Eq{
+		"id":      category.ID,
+		"user_id": category.UserID,
+	}).PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return category, err
+	}
+
+	err = db.db.QueryRowContext(ctx, query, args...).Scan(&category.Name)
+	if err == sql.ErrNoRows {
+		return category, common.ErrCategoryNotFound
+	}
+
+	return category, err
+}
+
+// AddCategory - добавление категории; возвращает id категории
+func (db *CategoriesDB) AddCategory(ctx context.Context, category domain.ExpenceCategory) (int64, error) {
 	span, ctx := opentracing.StartSpanFromContext(ctx, "add_category_db")
 	defer span.Finish()
 
-	builder := sq.Insert("expence_category").Columns("user_id", "name").Values(category.UserID, category.Name).PlaceholderFormat(sq.Dollar)
+	builder := sq.Insert("expence_category").Columns("user_id", "name").Values(category.UserID, category.Name).Suffix("RETURNING id").PlaceholderFormat(sq.Dollar)
+	query, args, err := builder.ToSql()
+
+	if err != nil {
+		return 0, err
+	}
+
+	var id int64
+	err = db.db.QueryRowContext(ctx, query, args...).Scan(&id)
+
+	return id, err
+}
+
+func (db *CategoriesDB) GetUserCategories(ctx context.Context, user domain.User) ([]domain.ExpenceCategory, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_user_categories_db")
+	defer span.Finish()
+
+	var categories []domain.ExpenceCategory = nil
+
+	builder := sq.Select("id", "name").From("expence_category").Where(sq.Eq{
+		"user_id": user.UserID,
+	}).OrderBy("name").PlaceholderFormat(sq.Dollar)
+
 	query, args, err := builder.ToSql()
+	if err != nil {
+		return categories, err
+	}
+
+	rows, err := db.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return categories, err
+	}
+	defer rows.Close()
+
+	categories = make([]domain.ExpenceCategory, 0)
+	for rows.Next() {
+		category := domain.ExpenceCategory{UserID: user.UserID}
+		if err := rows.Scan(
+			&category.ID,
+			&category.Name,
+		); err != nil {
+			return categories, err
+		}
+		categories = append(categories, category)
+	}
 
+	if err = rows.Err(); err != nil {
+		return categories, err
+	}
+
+	return categories, nil
+}
+
+func (db *CategoriesDB) RenameCategory(ctx context.Context, category domain.ExpenceCategory) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "rename_category_db")
+	defer span.Finish()
+
+	builder := sq.Update("expence_category").Set("name", category.Name).Where(sq.Eq{
+		"id":      category.ID,
+		"user_id": category.UserID,
+	}).PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
 	if err != nil {
 		return err
 	}
@@ -55,3 +141,158 @@ func (db *CategoriesDB) AddCategory(ctx context.Context, category domain.Expence
 
 	return err
 }
+
+// MergeCategory - перенос трат, регулярных трат и правил импорта категории from в категорию into и удаление from
+func (db *CategoriesDB) MergeCategory(ctx context.Context, from domain.ExpenceCategory, into domain.ExpenceCategory) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "merge_category_db")
+	defer span.Finish()
+
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:all
+
+	// траты текущего месяца лимита переходят в лимит категории into
+	_, err = tx.ExecContext(ctx, "UPDATE expence_category SET current_month_limit = current_month_limit - (SELECT COALESCE(SUM(total), 0) FROM expences WHERE category_id = $1 AND ts >= COALESCE((SELECT users.limit_month FROM users JOIN expence_category ON expence_category.user_id = users.id WHERE expence_category.id = $3), $2)) WHERE id = $3 AND current_month_limit IS NOT NULL;", from.ID, helpers.GetStartOfCurrentMonth(), into.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE expences SET category_id = $1 WHERE category_id = $2 AND user_id = $3;", into.ID, from.ID, from.UserID)
+	if err != nil {
+		return err
+	}
+
+	// расписания удалились бы вместе с категорией каскадно
+	_, err = tx.ExecContext(ctx, "UPDATE recurring_expences SET category_id = $1 WHERE category_id = $2 AND user_id = $3;", into.ID, from.ID, from.UserID)
+	if err != nil {
+		return err
+	}
+
+	// ключевые слова, которые уже есть у into, удаляются вместе с from
+	_, err = tx.ExecContext(ctx, "UPDATE category_keywords SET category_id = $1 WHERE category_id = $2 AND keyword NOT IN (SELECT keyword FROM category_keywords WHERE category_id = $1);", into.ID, from.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM expence_category WHERE id = $1 AND user_id = $2;", from.ID, from.UserID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteCategory - удаление категории без трат и регулярных трат; траты удалились бы каскадно мимо лимита месяца,
+// а расписания - молча
+func (db *CategoriesDB) DeleteCategory(ctx context.Context, category domain.ExpenceCategory) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "delete_category_db")
+	defer span.Finish()
+
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:all
+
+	var hasExpences bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM expences WHERE category_id = $1);", category.ID).Scan(&hasExpences)
+	if err != nil {
+		return err
+	}
+	if hasExpences {
+		return common.ErrCategoryNotEmpty
+	}
+
+	var hasRecurring bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM recurring_expences WHERE category_id = $1);", category.ID).Scan(&hasRecurring)
+	if err != nil {
+		return err
+	}
+	if hasRecurring {
+		return common.ErrCategoryHasRecurring
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM expence_category WHERE id = $1 AND user_id = $2;", category.ID, category.UserID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SetCategoryLimit - лимит категории на месяц; в текущем месяце уже учитываются сделанные траты
+func (db *CategoriesDB) SetCategoryLimit(ctx context.Context, category domain.ExpenceCategory) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "set_category_limit_db")
+	defer span.Finish()
+
+	_, err := db.db.ExecContext(ctx, "UPDATE expence_category SET default_month_limit = $1, current_month_limit = $1 - (SELECT COALESCE(SUM(total), 0) FROM expences WHERE category_id = $2 AND ts >= COALESCE((SELECT limit_month FROM users WHERE id = $4), $3)) WHERE id = $2 AND user_id = $4;", category.DefaultMonthLimit, category.ID, helpers.GetStartOfCurrentMonth(), category.UserID)
+
+	return err
+}
+
+// AddCategoryKeyword - правило импорта выписки: keyword в описании строки -> категория
+func (db *CategoriesDB) AddCategoryKeyword(ctx context.Context, keyword domain.CategoryKeyword) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "add_category_keyword_db")
+	defer span.Finish()
+
+	builder := sq.Insert("category_keywords").Columns("category_id", "keyword").Values(keyword.CategoryID, keyword.Keyword).
+		Suffix("ON CONFLICT (category_id, keyword) DO NOTHING").PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = db.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+// GetUserCategoryKeywords - правила импорта пользователя, длинные ключевые слова первыми
+func (db *CategoriesDB) GetUserCategoryKeywords(ctx context.Context, user domain.User) ([]domain.CategoryKeyword, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_user_category_keywords_db")
+	defer span.Finish()
+
+	var keywords []domain.CategoryKeyword = nil
+
+	builder := sq.Select(
+		"category_keywords.category_id",
+		"expence_category.name",
+		"category_keywords.keyword",
+	).From("category_keywords").Join(
+		"expence_category ON category_keywords.category_id = expence_category.id",
+	).Where(sq.Eq{
+		"expence_category.user_id": user.UserID,
+	}).OrderBy("length(category_keywords.keyword) DESC", "category_keywords.id").PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return keywords, err
+	}
+
+	rows, err := db.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return keywords, err
+	}
+	defer rows.Close()
+
+	keywords = make([]domain.CategoryKeyword, 0)
+	for rows.Next() {
+		var keyword domain.CategoryKeyword
+		if err := rows.Scan(
+			&keyword.CategoryID,
+			&keyword.CategoryName,
+			&keyword.Keyword,
+		); err != nil {
+			return keywords, err
+		}
+		keywords = append(keywords, keyword)
+	}
+
+	if err = rows.Err(); err != nil {
+		return keywords, err
+	}
+
+	return keywords, nil
+}
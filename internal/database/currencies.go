@@ -3,6 +3,7 @@ package database
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/opentracing/opentracing-go"
@@ -36,11 +37,18 @@ func (db *CurreciesDB) IsCurrencyExists(ctx context.Context, currency domain.Cur
 	return currency, err
 }
 
-func (db *CurreciesDB) GetCurrencyRate(ctx context.Context, currency domain.Currency) (domain.Currency, error) {
-	span, ctx := opentracing.StartSpanFromContext(ctx, "get_currency_rate_db")
+// GetCurrencyRateAt - курс валюты на день date: последний известный курс не позже date,
+// а если истории на тот день нет - текущий курс из currency
+func (db *CurreciesDB) GetCurrencyRateAt(ctx context.Context, currency domain.Currency, date time.Time) (domain.Currency, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_currency_rate_at_db")
 	defer span.Finish()
 
-	builder := sq.Select("rate").From("currency").Where(sq.Eq{
+	history := sq.Select("rate").From("currency_rates").Where(sq.And{
+		sq.Eq{"currency_id": currency.ID},
+		sq.LtOrEq{"date": date},
+	}).OrderBy("date DESC").Limit(1)
+
+	builder := sq.Select().Column(sq.Expr("COALESCE((?), rate)", history)).From("currency").Where(sq.Eq{
 		"id": currency.ID,
 	}).PlaceholderFormat(sq.Dollar)
 
@@ -50,27 +58,54 @@ func (db *CurreciesDB) GetCurrencyRate(ctx context.Context, currency domain.Curr
 	}
 
 	err = db.db.QueryRowContext(ctx, query, args...).Scan(&currency.Rate)
+	currency.Date = date
 
 	return currency, err
 }
 
+// UpdateRates - новые курсы валют: последний курс в currency и запись в истории на день курса;
+// повторное получение курсов за тот же день перезаписывает запись
 func (db *CurreciesDB) UpdateRates(ctx context.Context, currencies []domain.Currency) error {
 	span, ctx := opentracing.StartSpanFromContext(ctx, "update_rates_db")
 	defer span.Finish()
 
+	if len(currencies) == 0 {
+		return nil
+	}
+
 	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
 	builder := psql.Insert("currency").Columns("id", "code", "rate").PlaceholderFormat(sq.Dollar)
+	historyBuilder := psql.Insert("currency_rates").Columns("currency_id", "date", "rate")
 	for _, v := range currencies {
 		builder = builder.Values(v.ID, v.Code, v.Rate)
+		historyBuilder = historyBuilder.Values(v.ID, v.Date, v.Rate)
 	}
 	builder = builder.Suffix("ON CONFLICT (id) DO UPDATE SET rate = EXCLUDED.rate")
+	historyBuilder = historyBuilder.Suffix("ON CONFLICT (currency_id, date) DO UPDATE SET rate = EXCLUDED.rate")
 
 	totalStr, vals, err := builder.ToSql()
 	if err != nil {
 		return err
 	}
 
-	_, err = db.db.ExecContext(ctx, totalStr, vals...)
+	historyStr, historyVals, err := historyBuilder.ToSql()
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:all
+
+	if _, err = tx.ExecContext(ctx, totalStr, vals...); err != nil {
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, historyStr, historyVals...); err != nil {
+		return err
+	}
 
-	return err
+	return tx.Commit()
 }
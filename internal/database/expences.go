@@ -4,9 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
 	"github.com/opentracing/opentracing-go"
 	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/common"
 	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/domain"
@@ -21,25 +23,36 @@ func NewExpencesDB(db *sql.DB) *ExpencesDB {
 	return &ExpencesDB{db}
 }
 
-func (db *ExpencesDB) AddExpence(ctx context.Context, expence domain.Expence) error {
+// AddExpence - сохранение траты с учётом лимитов месяца; возвращает id траты
+func (db *ExpencesDB) AddExpence(ctx context.Context, expence domain.Expence) (int64, error) {
 	span, ctx := opentracing.StartSpanFromContext(ctx, "add_expence_db")
 	defer span.Finish()
 
 	tx, err := db.db.BeginTx(ctx, nil)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer tx.Rollback() //nolint:all
 
-	if expence.Timestamp.After(helpers.GetStartOfCurrentMonth()) {
+	month, err := limitMonth(ctx, tx, expence.UserID)
+	if err != nil {
+		return 0, err
+	}
+
+	if isLimitMonth(expence.Timestamp, month) {
 		var monthLimit int64
-		if err := tx.QueryRowContext(ctx, "UPDATE users SET current_month_limit = current_month_limit - $1 WHERE id = $2 RETURNING current_month_limit;", expence.Total, expence.UserID).Scan(&monthLimit); err != nil {
+		var softLimit bool
+		if err := tx.QueryRowContext(ctx, "UPDATE users SET current_month_limit = current_month_limit - $1 WHERE id = $2 RETURNING current_month_limit, soft_limit;", expence.Total, expence.UserID).Scan(&monthLimit, &softLimit); err != nil {
 			if err == sql.ErrNoRows {
-				return fmt.Errorf("user not found")
+				return 0, fmt.Errorf("user not found")
 			}
 		}
-		if monthLimit < 0 {
-			return fmt.Errorf("add expence: %w", &common.LimitExceededError{})
+		// в мягком режиме трата сверх лимита сохраняется, о превышении предупреждает бот
+		if monthLimit < 0 && !softLimit {
+			return 0, fmt.Errorf("add expence: %w", &common.LimitExceededError{})
+		}
+		if err := spendCategoryLimit(ctx, tx, expence.CategoryID, expence.Total, softLimit); err != nil {
+			return 0, fmt.Errorf("add expence: %w", err)
 		}
 	}
 
@@ -48,42 +61,303 @@ func (db *ExpencesDB) AddExpence(ctx context.Context, expence domain.Expence) er
 		"category_id",
 		"ts",
 		"total",
+		"added_by",
+		"note",
+		"tags",
+		"currency_id",
+		"original_total",
 	).Values(
 		expence.UserID,
 		expence.CategoryID,
 		expence.Timestamp,
 		expence.Total,
-	).PlaceholderFormat(sq.Dollar)
+		expence.AddedBy,
+		expence.Note,
+		tagsArray(expence.Tags),
+		expence.CurrencyID,
+		expence.OriginalTotal,
+	).Suffix("RETURNING id").PlaceholderFormat(sq.Dollar)
 
 	query, args, err := builder.ToSql()
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	_, err = tx.ExecContext(ctx, query, args...)
+	var id int64
+	if err := tx.QueryRowContext(ctx, query, args...).Scan(&id); err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+// ImportExpences - добавление трат из выписки одной транзакцией; строки с уже импортированным
+// ImportHash пропускаются. Лимиты текущего месяца уменьшаются без отказа при превышении -
+// деньги по выписке уже потрачены. Возвращает количество добавленных трат
+func (db *ExpencesDB) ImportExpences(ctx context.Context, user domain.User, expences []domain.Expence) (int64, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "import_expences_db")
+	defer span.Finish()
+
+	tx, err := db.db.BeginTx(ctx, nil)
 	if err != nil {
-		return err
+		return 0, err
+	}
+	defer tx.Rollback() //nolint:all
+
+	month, err := limitMonth(ctx, tx, user.UserID)
+	if err != nil {
+		return 0, err
+	}
+
+	var added int64
+	for _, expence := range expences {
+		builder := sq.Insert("expences").Columns(
+			"user_id",
+			"category_id",
+			"ts",
+			"total",
+			"import_hash",
+			"added_by",
+			"currency_id",
+			"original_total",
+		).Values(
+			user.UserID,
+			expence.CategoryID,
+			expence.Timestamp,
+			expence.Total,
+			expence.ImportHash,
+			expence.AddedBy,
+			expence.CurrencyID,
+			expence.OriginalTotal,
+		).Suffix("ON CONFLICT (user_id, import_hash) DO NOTHING RETURNING id").PlaceholderFormat(sq.Dollar)
+
+		query, args, err := builder.ToSql()
+		if err != nil {
+			return 0, err
+		}
+
+		var id int64
+		err = tx.QueryRowContext(ctx, query, args...).Scan(&id)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		added++
+
+		if isLimitMonth(expence.Timestamp, month) {
+			if _, err := tx.ExecContext(ctx, "UPDATE users SET current_month_limit = current_month_limit - $1 WHERE id = $2;", expence.Total, user.UserID); err != nil {
+				return 0, err
+			}
+			if _, err := tx.ExecContext(ctx, "UPDATE expence_category SET current_month_limit = current_month_limit - $1 WHERE id = $2 AND current_month_limit IS NOT NULL;", expence.Total, expence.CategoryID); err != nil {
+				return 0, err
+			}
+		}
 	}
 
 	if err = tx.Commit(); err != nil {
-		return err
+		return 0, err
 	}
 
-	return err
+	return added, nil
 }
 
-func (db *ExpencesDB) GetUserExpences(ctx context.Context, user domain.User, limitTs time.Time) ([]domain.Expence, error) {
+// GetUserExpences - траты пользователя за период [startTs, endTs)
+func (db *ExpencesDB) GetUserExpences(ctx context.Context, user domain.User, startTs time.Time, endTs time.Time) ([]domain.Expence, error) {
 	span, ctx := opentracing.StartSpanFromContext(ctx, "get_user_expences_db")
 	defer span.Finish()
 
 	var expences []domain.Expence = nil
 
-	builder := sq.Select("expence_category.name, expences.total").From("expences").Where(
-		fmt.Sprintf("expences.user_id = %d AND expences.ts >= %s", user.UserID, limitTs.Format(`'2006-01-02 15:04:05'`)),
-	).Join("expence_category ON expences.category_id = expence_category.id").PlaceholderFormat(sq.Dollar)
+	builder := sq.Select("expence_category.name, expences.total, expences.note, expences.tags").From("expences").Where(sq.And{
+		sq.Eq{"expences.user_id": user.UserID},
+		sq.GtOrEq{"expences.ts": startTs},
+		sq.Lt{"expences.ts": endTs},
+	}).Join("expence_category ON expences.category_id = expence_category.id").PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+
+	if err != nil {
+		return expences, err
+	}
+
+	rows, err := db.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return expences, err
+	}
+
+	expences = make([]domain.Expence, 0)
+	for rows.Next() {
+		var expence domain.Expence
+		if err := rows.Scan(
+			&expence.CategoryName,
+			&expence.Total,
+			&expence.Note,
+			pq.Array(&expence.Tags),
+		); err != nil {
+			return expences, err
+		}
+		expences = append(expences, expence)
+	}
+
+	if err = rows.Err(); err != nil {
+		return expences, err
+	}
+
+	return expences, nil
+}
+
+// GetUserDailyTotals - суммы трат пользователя по дням за период [startTs, endTs), по возрастанию дня;
+// в ответе заполнены только Timestamp (начало дня) и Total
+func (db *ExpencesDB) GetUserDailyTotals(ctx context.Context, user domain.User, startTs time.Time, endTs time.Time) ([]domain.Expence, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_user_daily_totals_db")
+	defer span.Finish()
+
+	var expences []domain.Expence = nil
+
+	builder := sq.Select("date_trunc('day', ts) AS day, SUM(total)").From("expences").Where(sq.And{
+		sq.Eq{"user_id": user.UserID},
+		sq.GtOrEq{"ts": startTs},
+		sq.Lt{"ts": endTs},
+	}).GroupBy("day").OrderBy("day").PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return expences, err
+	}
+
+	rows, err := db.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return expences, err
+	}
+	defer rows.Close()
+
+	expences = make([]domain.Expence, 0)
+	for rows.Next() {
+		var expence domain.Expence
+		if err := rows.Scan(
+			&expence.Timestamp,
+			&expence.Total,
+		); err != nil {
+			return expences, err
+		}
+		expences = append(expences, expence)
+	}
+
+	if err = rows.Err(); err != nil {
+		return expences, err
+	}
+
+	return expences, nil
+}
+
+// GetUserMemberTotals - суммы трат бюджета за период [startTs, endTs) по добавившим их участникам
+func (db *ExpencesDB) GetUserMemberTotals(ctx context.Context, user domain.User, startTs time.Time, endTs time.Time) ([]domain.MemberTotal, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_user_member_totals_db")
+	defer span.Finish()
+
+	var totals []domain.MemberTotal = nil
+
+	builder := sq.Select(
+		"COALESCE(expences.added_by, 0)",
+		"COALESCE(users.name, '')",
+		"SUM(expences.total)",
+	).From("expences").LeftJoin(
+		"users ON expences.added_by = users.id",
+	).Where(sq.And{
+		sq.Eq{"expences.user_id": user.UserID},
+		sq.GtOrEq{"expences.ts": startTs},
+		sq.Lt{"expences.ts": endTs},
+	}).GroupBy("expences.added_by", "users.name").OrderBy("SUM(expences.total) DESC").PlaceholderFormat(sq.Dollar)
 
 	query, args, err := builder.ToSql()
+	if err != nil {
+		return totals, err
+	}
 
+	rows, err := db.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return totals, err
+	}
+	defer rows.Close()
+
+	totals = make([]domain.MemberTotal, 0)
+	for rows.Next() {
+		var total domain.MemberTotal
+		if err := rows.Scan(
+			&total.UserID,
+			&total.Name,
+			&total.Total,
+		); err != nil {
+			return totals, err
+		}
+		totals = append(totals, total)
+	}
+
+	if err = rows.Err(); err != nil {
+		return totals, err
+	}
+
+	return totals, nil
+}
+
+// GetUserExpencesPage - траты пользователя от новых к старым, начиная с offset
+func (db *ExpencesDB) GetUserExpencesPage(ctx context.Context, user domain.User, offset uint64, limit uint64) ([]domain.Expence, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_user_expences_page_db")
+	defer span.Finish()
+
+	builder := expenceRowsSelect().Where(sq.Eq{
+		"expences.user_id": user.UserID,
+	}).OrderBy("expences.ts DESC", "expences.id DESC").Offset(offset).Limit(limit).PlaceholderFormat(sq.Dollar)
+
+	return db.queryExpenceRows(ctx, user, builder)
+}
+
+// GetUserExpenceRows - траты пользователя за период [startTs, endTs) со всеми полями, от старых к новым
+func (db *ExpencesDB) GetUserExpenceRows(ctx context.Context, user domain.User, startTs time.Time, endTs time.Time) ([]domain.Expence, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_user_expence_rows_db")
+	defer span.Finish()
+
+	builder := expenceRowsSelect().Where(sq.And{
+		sq.Eq{"expences.user_id": user.UserID},
+		sq.GtOrEq{"expences.ts": startTs},
+		sq.Lt{"expences.ts": endTs},
+	}).OrderBy("expences.ts", "expences.id").PlaceholderFormat(sq.Dollar)
+
+	return db.queryExpenceRows(ctx, user, builder)
+}
+
+// SearchUserExpences - последние limit трат пользователя, в комментарии которых встречается text
+func (db *ExpencesDB) SearchUserExpences(ctx context.Context, user domain.User, text string, limit uint64) ([]domain.Expence, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "search_user_expences_db")
+	defer span.Finish()
+
+	builder := expenceRowsSelect().Where(sq.And{
+		sq.Eq{"expences.user_id": user.UserID},
+		sq.ILike{"expences.note": "%" + escapeLike(text) + "%"},
+	}).OrderBy("expences.ts DESC", "expences.id DESC").Limit(limit).PlaceholderFormat(sq.Dollar)
+
+	return db.queryExpenceRows(ctx, user, builder)
+}
+
+// GetUserTagTotals - суммы трат с меткой tag за период [startTs, endTs) по категориям
+func (db *ExpencesDB) GetUserTagTotals(ctx context.Context, user domain.User, tag string, startTs time.Time, endTs time.Time) ([]domain.Expence, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_user_tag_totals_db")
+	defer span.Finish()
+
+	var expences []domain.Expence = nil
+
+	builder := sq.Select("expence_category.name", "SUM(expences.total)").From("expences").Join(
+		"expence_category ON expences.category_id = expence_category.id",
+	).Where(sq.And{
+		sq.Eq{"expences.user_id": user.UserID},
+		sq.Expr("expences.tags @> ?", tagsArray([]string{tag})),
+		sq.GtOrEq{"expences.ts": startTs},
+		sq.Lt{"expences.ts": endTs},
+	}).GroupBy("expence_category.name").PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
 	if err != nil {
 		return expences, err
 	}
@@ -92,6 +366,7 @@ func (db *ExpencesDB) GetUserExpences(ctx context.Context, user domain.User, lim
 	if err != nil {
 		return expences, err
 	}
+	defer rows.Close()
 
 	expences = make([]domain.Expence, 0)
 	for rows.Next() {
@@ -111,3 +386,292 @@ func (db *ExpencesDB) GetUserExpences(ctx context.Context, user domain.User, lim
 
 	return expences, nil
 }
+
+// GetUserCurrencyTotals - суммы трат за период [startTs, endTs) по валютам, в которых они были введены;
+// в ответе заполнены CurrencyID, CurrencyCode, OriginalTotal и Total в системной валюте
+func (db *ExpencesDB) GetUserCurrencyTotals(ctx context.Context, user domain.User, startTs time.Time, endTs time.Time) ([]domain.Expence, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_user_currency_totals_db")
+	defer span.Finish()
+
+	var expences []domain.Expence = nil
+
+	builder := sq.Select(
+		"currency.id",
+		"currency.code",
+		"SUM(expences.original_total)",
+		"SUM(expences.total)",
+	).From("expences").Join(
+		"currency ON expences.currency_id = currency.id",
+	).Where(sq.And{
+		sq.Eq{"expences.user_id": user.UserID},
+		sq.GtOrEq{"expences.ts": startTs},
+		sq.Lt{"expences.ts": endTs},
+	}).GroupBy("currency.id", "currency.code").OrderBy("SUM(expences.total) DESC").PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return expences, err
+	}
+
+	rows, err := db.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return expences, err
+	}
+	defer rows.Close()
+
+	expences = make([]domain.Expence, 0)
+	for rows.Next() {
+		expence := domain.Expence{UserID: user.UserID}
+		if err := rows.Scan(
+			&expence.CurrencyID,
+			&expence.CurrencyCode,
+			&expence.OriginalTotal,
+			&expence.Total,
+		); err != nil {
+			return expences, err
+		}
+		expences = append(expences, expence)
+	}
+
+	if err = rows.Err(); err != nil {
+		return expences, err
+	}
+
+	return expences, nil
+}
+
+// expenceRowsSelect - выборка полей траты для queryExpenceRows
+func expenceRowsSelect() sq.SelectBuilder {
+	return sq.Select(
+		"expences.id",
+		"expences.category_id",
+		"expence_category.name",
+		"expences.ts",
+		"expences.total",
+		"expences.note",
+		"expences.tags",
+		"COALESCE(expences.currency_id, 0)",
+		"COALESCE(currency.code, '')",
+		"COALESCE(expences.original_total, 0)",
+	).From("expences").Join(
+		"expence_category ON expences.category_id = expence_category.id",
+	).LeftJoin(
+		"currency ON expences.currency_id = currency.id",
+	)
+}
+
+// queryExpenceRows - выполнение выборки полей из expenceRowsSelect
+func (db *ExpencesDB) queryExpenceRows(ctx context.Context, user domain.User, builder sq.SelectBuilder) ([]domain.Expence, error) {
+	var expences []domain.Expence = nil
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return expences, err
+	}
+
+	rows, err := db.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return expences, err
+	}
+	defer rows.Close()
+
+	expences = make([]domain.Expence, 0)
+	for rows.Next() {
+		expence := domain.Expence{UserID: user.UserID}
+		if err := rows.Scan(
+			&expence.ID,
+			&expence.CategoryID,
+			&expence.CategoryName,
+			&expence.Timestamp,
+			&expence.Total,
+			&expence.Note,
+			pq.Array(&expence.Tags),
+			&expence.CurrencyID,
+			&expence.CurrencyCode,
+			&expence.OriginalTotal,
+		); err != nil {
+			return expences, err
+		}
+		expences = append(expences, expence)
+	}
+
+	if err = rows.Err(); err != nil {
+		return expences, err
+	}
+
+	return expences, nil
+}
+
+// tagsArray - метки для колонки text[]; пустой массив вместо NULL
+func tagsArray(tags []string) interface{} {
+	if tags == nil {
+		tags = []string{}
+	}
+	return pq.Array(tags)
+}
+
+// escapeLike - экранирование спецсимволов шаблона LIKE
+func escapeLike(text string) string {
+	return strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(text)
+}
+
+// UpdateExpence - изменение траты с пересчётом лимита текущего месяца
+func (db *ExpencesDB) UpdateExpence(ctx context.Context, expence domain.Expence) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "update_expence_db")
+	defer span.Finish()
+
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:all
+
+	var old domain.Expence
+	err = tx.QueryRowContext(ctx, "SELECT category_id, ts, total FROM expences WHERE id = $1 AND user_id = $2 FOR UPDATE;", expence.ID, expence.UserID).Scan(&old.CategoryID, &old.Timestamp, &old.Total)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return common.ErrExpenceNotFound
+		}
+		return err
+	}
+
+	month, err := limitMonth(ctx, tx, expence.UserID)
+	if err != nil {
+		return err
+	}
+
+	// на сколько изменится сумма трат текущего месяца, в целом и по категориям
+	var delta int64
+	categoryDelta := make(map[int64]int64, 2)
+	if isLimitMonth(expence.Timestamp, month) {
+		delta += expence.Total
+		categoryDelta[expence.CategoryID] += expence.Total
+	}
+	if isLimitMonth(old.Timestamp, month) {
+		delta -= old.Total
+		categoryDelta[old.CategoryID] -= old.Total
+	}
+
+	var softLimit bool
+	if delta != 0 {
+		var monthLimit int64
+		if err := tx.QueryRowContext(ctx, "UPDATE users SET current_month_limit = current_month_limit - $1 WHERE id = $2 RETURNING current_month_limit, soft_limit;", delta, expence.UserID).Scan(&monthLimit, &softLimit); err != nil {
+			return err
+		}
+		if delta > 0 && monthLimit < 0 && !softLimit {
+			return fmt.Errorf("update expence: %w", &common.LimitExceededError{})
+		}
+	} else if categoryDelta[expence.CategoryID] > 0 {
+		// сумма трат месяца не изменилась, но трата перенесена в другую категорию
+		if err := tx.QueryRowContext(ctx, "SELECT soft_limit FROM users WHERE id = $1;", expence.UserID).Scan(&softLimit); err != nil {
+			return err
+		}
+	}
+
+	for categoryID, categoryTotal := range categoryDelta {
+		if categoryTotal == 0 {
+			continue
+		}
+		if err := spendCategoryLimit(ctx, tx, categoryID, categoryTotal, softLimit); err != nil {
+			return fmt.Errorf("update expence: %w", err)
+		}
+	}
+
+	builder := sq.Update("expences").
+		Set("category_id", expence.CategoryID).
+		Set("ts", expence.Timestamp).
+		Set("total", expence.Total).
+		Set("currency_id", expence.CurrencyID).
+		Set("original_total", expence.OriginalTotal).
+		Where(sq.Eq{
+			"id":      expence.ID,
+			"user_id": expence.UserID,
+		}).PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteExpence - удаление траты с возвратом её суммы в лимит текущего месяца
+func (db *ExpencesDB) DeleteExpence(ctx context.Context, expence domain.Expence) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "delete_expence_db")
+	defer span.Finish()
+
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:all
+
+	var deleted domain.Expence
+	err = tx.QueryRowContext(ctx, "DELETE FROM expences WHERE id = $1 AND user_id = $2 RETURNING category_id, ts, total;", expence.ID, expence.UserID).Scan(&deleted.CategoryID, &deleted.Timestamp, &deleted.Total)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return common.ErrExpenceNotFound
+		}
+		return err
+	}
+
+	month, err := limitMonth(ctx, tx, expence.UserID)
+	if err != nil {
+		return err
+	}
+
+	if isLimitMonth(deleted.Timestamp, month) {
+		if _, err := tx.ExecContext(ctx, "UPDATE users SET current_month_limit = current_month_limit + $1 WHERE id = $2;", deleted.Total, expence.UserID); err != nil {
+			return err
+		}
+		if err := spendCategoryLimit(ctx, tx, deleted.CategoryID, -deleted.Total, false); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// spendCategoryLimit - списание delta с лимита категории; категории без лимита пропускаются,
+// в мягком режиме softLimit лимит может уйти в минус
+func spendCategoryLimit(ctx context.Context, tx *sql.Tx, categoryID int64, delta int64, softLimit bool) error {
+	var monthLimit int64
+	var name string
+	err := tx.QueryRowContext(ctx, "UPDATE expence_category SET current_month_limit = current_month_limit - $1 WHERE id = $2 AND current_month_limit IS NOT NULL RETURNING current_month_limit, name;", delta, categoryID).Scan(&monthLimit, &name)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if delta > 0 && monthLimit < 0 && !softLimit {
+		return &common.LimitExceededError{Category: name}
+	}
+	return nil
+}
+
+// limitMonth - первый день месяца, за который считается лимит пользователя: начатый последним сбросом
+// лимитов, а до первого сброса - текущий месяц; оба - по времени пользователя
+func limitMonth(ctx context.Context, tx *sql.Tx, userID int64) (time.Time, error) {
+	var month sql.NullTime
+	var timezone string
+	err := tx.QueryRowContext(ctx, "SELECT limit_month, timezone FROM users WHERE id = $1;", userID).Scan(&month, &timezone)
+	if err != nil && err != sql.ErrNoRows {
+		return time.Time{}, err
+	}
+	if !month.Valid {
+		return helpers.LimitMonth(time.Now(), timezone), nil
+	}
+	return month.Time, nil
+}
+
+// траты текущего месяца лимита учитываются в current_month_limit
+func isLimitMonth(ts time.Time, month time.Time) bool {
+	return !ts.Before(month)
+}
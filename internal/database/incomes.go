@@ -0,0 +1,68 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/opentracing/opentracing-go"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/domain"
+)
+
+type IncomesDB struct {
+	db *sql.DB
+}
+
+func NewIncomesDB(db *sql.DB) *IncomesDB {
+	return &IncomesDB{db}
+}
+
+// AddIncome - доходы не участвуют в расчёте лимита месяца
+func (db *IncomesDB) AddIncome(ctx context.Context, income domain.Income) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "add_income_db")
+	defer span.Finish()
+
+	builder := sq.Insert("incomes").Columns(
+		"user_id",
+		"source",
+		"ts",
+		"total",
+	).Values(
+		income.UserID,
+		income.Source,
+		income.Timestamp,
+		income.Total,
+	).PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = db.db.ExecContext(ctx, query, args...)
+
+	return err
+}
+
+// GetUserIncomeTotal - сумма доходов пользователя за период [startTs, endTs)
+func (db *IncomesDB) GetUserIncomeTotal(ctx context.Context, user domain.User, startTs time.Time, endTs time.Time) (int64, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_user_income_total_db")
+	defer span.Finish()
+
+	var total int64
+	builder := sq.Select("COALESCE(SUM(total), 0)").From("incomes").Where(sq.And{
+		sq.Eq{"user_id": user.UserID},
+		sq.GtOrEq{"ts": startTs},
+		sq.Lt{"ts": endTs},
+	}).PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return total, err
+	}
+
+	err = db.db.QueryRowContext(ctx, query, args...).Scan(&total)
+
+	return total, err
+}
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/opentracing/opentracing-go"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/common"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/domain"
+)
+
+type OperationsDB struct {
+	db *sql.DB
+}
+
+func NewOperationsDB(db *sql.DB) *OperationsDB {
+	return &OperationsDB{db}
+}
+
+// AddOperation - запись изменения бюджета в журнал
+func (db *OperationsDB) AddOperation(ctx context.Context, operation domain.Operation) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "add_operation_db")
+	defer span.Finish()
+
+	builder := sq.Insert("operations").Columns(
+		"user_id",
+		"member_id",
+		"kind",
+		"object_id",
+		"month_limit",
+		"month_limit_delta",
+	).Values(
+		operation.UserID,
+		operation.MemberID,
+		operation.Kind,
+		operation.ObjectID,
+		operation.MonthLimit,
+		operation.MonthLimitDelta,
+	).PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = db.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+// GetLastOperation - последнее изменение бюджета member.UserID, сделанное участником member.MemberID;
+// common.ErrNothingToUndo - таких изменений в журнале нет
+func (db *OperationsDB) GetLastOperation(ctx context.Context, member domain.Operation) (domain.Operation, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_last_operation_db")
+	defer span.Finish()
+
+	operation := domain.Operation{UserID: member.UserID, MemberID: member.MemberID}
+
+	builder := sq.Select("id", "kind", "object_id", "month_limit", "month_limit_delta").From("operations").Where(sq.Eq{
+		"user_id":   member.UserID,
+		"member_id": member.MemberID,
+	}).OrderBy("id DESC").Limit(1).PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return operation, err
+	}
+
+	err = db.db.QueryRowContext(ctx, query, args...).Scan(
+		&operation.ID,
+		&operation.Kind,
+		&operation.ObjectID,
+		&operation.MonthLimit,
+		&operation.MonthLimitDelta,
+	)
+	if err == sql.ErrNoRows {
+		return operation, common.ErrNothingToUndo
+	}
+	return operation, err
+}
+
+// DeleteOperation - удаление отменённого изменения из журнала
+func (db *OperationsDB) DeleteOperation(ctx context.Context, operation domain.Operation) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "delete_operation_db")
+	defer span.Finish()
+
+	_, err := db.db.ExecContext(ctx, "DELETE FROM operations WHERE id = $1 AND user_id = $2;", operation.ID, operation.UserID)
+	return err
+}
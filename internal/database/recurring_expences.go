@@ -0,0 +1,180 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/opentracing/opentracing-go"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/common"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/domain"
+)
+
+type RecurringExpencesDB struct {
+	db *sql.DB
+}
+
+func NewRecurringExpencesDB(db *sql.DB) *RecurringExpencesDB {
+	return &RecurringExpencesDB{db}
+}
+
+func (db *RecurringExpencesDB) AddRecurringExpence(ctx context.Context, expence domain.RecurringExpence) (int64, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "add_recurring_expence_db")
+	defer span.Finish()
+
+	var id int64 = -1
+	builder := sq.Insert("recurring_expences").Columns(
+		"user_id",
+		"category_id",
+		"total",
+		"schedule",
+		"next_ts",
+	).Values(
+		expence.UserID,
+		expence.CategoryID,
+		expence.Total,
+		expence.Schedule,
+		expence.NextTimestamp,
+	).Suffix("RETURNING id").PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return id, err
+	}
+
+	err = db.db.QueryRowContext(ctx, query, args...).Scan(&id)
+
+	return id, err
+}
+
+func (db *RecurringExpencesDB) GetUserRecurringExpences(ctx context.Context, user domain.User) ([]domain.RecurringExpence, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_user_recurring_expences_db")
+	defer span.Finish()
+
+	return db.selectRecurringExpences(ctx, sq.Eq{
+		"recurring_expences.user_id": user.UserID,
+	})
+}
+
+// GetDueRecurringExpences - активные расписания, срок списания по которым наступил к ts
+func (db *RecurringExpencesDB) GetDueRecurringExpences(ctx context.Context, ts time.Time) ([]domain.RecurringExpence, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_due_recurring_expences_db")
+	defer span.Finish()
+
+	return db.selectRecurringExpences(ctx, sq.And{
+		sq.Eq{"recurring_expences.paused": false},
+		sq.LtOrEq{"recurring_expences.next_ts": ts},
+	})
+}
+
+func (db *RecurringExpencesDB) selectRecurringExpences(ctx context.Context, pred sq.Sqlizer) ([]domain.RecurringExpence, error) {
+	var expences []domain.RecurringExpence = nil
+
+	builder := sq.Select(
+		"recurring_expences.id",
+		"recurring_expences.user_id",
+		"recurring_expences.category_id",
+		"expence_category.name",
+		"recurring_expences.total",
+		"recurring_expences.schedule",
+		"recurring_expences.paused",
+		"recurring_expences.next_ts",
+	).From("recurring_expences").Join(
+		"expence_category ON recurring_expences.category_id = expence_category.id",
+	).Where(pred).OrderBy("recurring_expences.id").PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return expences, err
+	}
+
+	rows, err := db.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return expences, err
+	}
+	defer rows.Close()
+
+	expences = make([]domain.RecurringExpence, 0)
+	for rows.Next() {
+		var expence domain.RecurringExpence
+		if err := rows.Scan(
+			&expence.ID,
+			&expence.UserID,
+			&expence.CategoryID,
+			&expence.CategoryName,
+			&expence.Total,
+			&expence.Schedule,
+			&expence.Paused,
+			&expence.NextTimestamp,
+		); err != nil {
+			return expences, err
+		}
+		expences = append(expences, expence)
+	}
+
+	if err = rows.Err(); err != nil {
+		return expences, err
+	}
+
+	return expences, nil
+}
+
+// UpdateRecurringExpence - изменение признака паузы и времени следующего списания
+func (db *RecurringExpencesDB) UpdateRecurringExpence(ctx context.Context, expence domain.RecurringExpence) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "update_recurring_expence_db")
+	defer span.Finish()
+
+	builder := sq.Update("recurring_expences").
+		Set("paused", expence.Paused).
+		Set("next_ts", expence.NextTimestamp).
+		Where(sq.Eq{
+			"id":      expence.ID,
+			"user_id": expence.UserID,
+		}).PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return err
+	}
+
+	res, err := db.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	return checkRecurringExpenceAffected(res)
+}
+
+func (db *RecurringExpencesDB) DeleteRecurringExpence(ctx context.Context, expence domain.RecurringExpence) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "delete_recurring_expence_db")
+	defer span.Finish()
+
+	builder := sq.Delete("recurring_expences").Where(sq.Eq{
+		"id":      expence.ID,
+		"user_id": expence.UserID,
+	}).PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return err
+	}
+
+	res, err := db.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	return checkRecurringExpenceAffected(res)
+}
+
+func checkRecurringExpenceAffected(res sql.Result) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return common.ErrRecurringExpenceNotFound
+	}
+	return nil
+}
@@ -3,9 +3,12 @@ package database
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
 	"github.com/opentracing/opentracing-go"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/common"
 	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/domain"
 )
 
@@ -108,11 +111,94 @@ func (db *UsersDB) GetUserBaseCurrency(ctx context.Context, user domain.User) (d
 	return rv, err
 }
 
-func (db *UsersDB) UpdateMonthLimits(ctx context.Context) error {
+// GetTimezones - часовые пояса, в которых есть пользователи; пустой - время сервера
+func (db *UsersDB) GetTimezones(ctx context.Context) ([]string, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_timezones_db")
+	defer span.Finish()
+
+	rows, err := db.db.QueryContext(ctx, "SELECT DISTINCT timezone FROM users")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var timezones []string
+	for rows.Next() {
+		var timezone string
+		if err := rows.Scan(&timezone); err != nil {
+			return nil, err
+		}
+		timezones = append(timezones, timezone)
+	}
+
+	return timezones, rows.Err()
+}
+
+// UpdateMonthLimits - сброс лимитов пользователей часового пояса timezone и их категорий,
+// если у них ещё не начат месяц month (первый день месяца по их времени); лимит и остаток
+// закончившегося месяца сохраняются в историю для отчётов.
+// Пользователям без начатого месяца он только проставляется: лимит уже считается с момента добавления
+func (db *UsersDB) UpdateMonthLimits(ctx context.Context, timezone string, month time.Time) error {
 	span, ctx := opentracing.StartSpanFromContext(ctx, "update_month_limits_db")
 	defer span.Finish()
 
-	_, err := db.db.ExecContext(ctx, "UPDATE users SET current_month_limit = default_month_limit")
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:all
+
+	if _, err := tx.ExecContext(ctx, "INSERT INTO month_limits (user_id, month, month_limit, remainder) SELECT id, limit_month, default_month_limit, current_month_limit FROM users WHERE timezone = $1 AND limit_month < $2 AND default_month_limit > 0 ON CONFLICT DO NOTHING", timezone, month); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE expence_category SET current_month_limit = default_month_limit FROM users WHERE expence_category.user_id = users.id AND expence_category.default_month_limit IS NOT NULL AND users.timezone = $1 AND users.limit_month < $2", timezone, month); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE users SET current_month_limit = default_month_limit, limit_alert_fired = 0, limit_month = $2 WHERE timezone = $1 AND limit_month < $2", timezone, month); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE users SET limit_month = $2 WHERE timezone = $1 AND limit_month IS NULL", timezone, month); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SetUserTimezone - часовой пояс пользователя
+func (db *UsersDB) SetUserTimezone(ctx context.Context, user domain.User) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "set_user_timezone_db")
+	defer span.Finish()
+
+	builder := sq.Update("users").Set("timezone", user.Timezone).Where(sq.Eq{
+		"id": user.UserID,
+	}).PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = db.db.ExecContext(ctx, query, args...)
+
+	return err
+}
+
+// SetUserLanguage - язык ответов бота пользователю
+func (db *UsersDB) SetUserLanguage(ctx context.Context, user domain.User) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "set_user_language_db")
+	defer span.Finish()
+
+	builder := sq.Update("users").Set("language", user.Language).Where(sq.Eq{
+		"id": user.UserID,
+	}).PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = db.db.ExecContext(ctx, query, args...)
+
 	return err
 }
 
@@ -120,7 +206,8 @@ func (db *UsersDB) SetUserLimit(ctx context.Context, user domain.User) error {
 	span, ctx := opentracing.StartSpanFromContext(ctx, "set_user_limit_db")
 	defer span.Finish()
 
-	builder := sq.Update("users").Set("default_month_limit", user.DefaultMonthLimit).Set("current_month_limit", user.CurrentMonthLimit).Where(sq.Eq{
+	// лимит считается заново, поэтому и уведомления о нём
+	builder := sq.Update("users").Set("default_month_limit", user.DefaultMonthLimit).Set("current_month_limit", user.CurrentMonthLimit).Set("limit_alert_fired", 0).Where(sq.Eq{
 		"id": user.UserID,
 	}).PlaceholderFormat(sq.Dollar)
 
@@ -138,6 +225,313 @@ func (db *UsersDB) ResetUserLimit(ctx context.Context, user domain.User) error {
 	span, ctx := opentracing.StartSpanFromContext(ctx, "reset_user_limit_db")
 	defer span.Finish()
 
-	_, err := db.db.ExecContext(ctx, "UPDATE users SET current_month_limit = default_month_limit WHERE id=$1", user.UserID)
+	_, err := db.db.ExecContext(ctx, "UPDATE users SET current_month_limit = default_month_limit, limit_alert_fired = 0 WHERE id=$1", user.UserID)
 	return err
 }
+
+// GetUserLimits - лимит месяца и его остаток
+func (db *UsersDB) GetUserLimits(ctx context.Context, user domain.User) (domain.User, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_user_limits_db")
+	defer span.Finish()
+
+	builder := sq.Select("COALESCE(default_month_limit, 0)", "COALESCE(current_month_limit, 0)").From("users").Where(sq.Eq{
+		"id": user.UserID,
+	}).PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return user, err
+	}
+
+	err = db.db.QueryRowContext(ctx, query, args...).Scan(&user.DefaultMonthLimit, &user.CurrentMonthLimit)
+
+	return user, err
+}
+
+// GetOverspend - сумма превышений лимитов месяцев, начинающихся в [start, end): закончившихся - из истории,
+// текущего - по остатку; month - начало текущего месяца для пользователей, у которых он ещё не проставлен
+func (db *UsersDB) GetOverspend(ctx context.Context, user domain.User, start time.Time, end time.Time, month time.Time) (int64, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_overspend_db")
+	defer span.Finish()
+
+	var overspend int64
+	err := db.db.QueryRowContext(ctx, "SELECT COALESCE(SUM(-remainder), 0) FROM ("+
+		"SELECT month, month_limit, remainder FROM month_limits WHERE user_id = $1 "+
+		"UNION ALL "+
+		"SELECT COALESCE(limit_month, $4), default_month_limit, current_month_limit FROM users WHERE id = $1"+
+		") AS limits WHERE month >= $2 AND month < $3 AND month_limit > 0 AND remainder < 0;",
+		user.UserID, start, end, month).Scan(&overspend)
+
+	return overspend, err
+}
+
+// UndoUserLimit - возврат лимита месяца, бывшего до изменения; остаток уменьшается на прибавку от изменения,
+// так траты, добавленные после изменения, остаются учтены
+func (db *UsersDB) UndoUserLimit(ctx context.Context, operation domain.Operation) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "undo_user_limit_db")
+	defer span.Finish()
+
+	_, err := db.db.ExecContext(ctx, "UPDATE users SET default_month_limit = $1, current_month_limit = current_month_limit - $2 WHERE id = $3;", operation.MonthLimit, operation.MonthLimitDelta, operation.UserID)
+	return err
+}
+
+// GetLimitAlerts - лимит месяца, его остаток, пороги уведомлений и наибольший сработавший порог
+func (db *UsersDB) GetLimitAlerts(ctx context.Context, user domain.User) (domain.User, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_limit_alerts_db")
+	defer span.Finish()
+
+	builder := sq.Select(
+		"COALESCE(default_month_limit, 0)",
+		"COALESCE(current_month_limit, 0)",
+		"limit_alerts",
+		"limit_alert_fired",
+	).From("users").Where(sq.Eq{
+		"id": user.UserID,
+	}).PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return user, err
+	}
+
+	err = db.db.QueryRowContext(ctx, query, args...).Scan(
+		&user.DefaultMonthLimit,
+		&user.CurrentMonthLimit,
+		pq.Array(&user.LimitAlerts),
+		&user.LimitAlertFired,
+	)
+
+	return user, err
+}
+
+// SetLimitAlerts - пороги уведомлений; сработавшие в этом месяце пороги не повторяются
+func (db *UsersDB) SetLimitAlerts(ctx context.Context, user domain.User) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "set_limit_alerts_db")
+	defer span.Finish()
+
+	alerts := user.LimitAlerts
+	if alerts == nil {
+		alerts = []int64{}
+	}
+
+	builder := sq.Update("users").Set("limit_alerts", pq.Array(alerts)).Where(sq.Eq{
+		"id": user.UserID,
+	}).PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = db.db.ExecContext(ctx, query, args...)
+
+	return err
+}
+
+// FireLimitAlert - отметка порога user.LimitAlertFired сработавшим; false - о нём или большем пороге уже уведомили
+func (db *UsersDB) FireLimitAlert(ctx context.Context, user domain.User) (bool, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "fire_limit_alert_db")
+	defer span.Finish()
+
+	res, err := db.db.ExecContext(ctx, "UPDATE users SET limit_alert_fired = $1 WHERE id = $2 AND limit_alert_fired < $1;", user.LimitAlertFired, user.UserID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
+
+// GetLimitMode - режим лимита: мягкий или жёсткий
+func (db *UsersDB) GetLimitMode(ctx context.Context, user domain.User) (domain.User, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_limit_mode_db")
+	defer span.Finish()
+
+	builder := sq.Select("soft_limit").From("users").Where(sq.Eq{
+		"id": user.UserID,
+	}).PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return user, err
+	}
+
+	err = db.db.QueryRowContext(ctx, query, args...).Scan(&user.SoftLimit)
+
+	return user, err
+}
+
+func (db *UsersDB) SetLimitMode(ctx context.Context, user domain.User) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "set_limit_mode_db")
+	defer span.Finish()
+
+	builder := sq.Update("users").Set("soft_limit", user.SoftLimit).Where(sq.Eq{
+		"id": user.UserID,
+	}).PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = db.db.ExecContext(ctx, query, args...)
+
+	return err
+}
+
+// GetUserBudget - бюджет, в котором ведёт учёт пользователь: общий, либо собственный, и часовой пояс пользователя
+func (db *UsersDB) GetUserBudget(ctx context.Context, user domain.User) (domain.User, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_user_budget_db")
+	defer span.Finish()
+
+	builder := sq.Select("COALESCE(budget_id, id)", "timezone", "language").From("users").Where(sq.Eq{
+		"id": user.UserID,
+	}).PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return user, err
+	}
+
+	err = db.db.QueryRowContext(ctx, query, args...).Scan(&user.BudgetID, &user.Timezone, &user.Language)
+
+	return user, err
+}
+
+func (db *UsersDB) SetUserName(ctx context.Context, user domain.User) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "set_user_name_db")
+	defer span.Finish()
+
+	builder := sq.Update("users").Set("name", user.Name).Where(sq.Eq{
+		"id": user.UserID,
+	}).PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = db.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+// GetBudgetInviteCode - код приглашения в бюджет, sql.ErrNoRows - бюджет ещё не создан
+func (db *UsersDB) GetBudgetInviteCode(ctx context.Context, budget domain.Budget) (string, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_budget_invite_code_db")
+	defer span.Finish()
+
+	var code string
+	err := db.db.QueryRowContext(ctx, "SELECT invite_code FROM budgets WHERE id = $1", budget.ID).Scan(&code)
+	return code, err
+}
+
+func (db *UsersDB) CreateBudget(ctx context.Context, budget domain.Budget) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "create_budget_db")
+	defer span.Finish()
+
+	builder := sq.Insert("budgets").Columns("id", "invite_code").Values(budget.ID, budget.InviteCode).PlaceholderFormat(sq.Dollar)
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = db.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+// JoinBudget - вступление в бюджет по коду приглашения; владелец бюджета с участниками
+// вступить в чужой бюджет не может. Код владельца, который сам вступил в чужой бюджет, не действует
+func (db *UsersDB) JoinBudget(ctx context.Context, user domain.User, inviteCode string) (int64, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "join_budget_db")
+	defer span.Finish()
+
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback() //nolint:all
+
+	var budgetID int64
+	if err := tx.QueryRowContext(ctx, "SELECT budgets.id FROM budgets JOIN users ON users.id = budgets.id WHERE budgets.invite_code = $1 AND (users.budget_id IS NULL OR users.id = $2)", inviteCode, user.UserID).Scan(&budgetID); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, common.ErrBudgetNotFound
+		}
+		return 0, err
+	}
+
+	var members int64
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE budget_id = $1", user.UserID).Scan(&members); err != nil {
+		return 0, err
+	}
+	if members > 0 && budgetID != user.UserID {
+		return 0, common.ErrBudgetHasMembers
+	}
+
+	// в собственный бюджет вступать не нужно
+	var newBudgetID interface{} = budgetID
+	if budgetID == user.UserID {
+		newBudgetID = nil
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE users SET budget_id = $1 WHERE id = $2", newBudgetID, user.UserID); err != nil {
+		return 0, err
+	}
+
+	// по коду собственного бюджета больше никто не вступит в покинутый бюджет; после выхода будет выдан новый код
+	if newBudgetID != nil {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM budgets WHERE id = $1", user.UserID); err != nil {
+			return 0, err
+		}
+	}
+
+	return budgetID, tx.Commit()
+}
+
+func (db *UsersDB) LeaveBudget(ctx context.Context, user domain.User) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "leave_budget_db")
+	defer span.Finish()
+
+	_, err := db.db.ExecContext(ctx, "UPDATE users SET budget_id = NULL WHERE id = $1", user.UserID)
+	return err
+}
+
+// GetBudgetMembers - владелец и участники бюджета
+func (db *UsersDB) GetBudgetMembers(ctx context.Context, budget domain.Budget) ([]domain.User, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_budget_members_db")
+	defer span.Finish()
+
+	var members []domain.User = nil
+
+	builder := sq.Select("id", "COALESCE(name, '')").From("users").Where(sq.Or{
+		sq.Eq{"id": budget.ID},
+		sq.Eq{"budget_id": budget.ID},
+	}).OrderBy("id").PlaceholderFormat(sq.Dollar)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return members, err
+	}
+
+	rows, err := db.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return members, err
+	}
+	defer rows.Close()
+
+	members = make([]domain.User, 0)
+	for rows.Next() {
+		var member domain.User
+		if err := rows.Scan(
+			&member.UserID,
+			&member.Name,
+		); err != nil {
+			return members, err
+		}
+		members = append(members, member)
+	}
+
+	if err = rows.Err(); err != nil {
+		return members, err
+	}
+
+	return members, nil
+}
@@ -0,0 +1,15 @@
+package domain
+
+// Budget - общий бюджет; ID совпадает с ID пользователя-владельца,
+// категории, траты и лимиты бюджета хранятся под этим ID
+type Budget struct {
+	ID         int64
+	InviteCode string
+}
+
+// MemberTotal - сумма трат участника бюджета за период
+type MemberTotal struct {
+	UserID int64
+	Name   string
+	Total  int64
+}
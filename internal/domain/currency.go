@@ -1,7 +1,11 @@
 package domain
 
+import "time"
+
 type Currency struct {
 	ID   int
 	Code string
 	Rate float64
+	// день, на который действует курс
+	Date time.Time
 }
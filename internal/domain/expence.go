@@ -9,4 +9,21 @@ type Expence struct {
 	CategoryName string
 	Timestamp    time.Time
 	Total        int64
+
+	// CurrencyID, CurrencyCode, OriginalTotal - валюта и сумма, в которых трата была введена;
+	// Total всегда в системной валюте
+	CurrencyID    int
+	CurrencyCode  string
+	OriginalTotal int64
+
+	// Note - комментарий к трате в свободной форме
+	Note string
+	// Tags - метки траты без символа #, в нижнем регистре
+	Tags []string
+
+	// AddedBy - участник бюджета, добавивший трату
+	AddedBy int64
+
+	// ImportHash - отпечаток строки выписки, по которому повторный импорт не создаёт дублей
+	ImportHash string
 }
@@ -4,4 +4,6 @@ type ExpenceCategory struct {
 	ID     int64
 	UserID int64
 	Name   string
+
+	DefaultMonthLimit int64
 }
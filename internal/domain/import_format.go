@@ -0,0 +1,23 @@
+package domain
+
+// ImportFormat - сопоставление столбцов CSV-выписки банка полям траты;
+// пустые CategoryColumn и DescriptionColumn - столбца в выписке нет
+type ImportFormat struct {
+	Name              string
+	Delimiter         rune
+	DateColumn        string
+	DateFormat        string
+	AmountColumn      string
+	CategoryColumn    string
+	DescriptionColumn string
+
+	// траты в выписке со знаком минус, положительные суммы - поступления
+	NegativeExpences bool
+}
+
+// CategoryKeyword - правило импорта: строки выписки с Keyword в описании относятся к категории
+type CategoryKeyword struct {
+	CategoryID   int64
+	CategoryName string
+	Keyword      string
+}
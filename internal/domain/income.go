@@ -0,0 +1,11 @@
+package domain
+
+import "time"
+
+type Income struct {
+	ID        int64
+	UserID    int64
+	Source    string
+	Timestamp time.Time
+	Total     int64
+}
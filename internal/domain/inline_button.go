@@ -0,0 +1,7 @@
+package domain
+
+// InlineButton - кнопка inline-клавиатуры, Data возвращается в callback при нажатии
+type InlineButton struct {
+	Text string
+	Data string
+}
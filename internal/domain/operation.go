@@ -0,0 +1,25 @@
+package domain
+
+// виды изменений в журнале для /undo
+const (
+	OperationAddExpence     = "add_expence"
+	OperationAddCategory    = "add_category"
+	OperationMonthLimit     = "month_limit"
+	OperationChangeCurrency = "change_currency"
+)
+
+// Operation - запись журнала изменений бюджета, по которой /undo отменяет последнее изменение
+type Operation struct {
+	ID     int64
+	UserID int64
+	// MemberID - участник бюджета, сделавший изменение
+	MemberID int64
+	Kind     string
+
+	// ObjectID - добавленная трата или категория, либо валюта до смены
+	ObjectID int64
+	// MonthLimit - лимит месяца до изменения
+	MonthLimit int64
+	// MonthLimitDelta - на сколько изменение увеличило остаток лимита месяца
+	MonthLimitDelta int64
+}
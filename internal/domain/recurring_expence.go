@@ -0,0 +1,14 @@
+package domain
+
+import "time"
+
+type RecurringExpence struct {
+	ID            int64
+	UserID        int64
+	CategoryID    int64
+	CategoryName  string
+	Total         int64
+	Schedule      string
+	Paused        bool
+	NextTimestamp time.Time
+}
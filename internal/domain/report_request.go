@@ -3,6 +3,10 @@ package domain
 import "time"
 
 type ReportRequest struct {
+	RequestID int64
 	UserID    int64
 	Timestamp time.Time
+
+	// конец периода отчёта, не включая
+	EndTimestamp time.Time
 }
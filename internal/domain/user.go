@@ -5,4 +5,29 @@ type User struct {
 	BaseCurrencyID    uint64
 	DefaultMonthLimit int64
 	CurrentMonthLimit int64
+
+	// Name - имя в Telegram, показывается участникам общего бюджета
+	Name string
+
+	// BudgetID - бюджет, в котором ведёт учёт пользователь: общий, либо собственный
+	BudgetID int64
+	// Timezone - часовой пояс пользователя (Europe/Berlin), пустой - время сервера
+	Timezone string
+	// Language - язык ответов бота (en, ru), пустой - язык из настроек Telegram
+	Language string
+
+	// LimitAlerts - пороги уведомлений в процентах лимита месяца
+	LimitAlerts []int64
+	// LimitAlertFired - наибольший порог, о котором уже уведомили в текущем месяце
+	LimitAlertFired int64
+	// SoftLimit - мягкий режим лимита: траты сверх лимита месяца и категорий сохраняются с предупреждением
+	SoftLimit bool
+}
+
+// LimitAlert - предупреждение о тратах после добавления трат
+type LimitAlert struct {
+	// Threshold - впервые в этом месяце достигнутый порог в процентах лимита месяца, 0 - нет
+	Threshold int64
+	// Overspend - на сколько превышен лимит месяца в базовой валюте, 0 - не превышен
+	Overspend int64
 }
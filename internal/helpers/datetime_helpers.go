@@ -1,6 +1,10 @@
 package helpers
 
-import "time"
+import (
+	"math"
+	"strconv"
+	"time"
+)
 
 func GetNowDateTimeLoc() (int, time.Month, int, *time.Location) {
 	now := time.Now()
@@ -10,16 +14,69 @@ func GetNowDateTimeLoc() (int, time.Month, int, *time.Location) {
 }
 
 func GetStartOfCurrentYear() time.Time {
-	currentYear, _, _, loc := GetNowDateTimeLoc()
-	return time.Date(currentYear, 1, 1, 0, 0, 0, 0, loc)
+	return StartOfYear(time.Now())
 }
 
 func GetStartOfCurrentMonth() time.Time {
-	year, month, _, loc := GetNowDateTimeLoc()
-	return time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	return StartOfMonth(time.Now())
 }
 
 func GetStartOfCurrentDay() time.Time {
-	year, month, day, loc := GetNowDateTimeLoc()
-	return time.Date(year, month, day, 0, 0, 0, 0, loc)
+	return StartOfDay(time.Now())
+}
+
+// GetStartOfCurrentWeek - неделя начинается с понедельника
+func GetStartOfCurrentWeek() time.Time {
+	return StartOfWeek(time.Now())
+}
+
+// начало года, месяца, дня и недели, в которые попадает now, в часовом поясе now
+func StartOfYear(now time.Time) time.Time {
+	return time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location())
+}
+
+func StartOfMonth(now time.Time) time.Time {
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+}
+
+func StartOfDay(now time.Time) time.Time {
+	year, month, day := now.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+}
+
+func StartOfWeek(now time.Time) time.Time {
+	startOfDay := StartOfDay(now)
+	daysSinceMonday := (int(startOfDay.Weekday()) + 6) % 7
+	return startOfDay.AddDate(0, 0, -daysSinceMonday)
+}
+
+// LoadLocation - часовой пояс пользователя, пустой или неизвестный - время сервера
+func LoadLocation(timezone string) *time.Location {
+	if timezone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// LimitMonth - месяц лимита пользователя часового пояса timezone, в который попадает now: его первый день как дата
+func LimitMonth(now time.Time, timezone string) time.Time {
+	return DateOf(StartOfMonth(now.In(LoadLocation(timezone))))
+}
+
+// LongitudeTimezone - часовой пояс с постоянным смещением по долготе: 15° на час.
+// Границы поясов и летнее время так не учесть, но для начала дня и месяца этого хватает
+func LongitudeTimezone(longitude float64) string {
+	offset := int(math.Round(longitude / 15))
+	if offset == 0 {
+		return "Etc/GMT"
+	}
+	// в именах Etc/GMT знак обратный: Etc/GMT-3 - это UTC+3
+	if offset > 0 {
+		return "Etc/GMT-" + strconv.Itoa(offset)
+	}
+	return "Etc/GMT+" + strconv.Itoa(-offset)
 }
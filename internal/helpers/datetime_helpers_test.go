@@ -0,0 +1,56 @@
+package helpers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_LongitudeTimezone(t *testing.T) {
+	tests := []struct {
+		name      string
+		longitude float64
+		want      string
+	}{
+		{"greenwich", 0.5, "Etc/GMT"},
+		{"moscow", 37.6, "Etc/GMT-3"},
+		{"new york", -74, "Etc/GMT+5"},
+		{"vladivostok", 131.9, "Etc/GMT-9"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timezone := LongitudeTimezone(tt.longitude)
+			assert.Equal(t, tt.want, timezone)
+
+			_, err := time.LoadLocation(timezone)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func Test_LoadLocation_UnknownShouldBeServerTime(t *testing.T) {
+	assert.Equal(t, time.Local, LoadLocation(""))
+	assert.Equal(t, time.Local, LoadLocation("Mars/Olympus"))
+	assert.Equal(t, "Europe/Berlin", LoadLocation("Europe/Berlin").String())
+}
+
+func Test_StartOf_ShouldUseTimezoneOfNow(t *testing.T) {
+	tokyo := LoadLocation("Asia/Tokyo")
+	// в UTC ещё 31 октября, в Токио уже 1 ноября
+	now := time.Date(2026, 10, 31, 20, 0, 0, 0, time.UTC).In(tokyo)
+
+	assert.Equal(t, time.Date(2026, 11, 1, 0, 0, 0, 0, tokyo), StartOfDay(now))
+	assert.Equal(t, time.Date(2026, 11, 1, 0, 0, 0, 0, tokyo), StartOfMonth(now))
+	assert.Equal(t, time.Date(2026, 10, 26, 0, 0, 0, 0, tokyo), StartOfWeek(now))
+	assert.Equal(t, time.Date(2026, 1, 1, 0, 0, 0, 0, tokyo), StartOfYear(now))
+
+	today, err := ParseDate("today", now)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), today)
+
+	// месяц лимита - по часовому поясу пользователя, а не сервера
+	assert.Equal(t, time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), LimitMonth(now.In(time.UTC), "Asia/Tokyo"))
+	assert.Equal(t, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), LimitMonth(now, "Europe/Berlin"))
+}
@@ -1,8 +1,100 @@
 package helpers
 
-import "time"
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
 
+var ErrDateWrongFormat = errors.New("wrong date format")
+
+// полная дата: 12/10/2026, 12.10.2026
+var regexpFullDate = regexp.MustCompile(`^(\d{1,2})([./])(\d{1,2})([./])(\d{4})$`)
+
+// дата без года: 12/10
+var regexpShortDate = regexp.MustCompile(`^(\d{1,2})/(\d{1,2})$`)
+
+// ISO дата: 2026-10-12
+var regexpISODate = regexp.MustCompile(`^(\d{4})-(\d{1,2})-(\d{1,2})$`)
+
+// похоже на дату, но могло не разобраться: 31/02, 2026-13-01
+var regexpDateLike = regexp.MustCompile(`^\d{1,4}[./-]\d{1,2}([./-]\d{1,4})?$`)
+
+var relativeDays = map[string]int{
+	"today": 0, "сегодня": 0,
+	"yesterday": -1, "вчера": -1,
+}
+
+var weekdays = map[string]time.Weekday{
+	"monday": time.Monday, "mon": time.Monday, "понедельник": time.Monday, "пн": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "вторник": time.Tuesday, "вт": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday, "среда": time.Wednesday, "среду": time.Wednesday, "ср": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "четверг": time.Thursday, "чт": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday, "пятница": time.Friday, "пятницу": time.Friday, "пт": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday, "суббота": time.Saturday, "субботу": time.Saturday, "сб": time.Saturday,
+	"sunday": time.Sunday, "sun": time.Sunday, "воскресенье": time.Sunday, "вс": time.Sunday,
+}
+
+// StringToDate - дата из текста относительно текущего дня, см. ParseDate
 func StringToDate(stringDate string) (time.Time, error) {
-	rv, err := time.Parse("02/01/2006", stringDate)
-	return rv, err
+	return ParseDate(stringDate, time.Now())
+}
+
+// ParseDate - дата из текста: dd/mm/yyyy, dd.mm.yyyy, dd/mm, yyyy-mm-dd, today/yesterday (сегодня/вчера)
+// или день недели - последний такой день не позже now. Дата без года относится к последнему году,
+// в котором она не позже now. Даты возвращаются полуночью в UTC, как и хранятся траты
+func ParseDate(stringDate string, now time.Time) (time.Time, error) {
+	today := DateOf(now)
+	lower := strings.ToLower(stringDate)
+
+	if days, found := relativeDays[lower]; found {
+		return today.AddDate(0, 0, days), nil
+	}
+	if weekday, found := weekdays[lower]; found {
+		daysAgo := (int(today.Weekday()) - int(weekday) + 7) % 7
+		return today.AddDate(0, 0, -daysAgo), nil
+	}
+
+	if m := regexpFullDate.FindStringSubmatch(stringDate); m != nil {
+		// разделители не смешиваются: 12.10/2026 - не дата
+		if m[2] != m[4] {
+			return time.Time{}, ErrDateWrongFormat
+		}
+		return makeDate(m[5], m[3], m[1])
+	}
+	if m := regexpISODate.FindStringSubmatch(stringDate); m != nil {
+		return makeDate(m[1], m[2], m[3])
+	}
+	if m := regexpShortDate.FindStringSubmatch(stringDate); m != nil {
+		date, err := makeDate(today.Format("2006"), m[2], m[1])
+		if err != nil {
+			return date, err
+		}
+		if date.After(today) {
+			return makeDate(today.AddDate(-1, 0, 0).Format("2006"), m[2], m[1])
+		}
+		return date, nil
+	}
+
+	return time.Time{}, ErrDateWrongFormat
+}
+
+// IsDateLike - текст записан как дата, даже если такой даты нет (31/02)
+func IsDateLike(stringDate string) bool {
+	return regexpDateLike.MatchString(stringDate)
+}
+
+// DateOf - календарный день t полуночью в UTC
+func DateOf(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func makeDate(year, month, day string) (time.Time, error) {
+	date, err := time.Parse("2006-1-2", year+"-"+month+"-"+day)
+	if err != nil {
+		return time.Time{}, ErrDateWrongFormat
+	}
+	return date, nil
 }
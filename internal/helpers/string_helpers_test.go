@@ -0,0 +1,72 @@
+package helpers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseDate(t *testing.T) {
+	// суббота
+	now := time.Date(2026, 10, 17, 15, 30, 0, 0, time.Local)
+
+	tests := []struct {
+		name string
+		text string
+		want time.Time
+	}{
+		{"full date", "12/10/2026", time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)},
+		{"full date without leading zeros", "1/2/2025", time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"dotted date", "12.10.2026", time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)},
+		{"iso date", "2026-10-12", time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)},
+		{"short date", "12/10", time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)},
+		{"short date today", "17/10", time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)},
+		{"short date later this year is last year", "30/12", time.Date(2025, 12, 30, 0, 0, 0, 0, time.UTC)},
+		{"today", "today", time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)},
+		{"yesterday", "Yesterday", time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)},
+		{"сегодня", "сегодня", time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)},
+		{"вчера", "Вчера", time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)},
+		{"weekday", "monday", time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)},
+		{"short weekday", "Fri", time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)},
+		{"weekday is today", "saturday", time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)},
+		{"weekday in russian", "воскресенье", time.Date(2026, 10, 11, 0, 0, 0, 0, time.UTC)},
+		{"weekday in russian accusative", "среду", time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)},
+		{"short weekday in russian", "пт", time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDate(tt.text, now)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_ParseDate_WrongFormat(t *testing.T) {
+	now := time.Date(2026, 10, 17, 15, 30, 0, 0, time.Local)
+
+	tests := []struct {
+		name     string
+		text     string
+		dateLike bool
+	}{
+		{"no such day", "31/02/2026", true},
+		{"no such month", "2026-13-01", true},
+		{"mixed separators", "12.10/2026", true},
+		{"short dotted date is an amount", "12.10", true},
+		{"two digit year", "12/10/26", true},
+		{"word", "coffee", false},
+		{"number", "250", false},
+		{"empty", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseDate(tt.text, now)
+			assert.Equal(t, ErrDateWrongFormat, err)
+			assert.Equal(t, tt.dateLike, IsDateLike(tt.text))
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
+	domain "gitlab.ozon.dev/akosykh114/telegram-bot/internal/domain"
 )
 
 // MockMessageSender is a mock of MessageSender interface.
@@ -46,3 +47,59 @@ func (mr *MockMessageSenderMockRecorder) SendMessage(text, userID interface{}) *
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMessage", reflect.TypeOf((*MockMessageSender)(nil).SendMessage), text, userID)
 }
+
+// SendFormattedMessage mocks base method.
+func (m *MockMessageSender) SendFormattedMessage(text string, userID int64, parseMode string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendFormattedMessage", text, userID, parseMode)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendFormattedMessage indicates an expected call of SendFormattedMessage.
+func (mr *MockMessageSenderMockRecorder) SendFormattedMessage(text, userID, parseMode interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendFormattedMessage", reflect.TypeOf((*MockMessageSender)(nil).SendFormattedMessage), text, userID, parseMode)
+}
+
+// SendPhoto mocks base method.
+func (m *MockMessageSender) SendPhoto(image []byte, caption string, userID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendPhoto", image, caption, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendPhoto indicates an expected call of SendPhoto.
+func (mr *MockMessageSenderMockRecorder) SendPhoto(image, caption, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendPhoto", reflect.TypeOf((*MockMessageSender)(nil).SendPhoto), image, caption, userID)
+}
+
+// SendDocument mocks base method.
+func (m *MockMessageSender) SendDocument(data []byte, fileName, caption string, userID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendDocument", data, fileName, caption, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendDocument indicates an expected call of SendDocument.
+func (mr *MockMessageSenderMockRecorder) SendDocument(data, fileName, caption, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendDocument", reflect.TypeOf((*MockMessageSender)(nil).SendDocument), data, fileName, caption, userID)
+}
+
+// SendMessageWithButtons mocks base method.
+func (m *MockMessageSender) SendMessageWithButtons(text string, userID int64, buttons []domain.InlineButton) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendMessageWithButtons", text, userID, buttons)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendMessageWithButtons indicates an expected call of SendMessageWithButtons.
+func (mr *MockMessageSenderMockRecorder) SendMessageWithButtons(text, userID, buttons interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMessageWithButtons", reflect.TypeOf((*MockMessageSender)(nil).SendMessageWithButtons), text, userID, buttons)
+}
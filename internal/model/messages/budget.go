@@ -0,0 +1,108 @@
+package messages
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/opentracing/opentracing-go"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/common"
+)
+
+var errBudgetNotFound = fmt.Errorf("budget with this invite code was not found")
+var errBudgetHasMembers = fmt.Errorf("other users have joined your budget - you can't join another one")
+var errLeaveOwnBudget = fmt.Errorf("this is your own budget - members can leave it, the owner can't")
+
+// GetBudget - участники бюджета и код приглашения; бюджет создаётся при первом вызове
+func (s *Model) GetBudget(ctx context.Context, budgetID int64, msg Message) (string, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_budget_command")
+	defer span.Finish()
+
+	if err := s.storage.SetUserName(ctx, msg.UserID, msg.UserName); err != nil {
+		return "", errServer
+	}
+
+	code, err := s.storage.GetBudgetInviteCode(ctx, budgetID)
+	if err != nil {
+		return "", errServer
+	}
+
+	members, err := s.storage.GetBudgetMembers(ctx, budgetID)
+	if err != nil {
+		return "", errServer
+	}
+
+	lang := languageFrom(ctx)
+
+	var rvSb strings.Builder
+	rvSb.WriteString(lang.tr("Budget members:") + "\n")
+	for _, member := range members {
+		rvSb.WriteString(memberName(lang, member.UserID, member.Name))
+		if member.UserID == budgetID {
+			rvSb.WriteString(" " + lang.tr("(owner)"))
+		}
+		rvSb.WriteString("\n")
+	}
+	rvSb.WriteString("\n" + lang.tr("Invite code: %s\nTo join send '/%s %s'",
+		code, CommandNameMap[JoinBudgetCmd].Command, code))
+
+	return rvSb.String(), nil
+}
+
+// JoinBudget - вступление в бюджет по коду приглашения; собственные траты пользователя
+// сохраняются и снова доступны после выхода из бюджета; в группе вступает вся группа
+func (s *Model) JoinBudget(ctx context.Context, msg Message, text string) (string, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "join_budget_command")
+	defer span.Finish()
+
+	code := strings.ToUpper(strings.TrimSpace(text))
+	if code == "" || strings.Contains(code, " ") {
+		return "", errWrongCommandFormat
+	}
+
+	if err := s.storage.SetUserName(ctx, msg.UserID, msg.UserName); err != nil {
+		return "", errServer
+	}
+
+	budgetID, err := s.storage.JoinBudget(ctx, msg.chatID(), code)
+	if err != nil {
+		switch err {
+		case common.ErrBudgetNotFound:
+			return "", errBudgetNotFound
+		case common.ErrBudgetHasMembers:
+			return "", errBudgetHasMembers
+		}
+		return "", errServer
+	}
+
+	if budgetID == msg.chatID() {
+		return languageFrom(ctx).tr("You are back in your own budget"), nil
+	}
+	return languageFrom(ctx).tr("You have joined the budget"), nil
+}
+
+func (s *Model) LeaveBudget(ctx context.Context, budgetID int64, userID int64) (string, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "leave_budget_command")
+	defer span.Finish()
+
+	if budgetID == userID {
+		return "", errLeaveOwnBudget
+	}
+
+	if err := s.storage.LeaveBudget(ctx, userID); err != nil {
+		return "", errServer
+	}
+	return languageFrom(ctx).tr("You have left the budget"), nil
+}
+
+// memberName - имя участника, либо id, если имя неизвестно
+func memberName(lang language, userID int64, name string) string {
+	switch {
+	case name != "":
+		return name
+	case userID == 0:
+		// траты, добавленные до появления общих бюджетов
+		return lang.tr("unknown")
+	}
+	return fmt.Sprintf("id%d", userID)
+}
@@ -0,0 +1,284 @@
+package messages
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-redis/redismock/v8"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/database"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/helpers"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/logger"
+	mocks "gitlab.ozon.dev/akosykh114/telegram-bot/internal/mocks/messages"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/storage"
+)
+
+func Test_OnJoinBudgetCommand_ShouldJoinByInviteCode(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, _ := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+
+	columns := []string{"id"}
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(mock.NewRows([]string{"id", "timezone", "language"}).AddRow(123, "", ""))
+	mock.ExpectExec("UPDATE users SET name").WithArgs("@bob", 123).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT budgets.id FROM budgets").WithArgs("ABCD2345", 123).WillReturnRows(mock.NewRows(columns).AddRow(7))
+	mock.ExpectQuery("SELECT COUNT").WithArgs(123).WillReturnRows(mock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec("UPDATE users SET budget_id").WithArgs(7, 123).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("DELETE FROM budgets").WithArgs(123).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	sender.EXPECT().SendMessage("You have joined the budget", int64(123))
+
+	err = model.IncomingCommandMessage(context.Background(), CommandMessage{
+		Message: Message{
+			UserID:   123,
+			UserName: "@bob",
+		},
+		CommandName:      "join_budget",
+		CommandArguments: "abcd2345",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_OnJoinBudgetByCodeOfMemberOfOtherBudget_ShouldAnswerNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, _ := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+
+	columns := []string{"id"}
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(mock.NewRows([]string{"id", "timezone", "language"}).AddRow(123, "", ""))
+	mock.ExpectExec("UPDATE users SET name").WithArgs("@bob", 123).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectBegin()
+	// владелец кода 7 сам вступил в другой бюджет
+	mock.ExpectQuery("SELECT budgets.id FROM budgets JOIN users ON users.id = budgets.id WHERE budgets.invite_code = \\$1 AND \\(users.budget_id IS NULL OR users.id = \\$2\\)").WithArgs("ABCD2345", 123).WillReturnRows(mock.NewRows(columns))
+	mock.ExpectRollback()
+
+	sender.EXPECT().SendMessage(errBudgetNotFound.Error(), int64(123))
+
+	err = model.IncomingCommandMessage(context.Background(), CommandMessage{
+		Message: Message{
+			UserID:   123,
+			UserName: "@bob",
+		},
+		CommandName:      "join_budget",
+		CommandArguments: "abcd2345",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_OnAddExpenceInSharedBudget_ShouldRecordMember(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, mocksRedis := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+
+	mocksRedis.ExpectKeys("7*").SetVal([]string{})
+
+	columns := []string{"id"}
+	date, _ := helpers.StringToDate("12/10/2012")
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(mock.NewRows([]string{"id", "timezone", "language"}).AddRow(7, "", ""))
+	mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("food", 7).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("food", 7).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT base_currency_id").WithArgs(7).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT COALESCE\\(\\(SELECT rate FROM currency_rates").WithArgs(1, sqlmock.AnyArg(), 1).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT limit_month").WithArgs(7).WillReturnRows(mock.NewRows([]string{"limit_month"}))
+	mock.ExpectQuery("INSERT INTO expences").WithArgs(7, 1, date, 10000, 123, "", "{}", 1, 10000).WillReturnRows(mock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+	mock.ExpectExec("INSERT INTO operations").WithArgs(7, 123, "add_expence", 1, 0, 0).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT COALESCE\\(default_month_limit, 0\\), COALESCE\\(current_month_limit, 0\\), limit_alerts, limit_alert_fired FROM users").WithArgs(7).WillReturnRows(
+		mock.NewRows([]string{"default_month_limit", "current_month_limit", "limit_alerts", "limit_alert_fired"}).AddRow(1000000, 990000, "{50,80,100}", 0))
+
+	sender.EXPECT().SendMessage("Expence added", int64(123))
+
+	err = model.IncomingCommandMessage(context.Background(), CommandMessage{
+		Message: Message{
+			UserID: 123,
+		},
+		CommandName:      "add_expence",
+		CommandArguments: "food 100 12/10/2012",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.NoError(t, mocksRedis.ExpectationsWereMet())
+}
+
+func Test_OnAddExpenceInGroupChat_ShouldRecordSenderAndReplyInGroup(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, mocksRedis := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+
+	mocksRedis.ExpectKeys("-100*").SetVal([]string{})
+
+	columns := []string{"id"}
+	date, _ := helpers.StringToDate("12/10/2012")
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(-100).WillReturnRows(mock.NewRows([]string{"id", "timezone", "language"}).AddRow(-100, "", ""))
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(mock.NewRows([]string{"id", "timezone", "language"}).AddRow(123, "", ""))
+	mock.ExpectExec("UPDATE users SET name").WithArgs("@bob", 123).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("food", -100).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("food", -100).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT base_currency_id").WithArgs(-100).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT COALESCE\\(\\(SELECT rate FROM currency_rates").WithArgs(1, sqlmock.AnyArg(), 1).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT limit_month").WithArgs(-100).WillReturnRows(mock.NewRows([]string{"limit_month"}))
+	mock.ExpectQuery("INSERT INTO expences").WithArgs(-100, 1, date, 10000, 123, "", "{}", 1, 10000).WillReturnRows(mock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+	mock.ExpectExec("INSERT INTO operations").WithArgs(-100, 123, "add_expence", 1, 0, 0).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT COALESCE\\(default_month_limit, 0\\), COALESCE\\(current_month_limit, 0\\), limit_alerts, limit_alert_fired FROM users").WithArgs(-100).WillReturnRows(
+		mock.NewRows([]string{"default_month_limit", "current_month_limit", "limit_alerts", "limit_alert_fired"}).AddRow(1000000, 990000, "{50,80,100}", 0))
+
+	sender.EXPECT().SendMessage("Expence added", int64(-100))
+
+	err = model.IncomingCommandMessage(context.Background(), CommandMessage{
+		Message: Message{
+			ChatID:   -100,
+			UserID:   123,
+			UserName: "@bob",
+		},
+		CommandName:      "add_expence",
+		CommandArguments: "food 100 12/10/2012",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.NoError(t, mocksRedis.ExpectationsWereMet())
+}
+
+func Test_OnPlainTextInGroupChat_ShouldIgnoreConversation(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, _ := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(-100).WillReturnRows(mock.NewRows([]string{"id", "timezone", "language"}).AddRow(-100, "", ""))
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(mock.NewRows([]string{"id", "timezone", "language"}).AddRow(123, "", ""))
+	mock.ExpectExec("UPDATE users SET name").WithArgs("@bob", 123).WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err = model.IncomingPlainTextMessage(context.Background(), PlainTextMessage{
+		Message: Message{
+			ChatID:   -100,
+			UserID:   123,
+			UserName: "@bob",
+		},
+		Text: "see you at dinner",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
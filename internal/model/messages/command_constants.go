@@ -6,10 +6,37 @@ const (
 	ResetCmd
 	AddCategoryCmd
 	AddExpenceCmd
+	AddIncomeCmd
 	GetReportCmd
+	ChartCmd
+	ExportCmd
+	ImportRuleCmd
+	BudgetCmd
+	JoinBudgetCmd
+	LeaveBudgetCmd
 	ChangeCurrency
+	TimezoneCmd
+	LanguageCmd
 	SetMonthLimit
 	ResetMonthLimit
+	LimitAlertsCmd
+	LimitModeCmd
+	SetCategoryLimitCmd
+	ListExpencesCmd
+	SearchExpencesCmd
+	EditExpenceCmd
+	DeleteExpenceCmd
+	ListCategoriesCmd
+	RenameCategoryCmd
+	MergeCategoryCmd
+	DeleteCategoryCmd
+	AddRecurringCmd
+	ListRecurringCmd
+	PauseRecurringCmd
+	ResumeRecurringCmd
+	CancelRecurringCmd
+	UndoCmd
+	CancelCmd
 	GetHelpCmd
 )
 
@@ -20,13 +47,40 @@ type CommandInfo struct {
 }
 
 var CommandNameMap = map[int]CommandInfo{
-	StartCmd:        {"start", "Start bot", ""},
-	ResetCmd:        {"reset", "Reset all expence data", ""},
-	AddCategoryCmd:  {"add_category", "Add new category", "<category>"},
-	AddExpenceCmd:   {"add_expence", "Add new expence", "<category> <total> <date>"},
-	GetReportCmd:    {"report", "Get expence report by day/month/year", "?<day/month/year>"},
-	ChangeCurrency:  {"currency", "Change currency", "<USD/CNY/EUR/RUB>"},
-	SetMonthLimit:   {"set_limit", "Set month limit", "<total>"},
-	ResetMonthLimit: {"reset_limit", "Reset month limit", ""},
-	GetHelpCmd:      {"help", "Get help", ""},
+	StartCmd:            {"start", "Start bot", ""},
+	ResetCmd:            {"reset", "Reset all expence data", ""},
+	AddCategoryCmd:      {"add_category", "Add new category", "<category>"},
+	AddExpenceCmd:       {"add_expence", "Add new expence with optional currency, date (today by default), tags and note", "<category> <total> ?<currency> ?<date> ?<#tag> ?<note>"},
+	AddIncomeCmd:        {"add_income", "Add new income", "<source> <total> <date>"},
+	GetReportCmd:        {"report", "Get income, expence and balance report for period, or expences with tag", "?<tag:name> ?<day/week/month/lastmonth/year/<N>d> or <dd/mm/yyyy> <dd/mm/yyyy>"},
+	ChartCmd:            {"chart", "Get chart of expences by category and by day for period", "?<day/week/month/lastmonth/year/<N>d> or <dd/mm/yyyy> <dd/mm/yyyy>"},
+	ExportCmd:           {"export", "Export expences for period as CSV file", "?<day/week/month/lastmonth/year/<N>d> or <dd/mm/yyyy> <dd/mm/yyyy>"},
+	ImportRuleCmd:       {"import_rule", "Put imported statement rows with keyword into category (upload CSV statement to import)", "<keyword> <category>"},
+	BudgetCmd:           {"budget", "Show budget members and invite code to share the budget", ""},
+	JoinBudgetCmd:       {"join_budget", "Join shared budget by invite code", "<code>"},
+	LeaveBudgetCmd:      {"leave_budget", "Leave shared budget and return to your own", ""},
+	ChangeCurrency:      {"currency", "Change currency", "<USD/CNY/EUR/RUB>"},
+	TimezoneCmd:         {"timezone", "Set timezone for dates, reports and month limit reset, or share location", "?<Europe/Berlin>"},
+	LanguageCmd:         {"language", "Set language of replies", "?<en/ru>"},
+	SetMonthLimit:       {"set_limit", "Set month limit", "<total>"},
+	ResetMonthLimit:     {"reset_limit", "Reset month limit", ""},
+	LimitAlertsCmd:      {"limit_alerts", "Set percents of month limit to warn at", "?<50 80 100/off>"},
+	LimitModeCmd:        {"limit_mode", "Set hard or soft month limit mode", "?<hard/soft>"},
+	SetCategoryLimitCmd: {"set_category_limit", "Set month limit for category", "<category> <total>"},
+	ListExpencesCmd:     {"list", "List expences with their ids", "?<page>"},
+	SearchExpencesCmd:   {"search", "Find expences by note", "<text>"},
+	EditExpenceCmd:      {"edit_expence", "Edit expence", "<id> <category> <total> <date>"},
+	DeleteExpenceCmd:    {"delete_expence", "Delete expence", "<id>"},
+	ListCategoriesCmd:   {"categories", "List categories", ""},
+	RenameCategoryCmd:   {"rename_category", "Rename category", "<old> <new>"},
+	MergeCategoryCmd:    {"merge_category", "Move expences to another category and delete the first one", "<from> <into>"},
+	DeleteCategoryCmd:   {"delete_category", "Delete category without expences", "<category>"},
+	AddRecurringCmd:     {"add_recurring", "Add expence charged by schedule", "<category> <total> <cron schedule/@daily/@weekly/@monthly>"},
+	ListRecurringCmd:    {"recurring", "List recurring expences", ""},
+	PauseRecurringCmd:   {"pause_recurring", "Pause recurring expence", "<id>"},
+	ResumeRecurringCmd:  {"resume_recurring", "Resume recurring expence", "<id>"},
+	CancelRecurringCmd:  {"cancel_recurring", "Cancel recurring expence", "<id>"},
+	UndoCmd:             {"undo", "Undo last added expence or category, limit or currency change", ""},
+	CancelCmd:           {"cancel", "Cancel current question of the bot", ""},
+	GetHelpCmd:          {"help", "Get help", ""},
 }
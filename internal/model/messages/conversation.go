@@ -0,0 +1,242 @@
+package messages
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/helpers"
+)
+
+// conversationTimeout - сколько бот ждёт ответа на вопрос о недостающем аргументе;
+// более поздний ответ обрабатывается как обычное сообщение
+const conversationTimeout = 10 * time.Minute
+
+// skipAnswer - ответ, которым пропускается необязательный вопрос
+const skipAnswer = "-"
+
+var errAmountNotPositive = fmt.Errorf("amount must be greater than zero")
+
+// conversationStep - вопрос о недостающем аргументе команды
+type conversationStep struct {
+	// question - вопрос на английском, переводится при отправке
+	question string
+	// categories - предложить категории бюджета кнопками
+	categories bool
+	// skip - аргумент, который подставляется при ответе skipAnswer; пустой - вопрос пропустить нельзя
+	skip string
+	// check - проверка ответа, ответ с ошибкой не принимается и вопрос задаётся снова
+	check func(ctx context.Context, s *Model, userID int64, now time.Time, answer string) error
+}
+
+// conversationCommand - команда, недостающие аргументы которой бот спрашивает по одному
+type conversationCommand struct {
+	steps []conversationStep
+	// required - сколько первых аргументов обязательны; если они все указаны, команда выполняется сразу
+	required int
+}
+
+var categoryStep = conversationStep{
+	question:   "Which category?",
+	categories: true,
+	check: func(ctx context.Context, s *Model, userID int64, _ time.Time, answer string) error {
+		if strings.Contains(answer, " ") {
+			return errWrongCommandFormat
+		}
+		if !s.storage.IsCategoryExists(ctx, userID, answer) {
+			return errCategoryNotFound
+		}
+		return nil
+	},
+}
+
+var sourceStep = conversationStep{
+	question: "Which source of income?",
+	check: func(_ context.Context, _ *Model, _ int64, _ time.Time, answer string) error {
+		if strings.Contains(answer, " ") {
+			return errWrongCommandFormat
+		}
+		return nil
+	},
+}
+
+var amountStep = conversationStep{
+	question: "How much?",
+	check: func(_ context.Context, _ *Model, _ int64, _ time.Time, answer string) error {
+		total, err := helpers.ConvertStringAmountToSub(answer)
+		if err != nil {
+			return err
+		}
+		// минус отбрасывается при разборе суммы, поэтому проверяется и сам ответ
+		if total <= 0 || strings.HasPrefix(answer, "-") {
+			return errAmountNotPositive
+		}
+		return nil
+	},
+}
+
+var dateStep = conversationStep{
+	question: "Which date?",
+	check: func(_ context.Context, _ *Model, _ int64, now time.Time, answer string) error {
+		date, err := helpers.ParseDate(answer, now)
+		if err != nil {
+			return errDateWrongFormat
+		}
+		return checkDateAhead(date, now)
+	},
+}
+
+// optionalDateStep - дата траты необязательна, по умолчанию - сегодня
+var optionalDateStep = conversationStep{
+	question: "Which date? Send - for today",
+	skip:     "today",
+	check:    dateStep.check,
+}
+
+// conversationCommands - команды, которые можно отправить без аргументов и ответить на вопросы бота
+var conversationCommands = map[int]conversationCommand{
+	AddExpenceCmd: {steps: []conversationStep{categoryStep, amountStep, optionalDateStep}, required: 2},
+	AddIncomeCmd:  {steps: []conversationStep{sourceStep, amountStep, dateStep}, required: 3},
+}
+
+// conversation - команда, ожидающая ответа на вопрос о недостающем аргументе
+type conversation struct {
+	Command int
+	// Args - уже собранные аргументы, следующий вопрос - steps[len(Args)]
+	Args      []string
+	ExpiresAt time.Time
+}
+
+func (c conversation) step() conversationStep {
+	return conversationCommands[c.Command].steps[len(c.Args)]
+}
+
+// StartConversation - если у команды не хватает обязательных аргументов, бот начинает спрашивать их по одному;
+// false - аргументов достаточно и команду нужно выполнить как обычно
+func (s *Model) StartConversation(ctx context.Context, userID int64, member chatMember, now time.Time, command int, text string) (string, bool, error) {
+	conversationCommand, found := conversationCommands[command]
+	if !found {
+		return "", false, nil
+	}
+
+	args := strings.Fields(text)
+	if len(args) >= conversationCommand.required {
+		return "", false, nil
+	}
+
+	span, ctx := opentracing.StartSpanFromContext(ctx, "start_conversation")
+	defer span.Finish()
+
+	// указанные аргументы проверяются сразу, чтобы не спрашивать остальные зря
+	for i, arg := range args {
+		if err := conversationCommand.steps[i].check(ctx, s, userID, now, arg); err != nil {
+			return "", true, err
+		}
+	}
+
+	conv := conversation{Command: command, Args: args, ExpiresAt: time.Now().Add(conversationTimeout)}
+	s.saveConversation(member, conv)
+
+	return languageFrom(ctx).tr(conv.step().question), true, nil
+}
+
+// ContinueConversation - ответ на вопрос бота; false - вопросов не задано, текст нужно обработать как обычно
+func (s *Model) ContinueConversation(ctx context.Context, userID int64, member chatMember, now time.Time, text string) (string, bool, error) {
+	conv, found := s.popConversation(member)
+	if !found {
+		return "", false, nil
+	}
+
+	span, ctx := opentracing.StartSpanFromContext(ctx, "continue_conversation")
+	defer span.Finish()
+
+	lang := languageFrom(ctx)
+	answer := strings.TrimSpace(text)
+	if step := conv.step(); step.skip != "" && answer == skipAnswer {
+		answer = step.skip
+	}
+
+	if err := conv.step().check(ctx, s, userID, now, answer); err != nil {
+		conv.ExpiresAt = time.Now().Add(conversationTimeout)
+		s.saveConversation(member, conv)
+		return lang.errorText(err) + "\n" + lang.tr(conv.step().question), true, nil
+	}
+
+	conv.Args = append(conv.Args, answer)
+	if len(conv.Args) < len(conversationCommands[conv.Command].steps) {
+		conv.ExpiresAt = time.Now().Add(conversationTimeout)
+		s.saveConversation(member, conv)
+		return lang.tr(conv.step().question), true, nil
+	}
+
+	answer, err := s.runConversation(ctx, userID, member, now, conv)
+	return answer, true, err
+}
+
+// runConversation - выполнение команды, когда все аргументы собраны
+func (s *Model) runConversation(ctx context.Context, userID int64, member chatMember, now time.Time, conv conversation) (string, error) {
+	args := strings.Join(conv.Args, " ")
+
+	switch conv.Command {
+	case AddExpenceCmd:
+		return s.AddExpence(ctx, userID, member.UserID, now, args)
+	case AddIncomeCmd:
+		return s.AddIncome(ctx, userID, now, args)
+	}
+	return "", errServer
+}
+
+// Cancel - отмена вопросов бота: о недостающих аргументах, о категории траты из текста и об импорте выписки
+func (s *Model) Cancel(ctx context.Context, member chatMember, pending bool) string {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "cancel_command")
+	defer span.Finish()
+
+	if _, imported := s.popImport(member); imported {
+		pending = true
+	}
+
+	lang := languageFrom(ctx)
+	if !pending {
+		return lang.tr("Nothing to cancel")
+	}
+	return lang.tr("Cancelled")
+}
+
+// awaitsCategory - следующий ответ участника - категория, её можно выбрать кнопкой
+func (s *Model) awaitsCategory(member chatMember) bool {
+	if conv, found := s.peekConversation(member); found {
+		return conv.step().categories
+	}
+	draft, pending := s.peekDraft(member)
+	return pending && draft.needsCategory()
+}
+
+func (s *Model) popConversation(member chatMember) (conversation, bool) {
+	s.conversationsMu.Lock()
+	defer s.conversationsMu.Unlock()
+
+	conv, found := s.conversations[member]
+	delete(s.conversations, member)
+	return conv, found && time.Now().Before(conv.ExpiresAt)
+}
+
+func (s *Model) peekConversation(member chatMember) (conversation, bool) {
+	s.conversationsMu.Lock()
+	defer s.conversationsMu.Unlock()
+
+	conv, found := s.conversations[member]
+	if found && !time.Now().Before(conv.ExpiresAt) {
+		delete(s.conversations, member)
+		return conversation{}, false
+	}
+	return conv, found
+}
+
+func (s *Model) saveConversation(member chatMember, conv conversation) {
+	s.conversationsMu.Lock()
+	defer s.conversationsMu.Unlock()
+
+	s.conversations[member] = conv
+}
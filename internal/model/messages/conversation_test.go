@@ -0,0 +1,353 @@
+package messages
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-redis/redismock/v8"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/database"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/domain"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/helpers"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/logger"
+	mocks "gitlab.ozon.dev/akosykh114/telegram-bot/internal/mocks/messages"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/storage"
+)
+
+func Test_OnAddExpenceWithoutArgs_ShouldAskForEachArg(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, mocksRedis := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+
+	mocksRedis.ExpectKeys("123*").SetVal([]string{})
+
+	columns := []string{"id"}
+	budgetRows := func() *sqlmock.Rows {
+		return mock.NewRows([]string{"id", "timezone", "language"}).AddRow(123, "", "")
+	}
+	date, _ := helpers.StringToDate("12/10/2026")
+
+	// /add_expence
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(budgetRows())
+	mock.ExpectQuery("SELECT id, name FROM expence_category").WithArgs(123).WillReturnRows(mock.NewRows([]string{"id", "name"}).AddRow(1, "food"))
+	// food
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(budgetRows())
+	mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("food", 123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	// abc
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(budgetRows())
+	// -5
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(budgetRows())
+	// 100
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(budgetRows())
+	// 12/10/2026
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(budgetRows())
+	mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("food", 123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("food", 123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT base_currency_id").WithArgs(123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT COALESCE\\(\\(SELECT rate FROM currency_rates").WithArgs(1, date, 1).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT limit_month").WithArgs(123).WillReturnRows(mock.NewRows([]string{"limit_month"}))
+	mock.ExpectQuery("UPDATE users SET current_month_limit").WithArgs(10000, 123).WillReturnRows(mock.NewRows([]string{"current_month_limit", "soft_limit"}).AddRow(90000, false))
+	mock.ExpectQuery("UPDATE expence_category SET current_month_limit").WithArgs(10000, 1).WillReturnRows(
+		mock.NewRows([]string{"current_month_limit", "name"}))
+	mock.ExpectQuery("INSERT INTO expences").WithArgs(123, 1, date, 10000, 123, "", "{}", 1, 10000).WillReturnRows(mock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+	mock.ExpectExec("INSERT INTO operations").WithArgs(123, 123, "add_expence", 1, 0, 0).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT COALESCE\\(default_month_limit, 0\\), COALESCE\\(current_month_limit, 0\\), limit_alerts, limit_alert_fired FROM users").WithArgs(123).WillReturnRows(
+		mock.NewRows([]string{"default_month_limit", "current_month_limit", "limit_alerts", "limit_alert_fired"}).AddRow(1000000, 990000, "{50,80,100}", 0))
+
+	gomock.InOrder(
+		sender.EXPECT().SendMessageWithButtons("Which category?", int64(123), []domain.InlineButton{
+			{Text: "food", Data: "category:1"},
+		}),
+		sender.EXPECT().SendMessage("How much?", int64(123)),
+		sender.EXPECT().SendMessage("invalid amount\nHow much?", int64(123)),
+		sender.EXPECT().SendMessage("amount must be greater than zero\nHow much?", int64(123)),
+		sender.EXPECT().SendMessage("Which date? Send - for today", int64(123)),
+		sender.EXPECT().SendMessage("Expence added", int64(123)),
+	)
+
+	err = model.IncomingCommandMessage(context.Background(), CommandMessage{
+		Message: Message{
+			UserID: 123,
+		},
+		CommandName: "add_expence",
+	})
+	assert.NoError(t, err)
+
+	for _, text := range []string{"food", "abc", "-5", "100", "12/10/2026"} {
+		err = model.IncomingPlainTextMessage(context.Background(), PlainTextMessage{
+			Message: Message{
+				UserID: 123,
+			},
+			Text: text,
+		})
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.NoError(t, mocksRedis.ExpectationsWereMet())
+}
+
+func Test_OnSkipDateAnswer_ShouldAddExpenceForToday(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, mocksRedis := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+
+	mocksRedis.ExpectKeys("123*").SetVal([]string{})
+
+	columns := []string{"id"}
+	budgetRows := func() *sqlmock.Rows {
+		return mock.NewRows([]string{"id", "timezone", "language"}).AddRow(123, "", "")
+	}
+	today := helpers.DateOf(time.Now())
+
+	// /add_expence food
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(budgetRows())
+	mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("food", 123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	// 100
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(budgetRows())
+	// -
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(budgetRows())
+	mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("food", 123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("food", 123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT base_currency_id").WithArgs(123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT COALESCE\\(\\(SELECT rate FROM currency_rates").WithArgs(1, today, 1).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT limit_month").WithArgs(123).WillReturnRows(mock.NewRows([]string{"limit_month"}))
+	mock.ExpectQuery("UPDATE users SET current_month_limit").WithArgs(10000, 123).WillReturnRows(mock.NewRows([]string{"current_month_limit", "soft_limit"}).AddRow(90000, false))
+	mock.ExpectQuery("UPDATE expence_category SET current_month_limit").WithArgs(10000, 1).WillReturnRows(
+		mock.NewRows([]string{"current_month_limit", "name"}))
+	mock.ExpectQuery("INSERT INTO expences").WithArgs(123, 1, today, 10000, 123, "", "{}", 1, 10000).WillReturnRows(mock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+	mock.ExpectExec("INSERT INTO operations").WithArgs(123, 123, "add_expence", 1, 0, 0).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT COALESCE\\(default_month_limit, 0\\), COALESCE\\(current_month_limit, 0\\), limit_alerts, limit_alert_fired FROM users").WithArgs(123).WillReturnRows(
+		mock.NewRows([]string{"default_month_limit", "current_month_limit", "limit_alerts", "limit_alert_fired"}).AddRow(1000000, 990000, "{50,80,100}", 0))
+
+	gomock.InOrder(
+		sender.EXPECT().SendMessage("How much?", int64(123)),
+		sender.EXPECT().SendMessage("Which date? Send - for today", int64(123)),
+		sender.EXPECT().SendMessage("Expence added", int64(123)),
+	)
+
+	err = model.IncomingCommandMessage(context.Background(), CommandMessage{
+		Message: Message{
+			UserID: 123,
+		},
+		CommandName:      "add_expence",
+		CommandArguments: "food",
+	})
+	assert.NoError(t, err)
+
+	for _, text := range []string{"100", "-"} {
+		err = model.IncomingPlainTextMessage(context.Background(), PlainTextMessage{
+			Message: Message{
+				UserID: 123,
+			},
+			Text: text,
+		})
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.NoError(t, mocksRedis.ExpectationsWereMet())
+}
+
+func Test_OnCancelCommand_ShouldStopAsking(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, _ := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+
+	budgetRows := func() *sqlmock.Rows {
+		return mock.NewRows([]string{"id", "timezone", "language"}).AddRow(123, "", "")
+	}
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(budgetRows())
+	mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("food", 123).WillReturnRows(mock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(budgetRows())
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(budgetRows())
+
+	gomock.InOrder(
+		sender.EXPECT().SendMessage("How much?", int64(123)),
+		sender.EXPECT().SendMessage("Cancelled", int64(123)),
+		sender.EXPECT().SendMessage("Nothing to cancel", int64(123)),
+	)
+
+	for _, command := range []CommandMessage{
+		{Message: Message{UserID: 123}, CommandName: "add_expence", CommandArguments: "food"},
+		{Message: Message{UserID: 123}, CommandName: "cancel"},
+		{Message: Message{UserID: 123}, CommandName: "cancel"},
+	} {
+		err = model.IncomingCommandMessage(context.Background(), command)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_OnAnswerAfterTimeout_ShouldNotContinueConversation(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, _ := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+
+	member := chatMember{ChatID: 123, UserID: 123}
+	model.saveConversation(member, conversation{
+		Command:   AddExpenceCmd,
+		Args:      []string{"food"},
+		ExpiresAt: time.Now().Add(-time.Minute),
+	})
+
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(mock.NewRows([]string{"id", "timezone", "language"}).AddRow(123, "", ""))
+
+	sender.EXPECT().SendMessage(model.Help(context.Background()), int64(123))
+
+	err = model.IncomingPlainTextMessage(context.Background(), PlainTextMessage{
+		Message: Message{
+			UserID: 123,
+		},
+		Text: "hello",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_OnTextAfterDraftTimeout_ShouldNotCompleteDraft(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, _ := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+
+	// трата "250" ждала категорию дольше таймаута
+	member := chatMember{ChatID: 123, UserID: 123}
+	model.drafts[member] = expenceDraft{
+		Amounts:   []int64{25000},
+		ExpiresAt: time.Now().Add(-time.Minute),
+	}
+
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(mock.NewRows([]string{"id", "timezone", "language"}).AddRow(123, "", ""))
+
+	sender.EXPECT().SendMessage(model.Help(context.Background()), int64(123))
+
+	err = model.IncomingPlainTextMessage(context.Background(), PlainTextMessage{
+		Message: Message{
+			UserID: 123,
+		},
+		Text: "food",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Empty(t, model.drafts)
+}
@@ -0,0 +1,124 @@
+package messages
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/opentracing/opentracing-go"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/domain"
+)
+
+// префикс метки в периоде отчёта: /report tag:vacation month
+const reportTagPrefix = "tag:"
+
+// количество трат в ответе /search
+const searchResultSize = 20
+
+var errEmptySearch = fmt.Errorf(fmt.Sprintf("nothing to search - use '/%s %s'", CommandNameMap[SearchExpencesCmd].Command, CommandNameMap[SearchExpencesCmd].Format))
+
+// parseExpenceNote - метки (#vacation) и комментарий из аргументов траты после даты;
+// метки приводятся к нижнему регистру, повторы убираются
+func parseExpenceNote(args []string) (string, []string) {
+	var tags []string
+	var words []string
+	seen := make(map[string]bool)
+
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "#") {
+			if arg != "" {
+				words = append(words, arg)
+			}
+			continue
+		}
+		tag := normalizeTag(arg)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+
+	return strings.Join(words, " "), tags
+}
+
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimLeft(tag, "#"))
+}
+
+// parseReportTag - метка "tag:vacation" из аргументов отчёта и оставшийся текст периода
+func parseReportTag(text string) (string, string) {
+	var tag string
+	var rest []string
+	for _, arg := range strings.Fields(text) {
+		if strings.HasPrefix(arg, reportTagPrefix) {
+			tag = normalizeTag(strings.TrimPrefix(arg, reportTagPrefix))
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return tag, strings.Join(rest, " ")
+}
+
+// getTagReport - траты с меткой tag за период по категориям
+func (s *Model) getTagReport(ctx context.Context, userID int64, tag string, period reportPeriod) (string, error) {
+	totalMap, err := s.storage.GetTagExpencesMap(ctx, userID, tag, period.Start, period.End)
+	if err != nil {
+		return "", errServer
+	}
+
+	lang := languageFrom(ctx)
+	if len(totalMap) == 0 {
+		return lang.tr("No expences tagged #%s!", tag), nil
+	}
+
+	return formatTagReport(lang, lang.tr("%s tagged #%s", period.Title, tag), totalMap), nil
+}
+
+// SearchExpences - последние траты, в комментарии которых встречается текст
+func (s *Model) SearchExpences(ctx context.Context, userID int64, text string) (string, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "search_expences_command")
+	defer span.Finish()
+
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", errEmptySearch
+	}
+
+	expences, err := s.storage.SearchExpences(ctx, userID, text, searchResultSize)
+	if err != nil {
+		return "", errServer
+	}
+
+	lang := languageFrom(ctx)
+	if len(expences) == 0 {
+		return lang.tr("No expences found!"), nil
+	}
+
+	var rvSb strings.Builder
+	rvSb.WriteString(lang.tr("Expences with '%s'", text) + "\n")
+	for _, expence := range expences {
+		rvSb.WriteString(formatExpenceLine(lang, expence))
+	}
+	return rvSb.String(), nil
+}
+
+// formatExpenceLine - трата в списке: id, дата, категория, сумма, исходная сумма в другой валюте,
+// метки и комментарий
+func formatExpenceLine(lang language, expence domain.Expence) string {
+	line := fmt.Sprintf("#%d %s %s: %s",
+		expence.ID,
+		lang.date(expence.Timestamp),
+		expence.CategoryName,
+		lang.amount(expence.Total))
+	if expence.CurrencyCode != "" {
+		line += fmt.Sprintf(" (%s %s)", lang.amount(expence.OriginalTotal), expence.CurrencyCode)
+	}
+	for _, tag := range expence.Tags {
+		line += " #" + tag
+	}
+	if expence.Note != "" {
+		line += " " + expence.Note
+	}
+	return line + "\n"
+}
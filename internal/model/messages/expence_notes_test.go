@@ -0,0 +1,212 @@
+package messages
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-redis/redismock/v8"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/database"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/helpers"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/logger"
+	mocks "gitlab.ozon.dev/akosykh114/telegram-bot/internal/mocks/messages"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/storage"
+)
+
+func Test_ParseExpenceNote(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		note string
+		tags []string
+	}{
+		{"empty", nil, "", nil},
+		{"note only", []string{"dinner", "with", "clients"}, "dinner with clients", nil},
+		{"tags and note", []string{"#vacation", "dinner", "#Work", "with", "clients"}, "dinner with clients", []string{"vacation", "work"}},
+		{"repeated tag", []string{"#vacation", "#VACATION", "#"}, "", []string{"vacation"}},
+		{"double spaces", []string{"", "dinner", "", "out"}, "dinner out", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			note, tags := parseExpenceNote(tt.args)
+			assert.Equal(t, tt.note, note)
+			assert.Equal(t, tt.tags, tags)
+		})
+	}
+}
+
+func Test_ParseReportTag(t *testing.T) {
+	tag, rest := parseReportTag("tag:Vacation 01/10/2026 31/10/2026")
+	assert.Equal(t, "vacation", tag)
+	assert.Equal(t, "01/10/2026 31/10/2026", rest)
+
+	tag, rest = parseReportTag("month")
+	assert.Equal(t, "", tag)
+	assert.Equal(t, "month", rest)
+}
+
+func Test_OnAddExpenceWithTagsAndNote_ShouldStoreThem(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, mocksRedis := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+
+	mocksRedis.ExpectKeys("123*").SetVal([]string{})
+
+	columns := []string{"id"}
+	date, _ := helpers.StringToDate("12/10/2012")
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(mock.NewRows([]string{"id", "timezone", "language"}).AddRow(123, "", ""))
+	mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("food", 123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("food", 123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT base_currency_id").WithArgs(123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT COALESCE\\(\\(SELECT rate FROM currency_rates").WithArgs(1, sqlmock.AnyArg(), 1).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT limit_month").WithArgs(123).WillReturnRows(mock.NewRows([]string{"limit_month"}))
+	mock.ExpectQuery("INSERT INTO expences").WithArgs(123, 1, date, 50000, 123, "dinner with clients", "{\"vacation\"}", 1, 50000).WillReturnRows(mock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+	mock.ExpectExec("INSERT INTO operations").WithArgs(123, 123, "add_expence", 1, 0, 0).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT COALESCE\\(default_month_limit, 0\\), COALESCE\\(current_month_limit, 0\\), limit_alerts, limit_alert_fired FROM users").WithArgs(123).WillReturnRows(
+		mock.NewRows([]string{"default_month_limit", "current_month_limit", "limit_alerts", "limit_alert_fired"}).AddRow(1000000, 990000, "{50,80,100}", 0))
+
+	sender.EXPECT().SendMessage("Expence added", int64(123))
+
+	err = model.IncomingCommandMessage(context.Background(), CommandMessage{
+		Message: Message{
+			UserID: 123,
+		},
+		CommandName:      "add_expence",
+		CommandArguments: "food 500 12/10/2012 #vacation dinner with clients",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.NoError(t, mocksRedis.ExpectationsWereMet())
+}
+
+func Test_OnTagReportCommand_ShouldAnswerWithTaggedExpences(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, _ := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+
+	columns := []string{"id"}
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(mock.NewRows([]string{"id", "timezone", "language"}).AddRow(123, "", ""))
+	mock.ExpectQuery("SELECT base_currency_id").WithArgs(123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT COALESCE\\(\\(SELECT rate FROM currency_rates").WithArgs(1, sqlmock.AnyArg(), 1).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT expence_category.name, SUM\\(expences.total\\)").WithArgs(123, "{\"vacation\"}", sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnRows(
+		mock.NewRows([]string{"name", "sum"}).
+			AddRow("food", 50000).
+			AddRow("taxi", 10000))
+
+	sender.EXPECT().SendFormattedMessage("<b>Expences from 01/10/2026 to 31/10/2026 tagged #vacation</b>\n<pre>"+
+		"food  500.00  83.3% ██████████\n"+
+		"taxi  100.00  16.7% ██\n"+
+		"────────────\n"+
+		"Total 600.00</pre>", int64(123), "HTML")
+
+	err = model.IncomingCommandMessage(context.Background(), CommandMessage{
+		Message: Message{
+			UserID: 123,
+		},
+		CommandName:      "report",
+		CommandArguments: "tag:vacation 01/10/2026 31/10/2026",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_OnSearchCommand_ShouldAnswerWithMatchingExpences(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, _ := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+
+	columns := []string{"id"}
+	date, _ := helpers.StringToDate("12/10/2026")
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(mock.NewRows([]string{"id", "timezone", "language"}).AddRow(123, "", ""))
+	mock.ExpectQuery("SELECT base_currency_id").WithArgs(123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT COALESCE\\(\\(SELECT rate FROM currency_rates").WithArgs(1, sqlmock.AnyArg(), 1).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("ILIKE").WithArgs(123, "%50\\%%").WillReturnRows(
+		mock.NewRows([]string{"id", "category_id", "name", "ts", "total", "note", "tags", "currency_id", "code", "original_total"}).
+			AddRow(7, 1, "food", date, 50000, "dinner, 50% off", "{}", 1, "RUB", 50000))
+
+	sender.EXPECT().SendMessage("Expences with '50%'\n#7 12/10/2026 food: 500.00 dinner, 50% off\n", int64(123))
+
+	err = model.IncomingCommandMessage(context.Background(), CommandMessage{
+		Message: Message{
+			UserID: 123,
+		},
+		CommandName:      "search",
+		CommandArguments: "50%",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,152 @@
+package messages
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/helpers"
+)
+
+// expenceDraft - трата, разобранная из свободного текста ("coffee 250", "такси 1200 вчера")
+type expenceDraft struct {
+	Categories []string
+	Amounts    []int64
+	Currency   string
+	Date       time.Time
+	// ExpiresAt - черновик ждёт уточнения столько же, сколько вопрос команды
+	ExpiresAt time.Time
+}
+
+// число с необязательными разделителями: 250 | 3400,50 | 12.5
+var regexpAmount = regexp.MustCompile(`^\d+([.,]\d{1,2})?$`)
+
+// группа разрядов после пробела: "3 400,50" -> 3 + 400,50
+var regexpAmountGroup = regexp.MustCompile(`^\d{3}([.,]\d{1,2})?$`)
+
+var currencyAliases = map[string]string{
+	"rub": "RUB", "руб": "RUB", "руб.": "RUB", "р": "RUB", "р.": "RUB", "₽": "RUB",
+	"usd": "USD", "$": "USD",
+	"eur": "EUR", "€": "EUR",
+	"cny": "CNY", "¥": "CNY",
+}
+
+// parseExpenceText - разбор свободного текста на категорию, сумму, дату относительно now и валюту
+func parseExpenceText(text string, now time.Time) expenceDraft {
+	var draft expenceDraft
+	tokens := strings.Fields(text)
+
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		lower := strings.ToLower(token)
+
+		if date, err := helpers.ParseDate(token, now); err == nil {
+			draft.Date = date
+			continue
+		}
+		if currency, found := currencyAliases[lower]; found {
+			draft.Currency = currency
+			continue
+		}
+
+		// символ валюты может быть записан слитно с суммой: 250₽, $5
+		number, currency := splitCurrencySymbol(token)
+		if currency != "" {
+			draft.Currency = currency
+		}
+		if !regexpAmount.MatchString(number) {
+			draft.Categories = append(draft.Categories, token)
+			continue
+		}
+
+		// склейка разрядов, записанных через пробел, пока не встретилась дробная часть
+		for !strings.ContainsAny(number, ".,") && i+1 < len(tokens) && regexpAmountGroup.MatchString(tokens[i+1]) {
+			number += tokens[i+1]
+			i++
+		}
+
+		total, err := helpers.ConvertStringAmountToSub(strings.Replace(number, ",", ".", 1))
+		if err != nil {
+			draft.Categories = append(draft.Categories, token)
+			continue
+		}
+		draft.Amounts = append(draft.Amounts, total)
+	}
+
+	return draft
+}
+
+// parseCurrencyArg - валюта, записанная кодом или символом; available - коды валют из конфигурации.
+// Другие слова из трёх букв - начало комментария: food 500 new year dinner
+func parseCurrencyArg(token string, available map[string]bool) (string, bool) {
+	if currency, found := currencyAliases[strings.ToLower(token)]; found {
+		return currency, true
+	}
+	if code := strings.ToUpper(token); available[code] {
+		return code, true
+	}
+	return "", false
+}
+
+func splitCurrencySymbol(token string) (string, string) {
+	for _, symbol := range []string{"₽", "$", "€", "¥"} {
+		if strings.HasPrefix(token, symbol) {
+			return strings.TrimPrefix(token, symbol), currencyAliases[symbol]
+		}
+		if strings.HasSuffix(token, symbol) {
+			return strings.TrimSuffix(token, symbol), currencyAliases[symbol]
+		}
+	}
+	return token, ""
+}
+
+// isExpence - текст похож на трату, только если в нём нашлась сумма
+func (d expenceDraft) isExpence() bool {
+	return len(d.Amounts) > 0
+}
+
+// needsCategory - категория не указана или указана неоднозначно
+func (d expenceDraft) needsCategory() bool {
+	return len(d.Categories) != 1
+}
+
+// merge - дополнение черновика ответом пользователя на уточняющий вопрос
+func (d expenceDraft) merge(reply expenceDraft) expenceDraft {
+	if d.needsCategory() && len(reply.Categories) > 0 {
+		d.Categories = reply.Categories
+	}
+	if len(d.Amounts) != 1 && len(reply.Amounts) > 0 {
+		d.Amounts = reply.Amounts
+	}
+	if reply.Currency != "" {
+		d.Currency = reply.Currency
+	}
+	if !reply.Date.IsZero() {
+		d.Date = reply.Date
+	}
+	return d
+}
+
+// question - уточняющий вопрос на языке lang, если по тексту нельзя однозначно определить трату
+func (d expenceDraft) question(lang language) string {
+	switch {
+	case len(d.Categories) == 0:
+		return lang.tr("Which category?")
+	case len(d.Categories) > 1:
+		return lang.tr("Which category: %s?", strings.Join(d.Categories, ", "))
+	case len(d.Amounts) == 0:
+		return lang.tr("How much was spent on %s?", d.Categories[0])
+	case len(d.Amounts) > 1:
+		amounts := make([]string, 0, len(d.Amounts))
+		for _, v := range d.Amounts {
+			amounts = append(amounts, lang.amount(v))
+		}
+		// в русском формате запятая - десятичный разделитель, поэтому суммы через точку с запятой
+		separator := ", "
+		if lang == langRussian {
+			separator = "; "
+		}
+		return lang.tr("Which amount: %s?", strings.Join(amounts, separator))
+	}
+	return ""
+}
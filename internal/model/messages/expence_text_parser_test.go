@@ -0,0 +1,106 @@
+package messages
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseExpenceText(t *testing.T) {
+	now := time.Date(2026, 10, 17, 15, 30, 0, 0, time.Local)
+
+	tests := []struct {
+		name string
+		text string
+		want expenceDraft
+	}{
+		{
+			name: "category and amount",
+			text: "coffee 250",
+			want: expenceDraft{Categories: []string{"coffee"}, Amounts: []int64{25000}},
+		},
+		{
+			name: "relative date",
+			text: "taxi 1200 yesterday",
+			want: expenceDraft{Categories: []string{"taxi"}, Amounts: []int64{120000}, Date: time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "grouped amount with comma and short date",
+			text: "продукты 3 400,50 12/10",
+			want: expenceDraft{
+				Categories: []string{"продукты"},
+				Amounts:    []int64{340050},
+				Date:       time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "short date ahead is last year",
+			text: "taxi 500 20/12",
+			want: expenceDraft{
+				Categories: []string{"taxi"},
+				Amounts:    []int64{50000},
+				Date:       time.Date(2025, 12, 20, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "currency code",
+			text: "food 12.50 EUR",
+			want: expenceDraft{Categories: []string{"food"}, Amounts: []int64{1250}, Currency: "EUR"},
+		},
+		{
+			name: "currency symbol attached",
+			text: "$5 coffee",
+			want: expenceDraft{Categories: []string{"coffee"}, Amounts: []int64{500}, Currency: "USD"},
+		},
+		{
+			name: "full date",
+			text: "вчера кафе 500 01.10.2026",
+			want: expenceDraft{
+				Categories: []string{"кафе"},
+				Amounts:    []int64{50000},
+				Date:       time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "iso date",
+			text: "taxi 500 2026-10-01",
+			want: expenceDraft{
+				Categories: []string{"taxi"},
+				Amounts:    []int64{50000},
+				Date:       time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "no amount",
+			text: "some text",
+			want: expenceDraft{Categories: []string{"some", "text"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseExpenceText(tt.text, now))
+		})
+	}
+}
+
+func Test_ExpenceDraftQuestion(t *testing.T) {
+	tests := []struct {
+		name  string
+		draft expenceDraft
+		want  string
+	}{
+		{"complete", expenceDraft{Categories: []string{"food"}, Amounts: []int64{100}}, ""},
+		{"no category", expenceDraft{Amounts: []int64{100}}, "Which category?"},
+		{"several categories", expenceDraft{Categories: []string{"dinner", "taxi"}, Amounts: []int64{100}}, "Which category: dinner, taxi?"},
+		{"no amount", expenceDraft{Categories: []string{"food"}}, "How much was spent on food?"},
+		{"several amounts", expenceDraft{Categories: []string{"food"}, Amounts: []int64{100, 25050}}, "Which amount: 1.00, 250.50?"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.draft.question(langEnglish))
+		})
+	}
+}
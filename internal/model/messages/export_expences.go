@@ -0,0 +1,74 @@
+package messages
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"strconv"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/domain"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/helpers"
+)
+
+const exportFileName = "expences.csv"
+
+var exportHeader = []string{"date", "category", "amount", "base_amount", "id"}
+
+// ExportExpences - CSV с тратами за период и подпись к файлу
+func (s *Model) ExportExpences(ctx context.Context, userID int64, now time.Time, text string) ([]byte, string, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "export_expences_command")
+	defer span.Finish()
+
+	lang := languageFrom(ctx)
+
+	period, err := parseReportPeriod(lang, text, now)
+	if err != nil {
+		return nil, "", err
+	}
+
+	expences, rate, err := s.storage.GetExpenceRows(ctx, userID, period.Start, period.End)
+	if err != nil {
+		return nil, "", errServer
+	}
+
+	if len(expences) == 0 {
+		return nil, lang.tr("No expences!"), nil
+	}
+
+	data, err := formatExpencesCSV(expences, rate)
+	if err != nil {
+		return nil, "", errServer
+	}
+	return data, period.Title, nil
+}
+
+// formatExpencesCSV - строка на трату: дата, категория, сумма в валюте пользователя,
+// сумма в хранимой валюте и id траты; формат не зависит от языка, чтобы файл можно было загрузить обратно
+func formatExpencesCSV(expences []domain.Expence, rate float64) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	if err := w.Write(exportHeader); err != nil {
+		return nil, err
+	}
+	for _, expence := range expences {
+		record := []string{
+			expence.Timestamp.Format("02/01/2006"),
+			expence.CategoryName,
+			helpers.ConvertSubToAmount(int64(float64(expence.Total) * rate)),
+			helpers.ConvertSubToAmount(expence.Total),
+			strconv.FormatInt(expence.ID, 10),
+		}
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
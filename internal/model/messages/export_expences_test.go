@@ -0,0 +1,84 @@
+package messages
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-redis/redismock/v8"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/database"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/domain"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/helpers"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/logger"
+	mocks "gitlab.ozon.dev/akosykh114/telegram-bot/internal/mocks/messages"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/storage"
+)
+
+func Test_FormatExpencesCSV_ShouldQuoteAndConvert(t *testing.T) {
+	data, err := formatExpencesCSV([]domain.Expence{
+		{ID: 7, CategoryName: "food", Timestamp: time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC), Total: 10000},
+		{ID: 8, CategoryName: "cafe, bar", Timestamp: time.Date(2026, 10, 13, 0, 0, 0, 0, time.UTC), Total: 2550},
+	}, 0.5)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "date,category,amount,base_amount,id\n"+
+		"12/10/2026,food,50.00,100.00,7\n"+
+		"13/10/2026,\"cafe, bar\",12.75,25.50,8\n", string(data))
+}
+
+func Test_OnExportCommand_ShouldSendCSVDocument(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, _ := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+
+	startTs := helpers.StartOfMonth(helpers.DateOf(time.Now()))
+	endTs := startTs.AddDate(0, 1, 0)
+
+	columns := []string{"id"}
+	mock.ExpectExec("INSERT INTO users").WithArgs(123).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT base_currency_id").WithArgs(123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT COALESCE\\(\\(SELECT rate FROM currency_rates").WithArgs(1, sqlmock.AnyArg(), 1).WillReturnRows(mock.NewRows(columns).AddRow(2))
+	mock.ExpectQuery("SELECT expences.id").WithArgs(123, startTs, endTs).WillReturnRows(
+		mock.NewRows([]string{"id", "category_id", "name", "ts", "total", "note", "tags", "currency_id", "code", "original_total"}).
+			AddRow(5, 1, "food", startTs, 10000, "", "{}", 1, "RUB", 10000))
+
+	sender.EXPECT().SendDocument(
+		[]byte("date,category,amount,base_amount,id\n"+startTs.Format("02/01/2006")+",food,200.00,100.00,5\n"),
+		"expences.csv", "Last month expences", int64(123))
+
+	err = model.IncomingCommandMessage(context.Background(), CommandMessage{
+		Message: Message{
+			UserID: 123,
+		},
+		CommandName:      "export",
+		CommandArguments: "month",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,407 @@
+package messages
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/common"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/domain"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/helpers"
+)
+
+type DocumentMessage struct {
+	Message  Message
+	FileName string
+	FileSize int
+	Data     []byte
+}
+
+// MaxDocumentSize - максимальный размер загружаемой выписки, файлы больше не скачиваются
+const MaxDocumentSize = 5 << 20
+
+// данные кнопок подтверждения импорта выписки
+const (
+	importConfirmCallback = "import:confirm"
+	importCancelCallback  = "import:cancel"
+)
+
+// количество строк выписки в предпросмотре
+const importPreviewSize = 10
+
+// формат файла /export - выгруженные траты можно загрузить обратно
+var exportImportFormat = domain.ImportFormat{
+	Name:           "export",
+	Delimiter:      ',',
+	DateColumn:     "date",
+	DateFormat:     "02/01/2006",
+	AmountColumn:   "amount",
+	CategoryColumn: "category",
+}
+
+var errImportNotCSV = fmt.Errorf("only CSV bank statements can be imported")
+var errImportTooLarge = fmt.Errorf(fmt.Sprintf("file is too large - maximum size is %d MB", MaxDocumentSize>>20))
+var errImportUnknownFormat = fmt.Errorf("unknown statement format - no date and amount columns found")
+var errNoPendingImport = fmt.Errorf("no statement is waiting for import - upload a CSV file first")
+
+// importPreview - разобранная выписка, ожидающая подтверждения
+type importPreview struct {
+	Format string
+	// траты с именем категории, суммы в базовой валюте пользователя
+	Expences []domain.Expence
+	// строки без категории: значение категории или описание -> количество строк
+	Unknown map[string]int
+	// строки с ошибками и поступления
+	Skipped int
+}
+
+// SetImportFormats - форматы выписок банков из конфигурации, проверяются до формата /export
+func (s *Model) SetImportFormats(formats []domain.ImportFormat) {
+	s.importFormats = append(append([]domain.ImportFormat{}, formats...), exportImportFormat)
+}
+
+func (s *Model) IncomingDocumentMessage(ctx context.Context, msg DocumentMessage) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "incoming_document_process")
+	defer span.Finish()
+
+	span.LogKV("file", msg.FileName)
+
+	user := s.ensureUser(ctx, msg.Message.chatID())
+	s.ensureMember(ctx, msg.Message)
+	ctx, _ = chatContext(ctx, user, msg.Message)
+	lang := languageFrom(ctx)
+
+	if msg.FileSize > MaxDocumentSize {
+		return s.tgClient.SendMessage(lang.errorText(errImportTooLarge), msg.Message.chatID())
+	}
+
+	answer, err := s.PreviewImport(ctx, user.BudgetID, msg.Message.member(), msg.FileName, msg.Data)
+	if err != nil {
+		return s.tgClient.SendMessage(lang.errorText(err), msg.Message.chatID())
+	}
+
+	if _, pending := s.peekImport(msg.Message.member()); pending {
+		return s.tgClient.SendMessageWithButtons(answer, msg.Message.chatID(), []domain.InlineButton{
+			{Text: lang.tr("Import"), Data: importConfirmCallback},
+			{Text: lang.tr("Cancel"), Data: importCancelCallback},
+		})
+	}
+	return s.tgClient.SendMessage(answer, msg.Message.chatID())
+}
+
+// PreviewImport - разбор выписки участника member для бюджета userID и предпросмотр;
+// траты сохраняются до подтверждения кнопкой
+func (s *Model) PreviewImport(ctx context.Context, userID int64, member chatMember, fileName string, data []byte) (string, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "preview_import_command")
+	defer span.Finish()
+
+	s.popImport(member)
+
+	if !strings.HasSuffix(strings.ToLower(fileName), ".csv") {
+		return "", errImportNotCSV
+	}
+
+	format, records, err := readImportCSV(data, s.importFormats)
+	if err != nil {
+		return "", err
+	}
+
+	categories, err := s.storage.GetCategories(ctx, userID)
+	if err != nil {
+		return "", errServer
+	}
+	rules, err := s.storage.GetImportRules(ctx, userID)
+	if err != nil {
+		return "", errServer
+	}
+
+	preview := buildImportPreview(format, records, categories, rules)
+	if len(preview.Expences) > 0 {
+		s.saveImport(member, preview)
+	}
+	return formatImportPreview(languageFrom(ctx), preview), nil
+}
+
+// ConfirmImport - сохранение трат выписки, ожидающей подтверждения, в бюджет userID
+func (s *Model) ConfirmImport(ctx context.Context, userID int64, member chatMember) (string, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "confirm_import_command")
+	defer span.Finish()
+
+	preview, found := s.popImport(member)
+	if !found {
+		return "", errNoPendingImport
+	}
+
+	added, err := s.storage.ImportExpences(ctx, userID, member.UserID, preview.Expences)
+	if err != nil {
+		if err == common.ErrCategoryNotFound {
+			return "", errCategoryNotFound
+		}
+		return "", errServer
+	}
+
+	lang := languageFrom(ctx)
+	rv := lang.tr("Imported %d expences", added)
+	if duplicates := int64(len(preview.Expences)) - added; duplicates > 0 {
+		rv += lang.tr(", %d already imported skipped", duplicates)
+	}
+	if added > 0 {
+		rv = s.withLimitAlert(ctx, userID, rv)
+	}
+	return rv, nil
+}
+
+func (s *Model) CancelImport(ctx context.Context, member chatMember) (string, error) {
+	if _, found := s.popImport(member); !found {
+		return "", errNoPendingImport
+	}
+	return languageFrom(ctx).tr("Import cancelled"), nil
+}
+
+// AddImportRule - правило импорта: строки выписки с ключевым словом в описании попадают в категорию
+func (s *Model) AddImportRule(ctx context.Context, userID int64, text string) (string, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "add_import_rule_command")
+	defer span.Finish()
+
+	commandArgs := strings.Fields(text)
+
+	// ключевое слово может состоять из нескольких слов, категория - последнее
+	if len(commandArgs) < 2 {
+		return "", errWrongCommandFormat
+	}
+	keyword := strings.Join(commandArgs[:len(commandArgs)-1], " ")
+	category := commandArgs[len(commandArgs)-1]
+
+	if err := s.storage.AddImportRule(ctx, userID, keyword, category); err != nil {
+		if err == common.ErrCategoryNotFound {
+			return "", errCategoryNotFound
+		}
+		return "", errServer
+	}
+	return languageFrom(ctx).tr("Imported rows with '%s' will be added to %s", keyword, category), nil
+}
+
+func (s *Model) popImport(member chatMember) (importPreview, bool) {
+	s.importsMu.Lock()
+	defer s.importsMu.Unlock()
+
+	preview, found := s.imports[member]
+	delete(s.imports, member)
+	return preview, found
+}
+
+func (s *Model) peekImport(member chatMember) (importPreview, bool) {
+	s.importsMu.Lock()
+	defer s.importsMu.Unlock()
+
+	preview, found := s.imports[member]
+	return preview, found
+}
+
+func (s *Model) saveImport(member chatMember, preview importPreview) {
+	s.importsMu.Lock()
+	defer s.importsMu.Unlock()
+
+	s.imports[member] = preview
+}
+
+// importColumns - номера столбцов формата в заголовке выписки, -1 - столбца нет
+type importColumns struct {
+	Date        int
+	Amount      int
+	Category    int
+	Description int
+}
+
+// readImportCSV - первый формат, все столбцы которого есть в заголовке, и строки выписки
+func readImportCSV(data []byte, formats []domain.ImportFormat) (domain.ImportFormat, [][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	for _, format := range formats {
+		r := csv.NewReader(bytes.NewReader(data))
+		r.Comma = format.Delimiter
+		r.LazyQuotes = true
+		r.FieldsPerRecord = -1
+
+		records, err := r.ReadAll()
+		if err != nil || len(records) == 0 {
+			continue
+		}
+		if _, ok := findImportColumns(format, records[0]); ok {
+			return format, records, nil
+		}
+	}
+	return domain.ImportFormat{}, nil, errImportUnknownFormat
+}
+
+func findImportColumns(format domain.ImportFormat, header []string) (importColumns, bool) {
+	index := func(name string) int {
+		for i, column := range header {
+			if name != "" && strings.EqualFold(strings.TrimSpace(column), name) {
+				return i
+			}
+		}
+		return -1
+	}
+
+	columns := importColumns{
+		Date:        index(format.DateColumn),
+		Amount:      index(format.AmountColumn),
+		Category:    index(format.CategoryColumn),
+		Description: index(format.DescriptionColumn),
+	}
+	ok := columns.Date >= 0 && columns.Amount >= 0 &&
+		(format.CategoryColumn == "" || columns.Category >= 0) &&
+		(format.DescriptionColumn == "" || columns.Description >= 0)
+	return columns, ok
+}
+
+// buildImportPreview - траты из строк выписки: категория берётся из столбца категории,
+// если у пользователя есть такая, иначе по правилам импорта
+func buildImportPreview(format domain.ImportFormat, records [][]string, categories []string, rules []domain.CategoryKeyword) importPreview {
+	preview := importPreview{
+		Format:   format.Name,
+		Expences: make([]domain.Expence, 0, len(records)),
+		Unknown:  make(map[string]int),
+	}
+	columns, _ := findImportColumns(format, records[0])
+
+	userCategories := make(map[string]string, len(categories))
+	for _, category := range categories {
+		userCategories[strings.ToLower(category)] = category
+	}
+
+	// одинаковые строки в одной выписке - разные траты, номер повтора входит в отпечаток
+	seen := make(map[string]int)
+
+	for _, record := range records[1:] {
+		field := func(i int) string {
+			if i < 0 || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		date, err := time.Parse(format.DateFormat, field(columns.Date))
+		if err != nil {
+			preview.Skipped++
+			continue
+		}
+		date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+
+		total, err := parseImportAmount(field(columns.Amount))
+		if format.NegativeExpences {
+			total = -total
+		}
+		if err != nil || total <= 0 {
+			preview.Skipped++
+			continue
+		}
+
+		categoryValue, description := field(columns.Category), field(columns.Description)
+		category, found := userCategories[strings.ToLower(categoryValue)]
+		if !found {
+			category, found = matchImportRule(rules, categoryValue+" "+description)
+		}
+		if !found {
+			unknown := categoryValue
+			if unknown == "" {
+				unknown = description
+			}
+			preview.Unknown[unknown]++
+			continue
+		}
+
+		raw := strings.Join(record, "\x1f")
+		seen[raw]++
+		hash := sha1.Sum([]byte(fmt.Sprintf("%s\x1f%d", raw, seen[raw])))
+
+		preview.Expences = append(preview.Expences, domain.Expence{
+			CategoryName: category,
+			Timestamp:    date,
+			Total:        total,
+			ImportHash:   hex.EncodeToString(hash[:]),
+		})
+	}
+
+	return preview
+}
+
+func matchImportRule(rules []domain.CategoryKeyword, text string) (string, bool) {
+	text = strings.ToLower(text)
+	for _, rule := range rules {
+		if strings.Contains(text, rule.Keyword) {
+			return rule.CategoryName, true
+		}
+	}
+	return "", false
+}
+
+// parseImportAmount - сумма выписки со знаком: "-1 234,56", "1234.56", "−500"
+func parseImportAmount(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	negative := strings.HasPrefix(value, "-") || strings.HasPrefix(value, "−")
+
+	// десятичная запятая, если точки нет; иначе запятые - разделители разрядов
+	if !strings.Contains(value, ".") {
+		value = strings.ReplaceAll(value, ",", ".")
+	}
+
+	total, err := helpers.ConvertStringAmountToSub(value)
+	if err != nil {
+		return 0, err
+	}
+	if negative {
+		total = -total
+	}
+	return total, nil
+}
+
+func formatImportPreview(lang language, preview importPreview) string {
+	var rvSb strings.Builder
+
+	var total int64
+	for _, expence := range preview.Expences {
+		total += expence.Total
+	}
+	rvSb.WriteString(lang.tr("Statement %s: %d expences, total %s",
+		preview.Format, len(preview.Expences), lang.amount(total)) + "\n")
+
+	for i, expence := range preview.Expences {
+		if i == importPreviewSize {
+			rvSb.WriteString(lang.tr("... and %d more", len(preview.Expences)-importPreviewSize) + "\n")
+			break
+		}
+		rvSb.WriteString(fmt.Sprintf("%s %s %s\n",
+			lang.date(expence.Timestamp),
+			expence.CategoryName,
+			lang.amount(expence.Total)))
+	}
+
+	if len(preview.Unknown) > 0 {
+		unknown := make([]string, 0, len(preview.Unknown))
+		for k := range preview.Unknown {
+			unknown = append(unknown, k)
+		}
+		sort.Strings(unknown)
+
+		rvSb.WriteString("\n" + lang.tr("Rows without category will not be imported - add categories or '/%s %s' and upload the file again:",
+			CommandNameMap[ImportRuleCmd].Command, CommandNameMap[ImportRuleCmd].Format) + "\n")
+		for _, k := range unknown {
+			rvSb.WriteString(fmt.Sprintf("%s: %d\n", k, preview.Unknown[k]))
+		}
+	}
+
+	if preview.Skipped > 0 {
+		rvSb.WriteString("\n" + lang.tr("Skipped rows without date or expence amount: %d", preview.Skipped) + "\n")
+	}
+
+	return strings.TrimSuffix(rvSb.String(), "\n")
+}
@@ -0,0 +1,231 @@
+package messages
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-redis/redismock/v8"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/database"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/domain"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/logger"
+	mocks "gitlab.ozon.dev/akosykh114/telegram-bot/internal/mocks/messages"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/storage"
+)
+
+var testBankFormat = domain.ImportFormat{
+	Name:              "bank",
+	Delimiter:         ';',
+	DateColumn:        "Дата операции",
+	DateFormat:        "02.01.2006 15:04:05",
+	AmountColumn:      "Сумма операции",
+	CategoryColumn:    "Категория",
+	DescriptionColumn: "Описание",
+	NegativeExpences:  true,
+}
+
+const testBankStatement = "\xef\xbb\xbf" +
+	"Дата операции;Сумма операции;Категория;Описание\n" +
+	"12.10.2026 14:33:00;-1 234,56;Супермаркеты;Магнит\n" +
+	"12.10.2026 19:01:00;-350,00;Фастфуд;Вкусно и точка\n" +
+	"12.10.2026 19:01:00;-350,00;Фастфуд;Вкусно и точка\n" +
+	"13.10.2026 09:00:00;50000,00;Пополнения;Зарплата\n" +
+	"13.10.2026 10:00:00;-99,00;Связь;МТС\n" +
+	"bad date;-10,00;Связь;МТС\n"
+
+func Test_ParseImportAmount(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int64
+	}{
+		{"1234.56", 123456},
+		{"-1 234,56", -123456},
+		{"1,234.50", 123450},
+		{"−500", -50000},
+		{" 12,5 ", 1250},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseImportAmount(tt.value)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := parseImportAmount("abc")
+	assert.Error(t, err)
+}
+
+func Test_ReadImportCSV_ShouldDetectFormatByHeader(t *testing.T) {
+	formats := []domain.ImportFormat{testBankFormat, exportImportFormat}
+
+	format, records, err := readImportCSV([]byte(testBankStatement), formats)
+	assert.NoError(t, err)
+	assert.Equal(t, "bank", format.Name)
+	assert.Len(t, records, 7)
+
+	format, _, err = readImportCSV([]byte("date,category,amount,base_amount,id\n12/10/2026,food,50.00,100.00,7\n"), formats)
+	assert.NoError(t, err)
+	assert.Equal(t, "export", format.Name)
+
+	_, _, err = readImportCSV([]byte("a,b\n1,2\n"), formats)
+	assert.Equal(t, errImportUnknownFormat, err)
+}
+
+func Test_BuildImportPreview_ShouldMapCategoriesAndReportUnknown(t *testing.T) {
+	_, records, err := readImportCSV([]byte(testBankStatement), []domain.ImportFormat{testBankFormat})
+	assert.NoError(t, err)
+
+	preview := buildImportPreview(testBankFormat, records, []string{"супермаркеты", "cafe"}, []domain.CategoryKeyword{
+		{CategoryName: "cafe", Keyword: "вкусно"},
+	})
+
+	date := time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)
+	assert.Len(t, preview.Expences, 3)
+	assert.Equal(t, "супермаркеты", preview.Expences[0].CategoryName)
+	assert.Equal(t, int64(123456), preview.Expences[0].Total)
+	assert.Equal(t, date, preview.Expences[0].Timestamp)
+	assert.Equal(t, "cafe", preview.Expences[1].CategoryName)
+	assert.Equal(t, int64(35000), preview.Expences[1].Total)
+
+	// одинаковые строки выписки - разные траты
+	assert.NotEqual(t, preview.Expences[1].ImportHash, preview.Expences[2].ImportHash)
+
+	assert.Equal(t, map[string]int{"Связь": 1}, preview.Unknown)
+	// поступление и строка без даты
+	assert.Equal(t, 2, preview.Skipped)
+
+	// повторный разбор того же файла даёт те же отпечатки
+	again := buildImportPreview(testBankFormat, records, []string{"супермаркеты", "cafe"}, []domain.CategoryKeyword{
+		{CategoryName: "cafe", Keyword: "вкусно"},
+	})
+	assert.Equal(t, preview.Expences, again.Expences)
+}
+
+func Test_OnStatementUpload_ShouldImportAfterConfirm(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, mocksRedis := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+
+	mocksRedis.ExpectKeys("123*").SetVal([]string{})
+
+	columns := []string{"id"}
+	mock.ExpectExec("INSERT INTO users").WithArgs(123).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT id, name FROM expence_category").WithArgs(123).WillReturnRows(
+		mock.NewRows([]string{"id", "name"}).AddRow(1, "food"))
+	mock.ExpectQuery("SELECT category_keywords.category_id").WithArgs(123).WillReturnRows(
+		mock.NewRows([]string{"category_id", "name", "keyword"}))
+
+	sender.EXPECT().SendMessageWithButtons("Statement export: 2 expences, total 150.00\n"+
+		"01/09/2022 food 100.00\n"+
+		"02/09/2022 food 50.00",
+		int64(123), []domain.InlineButton{
+			{Text: "Import", Data: "import:confirm"},
+			{Text: "Cancel", Data: "import:cancel"},
+		})
+
+	err = model.IncomingDocumentMessage(context.Background(), DocumentMessage{
+		Message:  Message{UserID: 123},
+		FileName: "expences.csv",
+		Data:     []byte("date,category,amount\n01/09/2022,food,100.00\n02/09/2022,Food,50.00\n"),
+	})
+	assert.NoError(t, err)
+
+	mock.ExpectQuery("SELECT id, name FROM expence_category").WithArgs(123).WillReturnRows(
+		mock.NewRows([]string{"id", "name"}).AddRow(1, "food"))
+	// курс на день каждой траты
+	mock.ExpectQuery("SELECT base_currency_id").WithArgs(123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT COALESCE\\(\\(SELECT rate FROM currency_rates").WithArgs(1, time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC), 1).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT base_currency_id").WithArgs(123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT COALESCE\\(\\(SELECT rate FROM currency_rates").WithArgs(1, time.Date(2022, 9, 2, 0, 0, 0, 0, time.UTC), 1).WillReturnRows(mock.NewRows(columns).AddRow(2))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT limit_month").WithArgs(123).WillReturnRows(mock.NewRows([]string{"limit_month"}))
+	mock.ExpectQuery("INSERT INTO expences").WithArgs(123, 1, sqlmock.AnyArg(), 10000, sqlmock.AnyArg(), 123, 1, 10000).WillReturnRows(
+		mock.NewRows(columns).AddRow(1))
+	// строка уже импортирована раньше
+	mock.ExpectQuery("INSERT INTO expences").WithArgs(123, 1, sqlmock.AnyArg(), 2500, sqlmock.AnyArg(), 123, 1, 5000).WillReturnRows(
+		mock.NewRows(columns))
+	mock.ExpectCommit()
+	// потрачено 85% лимита - уведомление о пороге 80%
+	mock.ExpectQuery("SELECT COALESCE\\(default_month_limit, 0\\), COALESCE\\(current_month_limit, 0\\), limit_alerts, limit_alert_fired FROM users").WithArgs(123).WillReturnRows(
+		mock.NewRows([]string{"default_month_limit", "current_month_limit", "limit_alerts", "limit_alert_fired"}).AddRow(1000000, 150000, "{50,80,100}", 50))
+	mock.ExpectExec("UPDATE users SET limit_alert_fired").WithArgs(80, 123).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	sender.EXPECT().SendMessage("Imported 1 expences, 1 already imported skipped\nWarning: 80% of the month limit is spent", int64(123))
+
+	err = model.IncomingCallbackMessage(context.Background(), CallbackMessage{
+		Message: Message{UserID: 123},
+		Data:    "import:confirm",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.NoError(t, mocksRedis.ExpectationsWereMet())
+}
+
+func Test_OnTooLargeStatementUpload_ShouldAnswerInUserLanguage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, _ := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(mock.NewRows([]string{"id", "timezone", "language"}).AddRow(123, "", ""))
+
+	sender.EXPECT().SendMessage("файл слишком большой - максимальный размер 5 МБ", int64(123))
+
+	// файл не скачивался - разбирать нечего
+	err = model.IncomingDocumentMessage(context.Background(), DocumentMessage{
+		Message:  Message{UserID: 123, LanguageCode: "ru"},
+		FileName: "expences.csv",
+		FileSize: MaxDocumentSize + 1,
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
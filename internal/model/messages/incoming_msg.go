@@ -5,11 +5,14 @@ import (
 	"errors"
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/opentracing/opentracing-go"
 	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/common"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/domain"
 	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/helpers"
 	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/logger"
 	metr "gitlab.ozon.dev/akosykh114/telegram-bot/internal/metrics"
@@ -18,19 +21,64 @@ import (
 
 type MessageSender interface {
 	SendMessage(text string, userID int64) error
+	SendFormattedMessage(text string, userID int64, parseMode string) error
+	SendMessageWithButtons(text string, userID int64, buttons []domain.InlineButton) error
+	SendPhoto(image []byte, caption string, userID int64) error
+	SendDocument(data []byte, fileName string, caption string, userID int64) error
 }
 
 type storageInterface interface {
-	IsUserAdded(ctx context.Context, userID int64) bool
+	GetUserBudget(ctx context.Context, userID int64) (domain.User, bool)
+	SetUserTimezone(ctx context.Context, userID int64, timezone string) error
+	SetUserLanguage(ctx context.Context, userID int64, language string) error
 	AddUser(ctx context.Context, userID int64) bool
 	ResetUser(ctx context.Context, userID int64) bool
-	ChangeCurrency(ctx context.Context, userID int64, currency string) bool
+	SetUserName(ctx context.Context, userID int64, name string) error
+	GetBudgetInviteCode(ctx context.Context, budgetID int64) (string, error)
+	JoinBudget(ctx context.Context, userID int64, inviteCode string) (int64, error)
+	LeaveBudget(ctx context.Context, userID int64) error
+	GetBudgetMembers(ctx context.Context, budgetID int64) ([]domain.User, error)
+	ChangeCurrency(ctx context.Context, userID int64, memberID int64, currency string) bool
 	IsCategoryExists(ctx context.Context, userID int64, cat string) bool
-	AddCategory(ctx context.Context, userID int64, cat string) bool
-	AddExpence(ctx context.Context, userID int64, cat string, total int64, date time.Time) error
-	GetExpencesMap(ctx context.Context, userID int64, limitTs time.Time) map[string]int64
-	SetUserLimit(ctx context.Context, userID int64, total int64) error
-	ResetUserLimit(ctx context.Context, userID int64) error
+	AddCategory(ctx context.Context, userID int64, memberID int64, cat string) bool
+	GetCategories(ctx context.Context, userID int64) ([]string, error)
+	GetUserCategories(ctx context.Context, userID int64) ([]domain.ExpenceCategory, error)
+	GetCategoryName(ctx context.Context, userID int64, categoryID int64) (string, error)
+	RenameCategory(ctx context.Context, userID int64, oldName string, newName string) error
+	MergeCategory(ctx context.Context, userID int64, from string, into string) error
+	DeleteCategory(ctx context.Context, userID int64, cat string) error
+	SetCategoryLimit(ctx context.Context, userID int64, cat string, total int64) error
+	AddImportRule(ctx context.Context, userID int64, keyword string, cat string) error
+	GetImportRules(ctx context.Context, userID int64) ([]domain.CategoryKeyword, error)
+	AddExpence(ctx context.Context, userID int64, memberID int64, cat string, total int64, currency string, date time.Time, note string, tags []string) error
+	GetExpencesMap(ctx context.Context, userID int64, startTs time.Time, endTs time.Time) map[string]int64
+	GetTagExpencesMap(ctx context.Context, userID int64, tag string, startTs time.Time, endTs time.Time) (map[string]int64, error)
+	GetMemberTotals(ctx context.Context, userID int64, startTs time.Time, endTs time.Time) ([]domain.MemberTotal, error)
+	GetCurrencyTotals(ctx context.Context, userID int64, startTs time.Time, endTs time.Time) ([]domain.Expence, error)
+	GetDailyExpences(ctx context.Context, userID int64, startTs time.Time, endTs time.Time) ([]domain.Expence, error)
+	GetExpenceRows(ctx context.Context, userID int64, startTs time.Time, endTs time.Time) ([]domain.Expence, float64, error)
+	ImportExpences(ctx context.Context, userID int64, memberID int64, expences []domain.Expence) (int64, error)
+	AddIncome(ctx context.Context, userID int64, source string, total int64, date time.Time) error
+	GetIncomeTotal(ctx context.Context, userID int64, startTs time.Time, endTs time.Time) (int64, error)
+	SetUserLimit(ctx context.Context, userID int64, memberID int64, total int64) error
+	ResetUserLimit(ctx context.Context, userID int64, memberID int64) error
+	ListExpences(ctx context.Context, userID int64, offset uint64, limit uint64) ([]domain.Expence, error)
+	SearchExpences(ctx context.Context, userID int64, text string, limit uint64) ([]domain.Expence, error)
+	EditExpence(ctx context.Context, userID int64, expenceID int64, cat string, total int64, date time.Time) error
+	DeleteExpence(ctx context.Context, userID int64, expenceID int64) error
+	AddRecurringExpence(ctx context.Context, userID int64, cat string, total int64, schedule string, nextTs time.Time) (int64, error)
+	GetRecurringExpences(ctx context.Context, userID int64) ([]domain.RecurringExpence, error)
+	GetDueRecurringExpences(ctx context.Context, ts time.Time) ([]domain.RecurringExpence, error)
+	SetRecurringExpenceState(ctx context.Context, userID int64, id int64, paused bool, nextTs time.Time) error
+	DeleteRecurringExpence(ctx context.Context, userID int64, id int64) error
+	Undo(ctx context.Context, userID int64, memberID int64) (domain.Operation, error)
+	PostRecurringExpence(ctx context.Context, expence domain.RecurringExpence, date time.Time) error
+	GetLimitAlerts(ctx context.Context, userID int64) ([]int64, error)
+	SetLimitAlerts(ctx context.Context, userID int64, alerts []int64) error
+	TakeLimitAlert(ctx context.Context, userID int64) (domain.LimitAlert, error)
+	GetOverspend(ctx context.Context, userID int64, startTs time.Time, endTs time.Time) (int64, error)
+	GetLimitMode(ctx context.Context, userID int64) (bool, error)
+	SetLimitMode(ctx context.Context, userID int64, soft bool) error
 }
 
 type ReportGetter interface {
@@ -40,6 +88,22 @@ type ReportGetter interface {
 type Model struct {
 	tgClient MessageSender
 	storage  storageInterface
+
+	// траты из свободного текста, ожидающие ответа на уточняющий вопрос
+	draftsMu sync.Mutex
+	drafts   map[chatMember]expenceDraft
+
+	// команды, ожидающие ответов на вопросы о недостающих аргументах
+	conversationsMu sync.Mutex
+	conversations   map[chatMember]conversation
+
+	// выписки, ожидающие подтверждения импорта
+	importsMu     sync.Mutex
+	imports       map[chatMember]importPreview
+	importFormats []domain.ImportFormat
+
+	// коды валют из конфигурации, которые можно указать после суммы
+	currencies map[string]bool
 }
 
 func New(
@@ -48,10 +112,57 @@ func New(
 	return &Model{
 		tgClient: tgClient,
 		storage:  storage,
+		drafts:   make(map[chatMember]expenceDraft),
+
+		conversations: make(map[chatMember]conversation),
+
+		imports:       make(map[chatMember]importPreview),
+		importFormats: []domain.ImportFormat{exportImportFormat},
+	}
+}
+
+// SetCurrencies - валюты из конфигурации, которые распознаются в аргументах команд вместе с currencyAliases
+func (s *Model) SetCurrencies(currencies []domain.Currency) {
+	s.currencies = make(map[string]bool, len(currencies))
+	for _, currency := range currencies {
+		s.currencies[strings.ToUpper(currency.Code)] = true
 	}
 }
 
 type Message struct {
+	// ChatID - чат, в который отправляется ответ; в группе это общий бюджет всех её участников,
+	// в личных сообщениях совпадает с UserID
+	ChatID int64
+	// UserID - отправитель сообщения
+	UserID int64
+	// UserName - имя отправителя в Telegram
+	UserName string
+	// LanguageCode - язык отправителя в настройках Telegram (ru, en-US), ответы на нём,
+	// пока язык не выбран через /language
+	LanguageCode string
+}
+
+// chatID - чат для ответа и учёта трат, отправитель, если чат не указан
+func (m Message) chatID() int64 {
+	if m.ChatID == 0 {
+		return m.UserID
+	}
+	return m.ChatID
+}
+
+// isGroup - сообщение из группового чата
+func (m Message) isGroup() bool {
+	return m.chatID() != m.UserID
+}
+
+func (m Message) member() chatMember {
+	return chatMember{ChatID: m.chatID(), UserID: m.UserID}
+}
+
+// chatMember - участник в конкретном чате; у каждого участника в каждом чате
+// свои черновики трат и выписки, ожидающие подтверждения
+type chatMember struct {
+	ChatID int64
 	UserID int64
 }
 
@@ -66,21 +177,121 @@ type CommandMessage struct {
 	CommandArguments string
 }
 
+type CallbackMessage struct {
+	Message Message
+	Data    string
+}
+
+// разметка сообщений Telegram, в которой отправляются отчёты
+const parseModeHTML = "HTML"
+
+// префикс данных кнопки выбора категории для траты из свободного текста
+const categoryCallbackPrefix = "category:"
+
+// количество трат на одной странице /list
+const expencesPageSize = 10
+
 var errWrongCommandFormat = fmt.Errorf(fmt.Sprintf("wrong command format - use '/%s'", CommandNameMap[GetHelpCmd].Command))
 var errCategoryNotFound = fmt.Errorf(fmt.Sprintf("category was not found - use '/%s %s'", CommandNameMap[AddCategoryCmd].Command, CommandNameMap[AddCategoryCmd].Format))
-var errDateWrongFormat = fmt.Errorf("wrong date format - right: dd/mm/yyyy")
+var errDateWrongFormat = fmt.Errorf("wrong date format - use dd/mm/yyyy, dd.mm.yyyy, dd/mm, yyyy-mm-dd, today, yesterday or weekday")
+var errDateTooFar = fmt.Errorf("date is too far in the future")
+var errCurrencyNotFound = fmt.Errorf("currency was not found - use USD/CNY/EUR/RUB")
 var errLimitIsTooSmall = fmt.Errorf("limit is too small")
 var errResetLimit = fmt.Errorf("error reseting limit")
 var errServer = fmt.Errorf("server error")
+var errExpenceNotFound = fmt.Errorf(fmt.Sprintf("expence was not found - use '/%s'", CommandNameMap[ListExpencesCmd].Command))
+var errCategoryExists = fmt.Errorf("category with this name already exists")
+var errCategoryNotEmpty = fmt.Errorf(fmt.Sprintf("category has expences - move them with '/%s %s' or delete them with '/%s %s'",
+	CommandNameMap[MergeCategoryCmd].Command, CommandNameMap[MergeCategoryCmd].Format,
+	CommandNameMap[DeleteExpenceCmd].Command, CommandNameMap[DeleteExpenceCmd].Format))
+var errCategoryHasRecurring = fmt.Errorf(fmt.Sprintf("category has recurring expences - move them with '/%s %s' or cancel them with '/%s %s'",
+	CommandNameMap[MergeCategoryCmd].Command, CommandNameMap[MergeCategoryCmd].Format,
+	CommandNameMap[CancelRecurringCmd].Command, CommandNameMap[CancelRecurringCmd].Format))
+var errNoPendingExpence = fmt.Errorf("no expence is waiting for a category - send an amount first")
+
+func (s *Model) IncomingPlainTextMessage(ctx context.Context, msg PlainTextMessage) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "incoming_plain_text_process")
+	defer span.Finish()
+
+	user := s.ensureUser(ctx, msg.Message.chatID())
+	s.ensureMember(ctx, msg.Message)
+	ctx, now := chatContext(ctx, user, msg.Message)
+
+	// ответ на вопрос бота о недостающем аргументе команды, иначе - трата из свободного текста
+	answer, handled, err := s.ContinueConversation(ctx, user.BudgetID, msg.Message.member(), now, msg.Text)
+	if !handled {
+		answer, err = s.AddExpenceFromText(ctx, user.BudgetID, msg.Message.member(), now, msg.Text)
+	}
+	if err != nil {
+		answer = languageFrom(ctx).errorText(err)
+	}
+	if answer == "" {
+		// в группе бот видит переписку участников и отвечает только на траты
+		if msg.Message.isGroup() {
+			return nil
+		}
+		answer = s.Help(ctx)
+	}
+
+	// при выборе категории показываем категории бюджета кнопками
+	if err == nil && s.awaitsCategory(msg.Message.member()) {
+		if buttons := s.categoryButtons(ctx, user.BudgetID); len(buttons) > 0 {
+			return s.tgClient.SendMessageWithButtons(answer, msg.Message.chatID(), buttons)
+		}
+	}
 
-func (s *Model) IncomingPlainTextMessage(msg PlainTextMessage) error {
-	return s.tgClient.SendMessage(s.Help(), msg.Message.UserID)
+	return s.tgClient.SendMessage(answer, msg.Message.chatID())
+}
+
+func (s *Model) IncomingCallbackMessage(ctx context.Context, msg CallbackMessage) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "incoming_callback_process")
+	defer span.Finish()
+
+	span.LogKV("data", msg.Data)
+
+	// трата или выписка попадают в бюджет, в котором участник состоит на момент нажатия кнопки
+	user := s.ensureUser(ctx, msg.Message.chatID())
+	ctx, now := chatContext(ctx, user, msg.Message)
+
+	var answer string
+	var err error
+
+	switch {
+	case strings.HasPrefix(msg.Data, categoryCallbackPrefix):
+		var cat string
+		cat, err = s.callbackCategory(ctx, user.BudgetID, strings.TrimPrefix(msg.Data, categoryCallbackPrefix))
+		if err != nil {
+			break
+		}
+		var handled bool
+		answer, handled, err = s.ContinueConversation(ctx, user.BudgetID, msg.Message.member(), now, cat)
+		if !handled {
+			answer, err = s.AddExpenceCategory(ctx, user.BudgetID, msg.Message.member(), now, cat)
+		}
+	case msg.Data == importConfirmCallback:
+		answer, err = s.ConfirmImport(ctx, user.BudgetID, msg.Message.member())
+	case msg.Data == importCancelCallback:
+		answer, err = s.CancelImport(ctx, msg.Message.member())
+	default:
+		answer = s.Help(ctx)
+	}
+	if err != nil {
+		answer = languageFrom(ctx).errorText(err)
+	}
+
+	return s.tgClient.SendMessage(answer, msg.Message.chatID())
 }
 
 func (s *Model) IncomingCommandMessage(ctx context.Context, msg CommandMessage) error {
 	var err error
 	var answer string
 
+	// разметка ответа, пустая - обычный текст
+	var parseMode string
+	// картинка или файл, answer отправляется подписью к ним
+	var photo []byte
+	var document []byte
+
 	span, ctx := opentracing.StartSpanFromContext(ctx, "incoming_command_process")
 	defer span.Finish()
 
@@ -89,36 +300,104 @@ func (s *Model) IncomingCommandMessage(ctx context.Context, msg CommandMessage)
 		"argument", msg.CommandArguments,
 	)
 
-	if !s.storage.IsUserAdded(ctx, msg.Message.UserID) {
-		if _, err = s.addUser(ctx, msg.Message.UserID); err != nil {
-			logger.Error("adding user error", zap.Error(err))
-		}
-	}
+	// данные ведутся в бюджете, в который вступил пользователь, в группе - в бюджете группы;
+	// даты и периоды считаются по времени пользователя, в группе - по времени группы
+	user := s.ensureUser(ctx, msg.Message.chatID())
+	s.ensureMember(ctx, msg.Message)
+	ctx, now := chatContext(ctx, user, msg.Message)
+	budgetID := user.BudgetID
+
+	// любая команда прерывает уточнение траты из свободного текста и вопросы о недостающих аргументах
+	_, drafted := s.popDraft(msg.Message.member())
+	_, asked := s.popConversation(msg.Message.member())
 
 	startTime := time.Now()
 
 	switch msg.CommandName {
 	case CommandNameMap[StartCmd].Command:
-		answer = s.welcomeMessage()
+		answer = s.welcomeMessage(ctx)
 	case CommandNameMap[ResetCmd].Command:
-		answer, err = s.resetUser(ctx, msg.Message.UserID)
+		answer, err = s.resetUser(ctx, msg.Message.chatID())
 	case CommandNameMap[AddCategoryCmd].Command:
-		answer, err = s.AddCategory(ctx, msg.Message.UserID, msg.CommandArguments)
+		answer, err = s.AddCategory(ctx, budgetID, msg.Message.UserID, msg.CommandArguments)
+	case CommandNameMap[ListCategoriesCmd].Command:
+		answer, err = s.ListCategories(ctx, budgetID)
+	case CommandNameMap[RenameCategoryCmd].Command:
+		answer, err = s.RenameCategory(ctx, budgetID, msg.CommandArguments)
+	case CommandNameMap[MergeCategoryCmd].Command:
+		answer, err = s.MergeCategory(ctx, budgetID, msg.CommandArguments)
+	case CommandNameMap[DeleteCategoryCmd].Command:
+		answer, err = s.DeleteCategory(ctx, budgetID, msg.CommandArguments)
 	case CommandNameMap[AddExpenceCmd].Command:
-		answer, err = s.AddExpence(ctx, msg.Message.UserID, msg.CommandArguments)
+		var asking bool
+		answer, asking, err = s.StartConversation(ctx, budgetID, msg.Message.member(), now, AddExpenceCmd, msg.CommandArguments)
+		if !asking {
+			answer, err = s.AddExpence(ctx, budgetID, msg.Message.UserID, now, msg.CommandArguments)
+		}
+	case CommandNameMap[AddIncomeCmd].Command:
+		var asking bool
+		answer, asking, err = s.StartConversation(ctx, budgetID, msg.Message.member(), now, AddIncomeCmd, msg.CommandArguments)
+		if !asking {
+			answer, err = s.AddIncome(ctx, budgetID, now, msg.CommandArguments)
+		}
 	case CommandNameMap[GetReportCmd].Command:
-		answer, err = s.GetReport(ctx, msg.Message.UserID, msg.CommandArguments)
+		answer, err = s.GetReport(ctx, budgetID, now, msg.CommandArguments)
+		parseMode = parseModeHTML
+	case CommandNameMap[ChartCmd].Command:
+		photo, answer, err = s.GetChart(ctx, budgetID, now, msg.CommandArguments)
+	case CommandNameMap[ExportCmd].Command:
+		document, answer, err = s.ExportExpences(ctx, budgetID, now, msg.CommandArguments)
+	case CommandNameMap[ImportRuleCmd].Command:
+		answer, err = s.AddImportRule(ctx, budgetID, msg.CommandArguments)
+	case CommandNameMap[BudgetCmd].Command:
+		answer, err = s.GetBudget(ctx, budgetID, msg.Message)
+	case CommandNameMap[JoinBudgetCmd].Command:
+		answer, err = s.JoinBudget(ctx, msg.Message, msg.CommandArguments)
+	case CommandNameMap[LeaveBudgetCmd].Command:
+		answer, err = s.LeaveBudget(ctx, budgetID, msg.Message.chatID())
 	case CommandNameMap[ChangeCurrency].Command:
-		answer, err = s.ChangeCurrency(ctx, msg.Message.UserID, msg.CommandArguments)
+		answer, err = s.ChangeCurrency(ctx, budgetID, msg.Message.UserID, msg.CommandArguments)
+	case CommandNameMap[TimezoneCmd].Command:
+		answer, err = s.SetTimezone(ctx, msg.Message.chatID(), now, msg.CommandArguments)
+	case CommandNameMap[LanguageCmd].Command:
+		answer, err = s.SetLanguage(ctx, msg.Message.chatID(), msg.CommandArguments)
 	case CommandNameMap[SetMonthLimit].Command:
-		answer, err = s.SetUserLimit(ctx, msg.Message.UserID, msg.CommandArguments)
+		answer, err = s.SetUserLimit(ctx, budgetID, msg.Message.UserID, msg.CommandArguments)
 	case CommandNameMap[ResetMonthLimit].Command:
-		answer, err = s.ResetUserLimit(ctx, msg.Message.UserID)
+		answer, err = s.ResetUserLimit(ctx, budgetID, msg.Message.UserID)
+	case CommandNameMap[LimitAlertsCmd].Command:
+		answer, err = s.SetLimitAlerts(ctx, budgetID, msg.CommandArguments)
+	case CommandNameMap[LimitModeCmd].Command:
+		answer, err = s.SetLimitMode(ctx, budgetID, msg.CommandArguments)
+	case CommandNameMap[SetCategoryLimitCmd].Command:
+		answer, err = s.SetCategoryLimit(ctx, budgetID, msg.CommandArguments)
+	case CommandNameMap[ListExpencesCmd].Command:
+		answer, err = s.ListExpences(ctx, budgetID, msg.CommandArguments)
+	case CommandNameMap[SearchExpencesCmd].Command:
+		answer, err = s.SearchExpences(ctx, budgetID, msg.CommandArguments)
+	case CommandNameMap[EditExpenceCmd].Command:
+		answer, err = s.EditExpence(ctx, budgetID, now, msg.CommandArguments)
+	case CommandNameMap[DeleteExpenceCmd].Command:
+		answer, err = s.DeleteExpence(ctx, budgetID, msg.CommandArguments)
+	case CommandNameMap[AddRecurringCmd].Command:
+		answer, err = s.AddRecurringExpence(ctx, budgetID, now, msg.CommandArguments)
+	case CommandNameMap[ListRecurringCmd].Command:
+		answer, err = s.ListRecurringExpences(ctx, budgetID, now)
+	case CommandNameMap[PauseRecurringCmd].Command:
+		answer, err = s.PauseRecurringExpence(ctx, budgetID, msg.CommandArguments)
+	case CommandNameMap[ResumeRecurringCmd].Command:
+		answer, err = s.ResumeRecurringExpence(ctx, budgetID, now, msg.CommandArguments)
+	case CommandNameMap[CancelRecurringCmd].Command:
+		answer, err = s.CancelRecurringExpence(ctx, budgetID, msg.CommandArguments)
+	case CommandNameMap[UndoCmd].Command:
+		answer, err = s.Undo(ctx, budgetID, msg.Message.UserID)
+	case CommandNameMap[CancelCmd].Command:
+		answer = s.Cancel(ctx, msg.Message.member(), drafted || asked)
 	default:
-		answer = s.Help()
+		answer = s.Help(ctx)
 	}
 	if err != nil {
-		answer = err.Error()
+		answer = languageFrom(ctx).errorText(err)
 	}
 
 	duration := time.Since(startTime)
@@ -129,11 +408,58 @@ func (s *Model) IncomingCommandMessage(ctx context.Context, msg CommandMessage)
 		WithLabelValues(msg.CommandName).
 		Observe(duration.Seconds())
 
-	return s.tgClient.SendMessage(answer, msg.Message.UserID)
+	if photo != nil && err == nil {
+		return s.tgClient.SendPhoto(photo, answer, msg.Message.chatID())
+	}
+	if document != nil && err == nil {
+		return s.tgClient.SendDocument(document, exportFileName, answer, msg.Message.chatID())
+	}
+	if parseMode != "" && err == nil {
+		return s.tgClient.SendFormattedMessage(answer, msg.Message.chatID(), parseMode)
+	}
+	if err == nil && s.awaitsCategory(msg.Message.member()) {
+		if buttons := s.categoryButtons(ctx, budgetID); len(buttons) > 0 {
+			return s.tgClient.SendMessageWithButtons(answer, msg.Message.chatID(), buttons)
+		}
+	}
+	return s.tgClient.SendMessage(answer, msg.Message.chatID())
+}
+
+func (s *Model) welcomeMessage(ctx context.Context) string {
+	return languageFrom(ctx).tr("Welcomen!")
+}
+
+// ensureUser - добавление нового пользователя; возвращает бюджет, в котором пользователь ведёт учёт,
+// его часовой пояс и язык
+func (s *Model) ensureUser(ctx context.Context, userID int64) domain.User {
+	user, found := s.storage.GetUserBudget(ctx, userID)
+	if !found {
+		if _, err := s.addUser(ctx, userID); err != nil {
+			logger.Error("adding user error", zap.Error(err))
+		}
+	}
+	return user
+}
+
+// chatContext - контекст с языком ответов в чате и текущее время по часовому поясу чата
+func chatContext(ctx context.Context, user domain.User, msg Message) (context.Context, time.Time) {
+	ctx = withLanguage(ctx, userLanguage(user.Language, msg.LanguageCode))
+	return ctx, time.Now().In(helpers.LoadLocation(user.Timezone))
 }
 
-func (s *Model) welcomeMessage() string {
-	return "Welcomen!"
+// ensureMember - в группе отправитель заводится отдельным пользователем с именем,
+// чтобы траты бюджета группы записывались на него и показывались в отчёте по участникам
+func (s *Model) ensureMember(ctx context.Context, msg Message) {
+	if !msg.isGroup() {
+		return
+	}
+	s.ensureUser(ctx, msg.UserID)
+	if msg.UserName == "" {
+		return
+	}
+	if err := s.storage.SetUserName(ctx, msg.UserID, msg.UserName); err != nil {
+		logger.Error("setting member name error", zap.Error(err))
+	}
 }
 
 func (s *Model) addUser(ctx context.Context, userID int64) (string, error) {
@@ -149,31 +475,148 @@ func (s *Model) resetUser(ctx context.Context, userID int64) (string, error) {
 	defer span.Finish()
 
 	if s.storage.ResetUser(ctx, userID) {
-		return "Data erased!", nil
+		return languageFrom(ctx).tr("Data erased!"), nil
 	}
 	return "", errServer
 }
 
 // добавление категории
-func (s *Model) AddCategory(ctx context.Context, userID int64, text string) (string, error) {
+func (s *Model) AddCategory(ctx context.Context, userID int64, memberID int64, text string) (string, error) {
 	span, ctx := opentracing.StartSpanFromContext(ctx, "add_category_command")
 	defer span.Finish()
 
 	if text == "" {
 		return "", errWrongCommandFormat
 	}
+	lang := languageFrom(ctx)
 	cat := strings.Split(text, " ")
 	if len(cat) == 1 {
-		if s.storage.AddCategory(ctx, userID, cat[0]) {
-			return fmt.Sprintf("Category %s is added", cat[0]), nil
+		if s.storage.AddCategory(ctx, userID, memberID, cat[0]) {
+			return lang.tr("Category %s is added", cat[0]), nil
 		}
-		return fmt.Sprintf("Category %s is already added", cat[0]), nil
+		return lang.tr("Category %s is already added", cat[0]), nil
 	}
 	return "", errWrongCommandFormat
 }
 
+// вывод всех категорий
+func (s *Model) ListCategories(ctx context.Context, userID int64) (string, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "list_categories_command")
+	defer span.Finish()
+
+	categories, err := s.storage.GetCategories(ctx, userID)
+	if err != nil {
+		return "", errServer
+	}
+
+	lang := languageFrom(ctx)
+	if len(categories) == 0 {
+		return lang.tr("No categories! Use '/%s %s'", CommandNameMap[AddCategoryCmd].Command, CommandNameMap[AddCategoryCmd].Format), nil
+	}
+
+	return lang.tr("Categories:") + "\n" + strings.Join(categories, "\n"), nil
+}
+
+// переименование категории
+func (s *Model) RenameCategory(ctx context.Context, userID int64, text string) (string, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "rename_category_command")
+	defer span.Finish()
+
+	commandArgs := strings.Split(text, " ")
+
+	// проверка, что 2 аргумента
+	if len(commandArgs) != 2 {
+		return "", errWrongCommandFormat
+	}
+
+	if err := s.storage.RenameCategory(ctx, userID, commandArgs[0], commandArgs[1]); err != nil {
+		return "", categoryChangeError(err)
+	}
+
+	return languageFrom(ctx).tr("Category %s is renamed to %s", commandArgs[0], commandArgs[1]), nil
+}
+
+// объединение категорий: траты первой переносятся во вторую
+func (s *Model) MergeCategory(ctx context.Context, userID int64, text string) (string, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "merge_category_command")
+	defer span.Finish()
+
+	commandArgs := strings.Split(text, " ")
+
+	// проверка, что 2 разных аргумента
+	if len(commandArgs) != 2 || commandArgs[0] == commandArgs[1] {
+		return "", errWrongCommandFormat
+	}
+
+	if err := s.storage.MergeCategory(ctx, userID, commandArgs[0], commandArgs[1]); err != nil {
+		return "", categoryChangeError(err)
+	}
+
+	return languageFrom(ctx).tr("Category %s is merged into %s", commandArgs[0], commandArgs[1]), nil
+}
+
+// удаление категории
+func (s *Model) DeleteCategory(ctx context.Context, userID int64, text string) (string, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "delete_category_command")
+	defer span.Finish()
+
+	if text == "" || strings.Contains(text, " ") {
+		return "", errWrongCommandFormat
+	}
+
+	if err := s.storage.DeleteCategory(ctx, userID, text); err != nil {
+		return "", categoryChangeError(err)
+	}
+
+	return languageFrom(ctx).tr("Category %s is deleted", text), nil
+}
+
+// установка лимита категории на месяц
+func (s *Model) SetCategoryLimit(ctx context.Context, userID int64, text string) (string, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "set_category_limit_command")
+	defer span.Finish()
+
+	commandArgs := strings.Split(text, " ")
+
+	// проверка, что 2 аргумента
+	if len(commandArgs) != 2 {
+		return "", errWrongCommandFormat
+	}
+
+	total, err := helpers.ConvertStringAmountToSub(commandArgs[1])
+	if err != nil {
+		return "", err
+	}
+
+	if total < 1 {
+		return "", errLimitIsTooSmall
+	}
+
+	if err := s.storage.SetCategoryLimit(ctx, userID, commandArgs[0], total); err != nil {
+		return "", categoryChangeError(err)
+	}
+
+	return languageFrom(ctx).tr("Month limit for category %s is set", commandArgs[0]), nil
+}
+
+func categoryChangeError(err error) error {
+	switch {
+	case errors.Is(err, common.ErrCategoryNotFound):
+		return errCategoryNotFound
+	case errors.Is(err, common.ErrCategoryExists):
+		return errCategoryExists
+	case errors.Is(err, common.ErrCategoryNotEmpty):
+		return errCategoryNotEmpty
+	case errors.Is(err, common.ErrCategoryHasRecurring):
+		return errCategoryHasRecurring
+	}
+	return errServer
+}
+
 // вывод всех команд
-func (s *Model) Help() string {
+func (s *Model) Help(ctx context.Context) string {
+	lang := languageFrom(ctx)
+
 	var commandsSb strings.Builder
 	keys := make([]int, 0, len(CommandNameMap))
 	for k := range CommandNameMap {
@@ -186,13 +629,13 @@ func (s *Model) Help() string {
 			fmt.Sprintf("/%s %s - %s.\n",
 				CommandNameMap[k].Command,
 				CommandNameMap[k].Format,
-				CommandNameMap[k].Description))
+				lang.tr(CommandNameMap[k].Description)))
 	}
-	return "Available commands:\n" + commandsSb.String()
+	return lang.tr("Available commands:") + "\n" + commandsSb.String()
 }
 
-// добавление траты
-func (s *Model) AddExpence(ctx context.Context, userID int64, text string) (string, error) {
+// добавление траты в бюджет userID участником memberID
+func (s *Model) AddExpence(ctx context.Context, userID int64, memberID int64, now time.Time, text string) (string, error) {
 	span, ctx := opentracing.StartSpanFromContext(ctx, "add_expence_command")
 	defer span.Finish()
 
@@ -201,8 +644,8 @@ func (s *Model) AddExpence(ctx context.Context, userID int64, text string) (stri
 	}
 	commandArgs := strings.Split(text, " ")
 
-	// проверка, что 3 аргумента
-	if len(commandArgs) != 3 {
+	// проверка, что есть категория и сумма, дальше могут идти валюта, дата, метки и комментарий
+	if len(commandArgs) < 2 {
 		return "", errWrongCommandFormat
 	}
 
@@ -216,26 +659,170 @@ func (s *Model) AddExpence(ctx context.Context, userID int64, text string) (stri
 	if err != nil {
 		return "", err
 	}
+	args := commandArgs[2:]
 
-	// проверка, что 3ий аргумент (дата) является датой
-	date, err := helpers.StringToDate(commandArgs[2])
-	if err != nil {
-		return "", errDateWrongFormat
+	// после суммы может стоять валюта: food 12.50 EUR 12/10/2026; "sun" - воскресенье, а не валюта
+	var currency string
+	if len(args) > 0 && !isDateArg(args[0], now) {
+		if code, found := parseCurrencyArg(args[0], s.currencies); found {
+			currency = code
+			args = args[1:]
+		}
+	}
+
+	// дата необязательна, по умолчанию - сегодня
+	date := helpers.DateOf(now)
+	if len(args) > 0 {
+		if parsed, err := helpers.ParseDate(args[0], now); err == nil {
+			date = parsed
+			args = args[1:]
+		} else if helpers.IsDateLike(args[0]) {
+			return "", errDateWrongFormat
+		}
+	}
+	if err := checkDateAhead(date, now); err != nil {
+		return "", err
 	}
 
-	if err := s.storage.AddExpence(ctx, userID, commandArgs[0], total, date); err != nil {
-		limitExceededError := &common.LimitExceededError{}
-		if errors.As(err, &limitExceededError) {
-			return "", err
+	note, tags := parseExpenceNote(args)
+
+	return s.addExpence(ctx, userID, memberID, commandArgs[0], total, currency, date, note, tags)
+}
+
+// добавление траты из свободного текста: "coffee 250", "taxi 1200 yesterday"
+// пустой ответ без ошибки означает, что текст не похож на трату
+func (s *Model) AddExpenceFromText(ctx context.Context, userID int64, member chatMember, now time.Time, text string) (string, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "add_expence_from_text")
+	defer span.Finish()
+
+	parsed := parseExpenceText(text, now)
+
+	// черновик у каждого участника бюджета свой
+	draft, pending := s.popDraft(member)
+	if pending {
+		draft = draft.merge(parsed)
+	} else {
+		if !parsed.isExpence() {
+			return "", nil
 		}
+		draft = parsed
+	}
+
+	return s.completeDraft(ctx, userID, member, now, draft)
+}
+
+// выбор категории кнопкой для траты, ожидающей уточнения
+func (s *Model) AddExpenceCategory(ctx context.Context, userID int64, member chatMember, now time.Time, cat string) (string, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "add_expence_category")
+	defer span.Finish()
+
+	draft, pending := s.popDraft(member)
+	if !pending {
+		return "", errNoPendingExpence
+	}
+	draft.Categories = []string{cat}
+
+	return s.completeDraft(ctx, userID, member, now, draft)
+}
+
+// сохранение траты из черновика, либо уточняющий вопрос, если данных не хватает
+func (s *Model) completeDraft(ctx context.Context, userID int64, member chatMember, now time.Time, draft expenceDraft) (string, error) {
+	if question := draft.question(languageFrom(ctx)); question != "" {
+		s.saveDraft(member, draft)
+		return question, nil
+	}
+
+	if !s.storage.IsCategoryExists(ctx, userID, draft.Categories[0]) {
 		return "", errCategoryNotFound
 	}
 
-	return "Expence added", nil
+	date := draft.Date
+	if date.IsZero() {
+		date = helpers.DateOf(now)
+	}
+	if err := checkDateAhead(date, now); err != nil {
+		return "", err
+	}
+
+	return s.addExpence(ctx, userID, member.UserID, draft.Categories[0], draft.Amounts[0], draft.Currency, date, "", nil)
 }
 
-func (s *Model) GetReport(ctx context.Context, userID int64, text string) (string, error) {
-	span, ctx := opentracing.StartSpanFromContext(ctx, "get_report_command")
+// общий путь сохранения траты для команды и свободного текста
+func (s *Model) addExpence(ctx context.Context, userID int64, memberID int64, cat string, total int64, currency string, date time.Time, note string, tags []string) (string, error) {
+	if err := s.storeExpence(ctx, userID, memberID, cat, total, currency, date, note, tags); err != nil {
+		return "", err
+	}
+
+	return s.withLimitAlert(ctx, userID, languageFrom(ctx).tr("Expence added")), nil
+}
+
+// storeExpence - сохранение траты и ошибка для ответа пользователю
+func (s *Model) storeExpence(ctx context.Context, userID int64, memberID int64, cat string, total int64, currency string, date time.Time, note string, tags []string) error {
+	return storeExpenceError(s.storage.AddExpence(ctx, userID, memberID, cat, total, currency, date, note, tags))
+}
+
+// storeExpenceError - ошибка сохранения траты для ответа пользователю
+func storeExpenceError(err error) error {
+	if err == nil {
+		return nil
+	}
+	limitExceededError := &common.LimitExceededError{}
+	if errors.As(err, &limitExceededError) {
+		return err
+	}
+	if err == common.ErrCurrencyNotFound {
+		return errCurrencyNotFound
+	}
+	return errCategoryNotFound
+}
+
+// isDateArg - аргумент команды является датой
+func isDateArg(arg string, now time.Time) bool {
+	_, err := helpers.ParseDate(arg, now)
+	return err == nil
+}
+
+// checkDateAhead - траты и доходы можно записать не дальше чем на месяц вперёд,
+// более поздняя дата - скорее всего опечатка в годе
+func checkDateAhead(date time.Time, now time.Time) error {
+	if date.After(helpers.DateOf(now).AddDate(0, 1, 0)) {
+		return errDateTooFar
+	}
+	return nil
+}
+
+func (s *Model) popDraft(member chatMember) (expenceDraft, bool) {
+	s.draftsMu.Lock()
+	defer s.draftsMu.Unlock()
+
+	draft, found := s.drafts[member]
+	delete(s.drafts, member)
+	return draft, found && time.Now().Before(draft.ExpiresAt)
+}
+
+func (s *Model) peekDraft(member chatMember) (expenceDraft, bool) {
+	s.draftsMu.Lock()
+	defer s.draftsMu.Unlock()
+
+	draft, found := s.drafts[member]
+	if found && !time.Now().Before(draft.ExpiresAt) {
+		delete(s.drafts, member)
+		return expenceDraft{}, false
+	}
+	return draft, found
+}
+
+func (s *Model) saveDraft(member chatMember, draft expenceDraft) {
+	s.draftsMu.Lock()
+	defer s.draftsMu.Unlock()
+
+	draft.ExpiresAt = time.Now().Add(conversationTimeout)
+	s.drafts[member] = draft
+}
+
+// вывод трат постранично, от новых к старым
+func (s *Model) ListExpences(ctx context.Context, userID int64, text string) (string, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "list_expences_command")
 	defer span.Finish()
 
 	commandArgs := strings.Split(text, " ")
@@ -245,40 +832,236 @@ func (s *Model) GetReport(ctx context.Context, userID int64, text string) (strin
 		return "", errWrongCommandFormat
 	}
 
-	// таймстэмп, по которому будет сравниваться
-	var limitTs time.Time = time.Date(1970, 1, 1, 0, 0, 0, 0, time.Now().Location())
+	var page uint64 = 1
+	if commandArgs[0] != "" {
+		var err error
+		page, err = strconv.ParseUint(commandArgs[0], 10, 64)
+		if err != nil || page == 0 {
+			return "", errWrongCommandFormat
+		}
+	}
 
-	switch commandArgs[0] {
-	case "day":
-		limitTs = helpers.GetStartOfCurrentDay()
-	case "month":
-		limitTs = helpers.GetStartOfCurrentMonth()
-	case "year":
-		limitTs = helpers.GetStartOfCurrentYear()
-	default:
+	// запрашивается на одну трату больше, чтобы понять, есть ли следующая страница
+	expences, err := s.storage.ListExpences(ctx, userID, (page-1)*expencesPageSize, expencesPageSize+1)
+	if err != nil {
+		return "", errServer
 	}
 
-	totalMap := s.storage.GetExpencesMap(ctx, userID, limitTs)
+	lang := languageFrom(ctx)
+	if len(expences) == 0 {
+		return lang.tr("No expences!"), nil
+	}
 
-	if len(totalMap) == 0 {
-		return "No expences!", nil
+	hasNextPage := len(expences) > expencesPageSize
+	if hasNextPage {
+		expences = expences[:expencesPageSize]
 	}
 
 	var rvSb strings.Builder
-	if commandArgs[0] != "" {
-		rvSb.WriteString(fmt.Sprintf("Last %s expences\n", commandArgs[0]))
-	} else {
-		rvSb.WriteString("All time expences\n")
+	rvSb.WriteString(lang.tr("Expences, page %d", page) + "\n")
+	for _, expence := range expences {
+		rvSb.WriteString(formatExpenceLine(lang, expence))
 	}
-
-	for k, v := range totalMap {
-		rvSb.WriteString(fmt.Sprintf("%s: %s\n", k, helpers.ConvertSubToAmount(v)))
+	if hasNextPage {
+		rvSb.WriteString(lang.tr("Next page: /%s %d", CommandNameMap[ListExpencesCmd].Command, page+1) + "\n")
 	}
 
 	return rvSb.String(), nil
 }
 
-func (s *Model) ChangeCurrency(ctx context.Context, userID int64, text string) (string, error) {
+// изменение траты
+func (s *Model) EditExpence(ctx context.Context, userID int64, now time.Time, text string) (string, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "edit_expence_command")
+	defer span.Finish()
+
+	commandArgs := strings.Split(text, " ")
+
+	// проверка, что 4 аргумента
+	if len(commandArgs) != 4 {
+		return "", errWrongCommandFormat
+	}
+
+	expenceID, err := strconv.ParseInt(commandArgs[0], 10, 64)
+	if err != nil {
+		return "", errWrongCommandFormat
+	}
+
+	if !s.storage.IsCategoryExists(ctx, userID, commandArgs[1]) {
+		return "", errCategoryNotFound
+	}
+
+	total, err := helpers.ConvertStringAmountToSub(commandArgs[2])
+	if err != nil {
+		return "", err
+	}
+
+	date, err := helpers.ParseDate(commandArgs[3], now)
+	if err != nil {
+		return "", errDateWrongFormat
+	}
+	if err := checkDateAhead(date, now); err != nil {
+		return "", err
+	}
+
+	if err := s.storage.EditExpence(ctx, userID, expenceID, commandArgs[1], total, date); err != nil {
+		return "", expenceChangeError(err)
+	}
+
+	return languageFrom(ctx).tr("Expence changed"), nil
+}
+
+// удаление траты
+func (s *Model) DeleteExpence(ctx context.Context, userID int64, text string) (string, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "delete_expence_command")
+	defer span.Finish()
+
+	expenceID, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		return "", errWrongCommandFormat
+	}
+
+	if err := s.storage.DeleteExpence(ctx, userID, expenceID); err != nil {
+		return "", expenceChangeError(err)
+	}
+
+	return languageFrom(ctx).tr("Expence deleted"), nil
+}
+
+func expenceChangeError(err error) error {
+	limitExceededError := &common.LimitExceededError{}
+	switch {
+	case errors.As(err, &limitExceededError):
+		return err
+	case errors.Is(err, common.ErrExpenceNotFound):
+		return errExpenceNotFound
+	}
+	return errServer
+}
+
+// categoryButtons - кнопки категорий бюджета; в данных кнопки id категории, а не название -
+// Telegram ограничивает данные кнопки 64 байтами, длинное название на кириллице в них не помещается
+func (s *Model) categoryButtons(ctx context.Context, userID int64) []domain.InlineButton {
+	categories, err := s.storage.GetUserCategories(ctx, userID)
+	if err != nil {
+		return nil
+	}
+
+	buttons := make([]domain.InlineButton, 0, len(categories))
+	for _, cat := range categories {
+		buttons = append(buttons, domain.InlineButton{Text: cat.Name, Data: categoryCallbackPrefix + strconv.FormatInt(cat.ID, 10)})
+	}
+	return buttons
+}
+
+// callbackCategory - название категории из данных кнопки; в кнопках, отправленных до перехода на id, - само название
+func (s *Model) callbackCategory(ctx context.Context, userID int64, data string) (string, error) {
+	categoryID, err := strconv.ParseInt(data, 10, 64)
+	if err != nil {
+		return data, nil
+	}
+
+	cat, err := s.storage.GetCategoryName(ctx, userID, categoryID)
+	if err == common.ErrCategoryNotFound {
+		return "", errCategoryNotFound
+	}
+	if err != nil {
+		return "", errServer
+	}
+	return cat, nil
+}
+
+func (s *Model) GetReport(ctx context.Context, userID int64, now time.Time, text string) (string, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_report_command")
+	defer span.Finish()
+
+	lang := languageFrom(ctx)
+	tag, text := parseReportTag(text)
+
+	period, err := parseReportPeriod(lang, text, now)
+	if err != nil {
+		return "", err
+	}
+
+	if tag != "" {
+		return s.getTagReport(ctx, userID, tag, period)
+	}
+
+	totalMap := s.storage.GetExpencesMap(ctx, userID, period.Start, period.End)
+
+	income, err := s.storage.GetIncomeTotal(ctx, userID, period.Start, period.End)
+	if err != nil {
+		return "", errServer
+	}
+
+	if len(totalMap) == 0 && income == 0 {
+		return lang.tr("No expences!"), nil
+	}
+
+	memberTotals, err := s.storage.GetMemberTotals(ctx, userID, period.Start, period.End)
+	if err != nil {
+		return "", errServer
+	}
+	members := make([]reportRow, 0, len(memberTotals))
+	for _, member := range memberTotals {
+		members = append(members, reportRow{Category: memberName(lang, member.UserID, member.Name), Total: member.Total})
+	}
+
+	currencyTotals, err := s.storage.GetCurrencyTotals(ctx, userID, period.Start, period.End)
+	if err != nil {
+		return "", errServer
+	}
+	currencies := make([]reportRow, 0, len(currencyTotals))
+	for _, total := range currencyTotals {
+		currencies = append(currencies, reportRow{
+			Category: fmt.Sprintf("%s %s", total.CurrencyCode, lang.amount(total.OriginalTotal)),
+			Total:    total.Total,
+		})
+	}
+
+	// превышение лимитов месяцев, которые затрагивает период
+	overspend, err := s.storage.GetOverspend(ctx, userID, period.Start, period.End)
+	if err != nil {
+		return "", errServer
+	}
+
+	return formatReport(lang, period.Title, totalMap, income, overspend, members, currencies), nil
+}
+
+// добавление дохода
+func (s *Model) AddIncome(ctx context.Context, userID int64, now time.Time, text string) (string, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "add_income_command")
+	defer span.Finish()
+
+	commandArgs := strings.Split(text, " ")
+
+	// проверка, что 3 аргумента
+	if len(commandArgs) != 3 {
+		return "", errWrongCommandFormat
+	}
+
+	// проверка, что 2ой аргумент (доход) является числом
+	total, err := helpers.ConvertStringAmountToSub(commandArgs[1])
+	if err != nil {
+		return "", err
+	}
+
+	// проверка, что 3ий аргумент (дата) является датой
+	date, err := helpers.ParseDate(commandArgs[2], now)
+	if err != nil {
+		return "", errDateWrongFormat
+	}
+	if err := checkDateAhead(date, now); err != nil {
+		return "", err
+	}
+
+	if err := s.storage.AddIncome(ctx, userID, commandArgs[0], total, date); err != nil {
+		return "", errServer
+	}
+
+	return languageFrom(ctx).tr("Income added"), nil
+}
+
+func (s *Model) ChangeCurrency(ctx context.Context, userID int64, memberID int64, text string) (string, error) {
 	span, ctx := opentracing.StartSpanFromContext(ctx, "change_currency_command")
 	defer span.Finish()
 
@@ -289,14 +1072,15 @@ func (s *Model) ChangeCurrency(ctx context.Context, userID int64, text string) (
 		return "", errWrongCommandFormat
 	}
 
-	if !s.storage.ChangeCurrency(ctx, userID, commandArgs[0]) {
-		return "Currency not found", nil
+	lang := languageFrom(ctx)
+	if !s.storage.ChangeCurrency(ctx, userID, memberID, commandArgs[0]) {
+		return lang.tr("Currency not found"), nil
 	}
 
-	return "Currency successfully changed", nil
+	return lang.tr("Currency successfully changed"), nil
 }
 
-func (s *Model) SetUserLimit(ctx context.Context, userID int64, text string) (string, error) {
+func (s *Model) SetUserLimit(ctx context.Context, userID int64, memberID int64, text string) (string, error) {
 	span, ctx := opentracing.StartSpanFromContext(ctx, "set_user_limit_command")
 	defer span.Finish()
 
@@ -316,19 +1100,19 @@ func (s *Model) SetUserLimit(ctx context.Context, userID int64, text string) (st
 		return "", errLimitIsTooSmall
 	}
 
-	if err := s.storage.SetUserLimit(ctx, userID, total); err != nil {
+	if err := s.storage.SetUserLimit(ctx, userID, memberID, total); err != nil {
 		return "", err
 	}
 
-	return "Month limit updated!", nil
+	return languageFrom(ctx).tr("Month limit updated!"), nil
 }
 
-func (s *Model) ResetUserLimit(ctx context.Context, userID int64) (string, error) {
+func (s *Model) ResetUserLimit(ctx context.Context, userID int64, memberID int64) (string, error) {
 	span, ctx := opentracing.StartSpanFromContext(ctx, "reset_user_limit_command")
 	defer span.Finish()
 
-	if err := s.storage.ResetUserLimit(ctx, userID); err != nil {
+	if err := s.storage.ResetUserLimit(ctx, userID, memberID); err != nil {
 		return "", errResetLimit
 	}
-	return "Month limit reseted", nil
+	return languageFrom(ctx).tr("Month limit reseted"), nil
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/go-redis/redismock/v8"
@@ -25,10 +26,12 @@ func (r *ReportRequestProducer) GetReportRequestChan() chan domain.ReportRequest
 
 type ExpencesGetter struct{}
 
-func (e *ExpencesGetter) GetReportExpencesChan() chan []domain.Expence {
+func (e *ExpencesGetter) WaitReportExpences(requestID int64) <-chan []domain.Expence {
 	return make(chan []domain.Expence)
 }
 
+func (e *ExpencesGetter) CancelReportExpences(requestID int64) {}
+
 func Test_OnStartCommand_ShouldAnswerWithIntroMessage(t *testing.T) {
 	db, _, err := sqlmock.New()
 	if err != nil {
@@ -45,6 +48,9 @@ func Test_OnStartCommand_ShouldAnswerWithIntroMessage(t *testing.T) {
 	categoriesDB := database.NewCategoriesDB(db)
 	currenciesDB := database.NewCurrenciesDB(db)
 	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
 
 	ctrl := gomock.NewController(t)
 	sender := mocks.NewMockMessageSender(ctrl)
@@ -52,7 +58,7 @@ func Test_OnStartCommand_ShouldAnswerWithIntroMessage(t *testing.T) {
 	r := &ReportRequestProducer{}
 	e := &ExpencesGetter{}
 
-	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, reportDB, r, e)
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
 	model := New(sender, storageModel)
 
 	sender.EXPECT().SendMessage("Welcomen!", int64(123))
@@ -82,6 +88,9 @@ func Test_OnUnknownCommand_ShouldAnswerWithHelpMessage(t *testing.T) {
 	categoriesDB := database.NewCategoriesDB(db)
 	currenciesDB := database.NewCurrenciesDB(db)
 	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
 
 	rdb, _ := redismock.NewClientMock()
 	reportDB := database.NewReportCacheDb(rdb)
@@ -92,11 +101,11 @@ func Test_OnUnknownCommand_ShouldAnswerWithHelpMessage(t *testing.T) {
 	r := &ReportRequestProducer{}
 	e := &ExpencesGetter{}
 
-	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, reportDB, r, e)
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
 	model := New(sender, storageModel)
-	sender.EXPECT().SendMessage(model.Help(), int64(123))
+	sender.EXPECT().SendMessage(model.Help(context.Background()), int64(123))
 
-	err = model.IncomingPlainTextMessage(PlainTextMessage{
+	err = model.IncomingPlainTextMessage(context.Background(), PlainTextMessage{
 		Message: Message{
 			UserID: 123,
 		},
@@ -119,6 +128,9 @@ func Test_OnAddCategoryCommand_ShouldAnswerWithAddedMessage(t *testing.T) {
 	categoriesDB := database.NewCategoriesDB(db)
 	currenciesDB := database.NewCurrenciesDB(db)
 	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
 
 	rdb, _ := redismock.NewClientMock()
 	reportDB := database.NewReportCacheDb(rdb)
@@ -129,13 +141,14 @@ func Test_OnAddCategoryCommand_ShouldAnswerWithAddedMessage(t *testing.T) {
 	r := &ReportRequestProducer{}
 	e := &ExpencesGetter{}
 
-	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, reportDB, r, e)
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
 	model := New(sender, storageModel)
 
 	columns := []string{"id"}
 	mock.ExpectExec("INSERT INTO users").WithArgs(123).WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("food", 123).WillReturnRows(mock.NewRows(columns))
-	mock.ExpectExec("INSERT INTO expence_category").WithArgs(123, "food").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("INSERT INTO expence_category").WithArgs(123, "food").WillReturnRows(mock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec("INSERT INTO operations").WithArgs(123, 123, "add_category", 1, 0, 0).WillReturnResult(sqlmock.NewResult(1, 1))
 
 	sender.EXPECT().SendMessage("Category food is added", int64(123))
 
@@ -162,6 +175,9 @@ func Test_OnAddCategoryCommand_ShouldAnswerWithErr(t *testing.T) {
 	categoriesDB := database.NewCategoriesDB(db)
 	currenciesDB := database.NewCurrenciesDB(db)
 	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
 
 	rdb, _ := redismock.NewClientMock()
 	reportDB := database.NewReportCacheDb(rdb)
@@ -171,13 +187,14 @@ func Test_OnAddCategoryCommand_ShouldAnswerWithErr(t *testing.T) {
 	r := &ReportRequestProducer{}
 	e := &ExpencesGetter{}
 
-	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, reportDB, r, e)
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
 	model := New(sender, storageModel)
 
 	columns := []string{"id"}
 	mock.ExpectExec("INSERT INTO users").WithArgs(123).WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("food", 123).WillReturnRows(mock.NewRows(columns))
-	mock.ExpectExec("INSERT INTO expence_category").WithArgs(123, "food").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("INSERT INTO expence_category").WithArgs(123, "food").WillReturnRows(mock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec("INSERT INTO operations").WithArgs(123, 123, "add_category", 1, 0, 0).WillReturnResult(sqlmock.NewResult(1, 1))
 
 	mock.ExpectExec("INSERT INTO users").WithArgs(123).WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("food", 123).WillReturnRows(mock.NewRows(columns).AddRow(1))
@@ -217,6 +234,9 @@ func Test_OnAddExpenceCommand_ShouldAnswerWithNoCatErr(t *testing.T) {
 	categoriesDB := database.NewCategoriesDB(db)
 	currenciesDB := database.NewCurrenciesDB(db)
 	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
 
 	rdb, _ := redismock.NewClientMock()
 	reportDB := database.NewReportCacheDb(rdb)
@@ -226,7 +246,7 @@ func Test_OnAddExpenceCommand_ShouldAnswerWithNoCatErr(t *testing.T) {
 	r := &ReportRequestProducer{}
 	e := &ExpencesGetter{}
 
-	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, reportDB, r, e)
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
 	model := New(sender, storageModel)
 
 	columns := []string{"id"}
@@ -262,6 +282,9 @@ func Test_OnAddExpenceCommand_ShouldAnswerWithExpAdded(t *testing.T) {
 	categoriesDB := database.NewCategoriesDB(db)
 	currenciesDB := database.NewCurrenciesDB(db)
 	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
 
 	rdb, mocksRedis := redismock.NewClientMock()
 	reportDB := database.NewReportCacheDb(rdb)
@@ -271,7 +294,7 @@ func Test_OnAddExpenceCommand_ShouldAnswerWithExpAdded(t *testing.T) {
 	r := &ReportRequestProducer{}
 	e := &ExpencesGetter{}
 
-	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, reportDB, r, e)
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
 	model := New(sender, storageModel)
 
 	mocksRedis.ExpectKeys("123*")
@@ -279,18 +302,23 @@ func Test_OnAddExpenceCommand_ShouldAnswerWithExpAdded(t *testing.T) {
 	columns := []string{"id"}
 	mock.ExpectExec("INSERT INTO users").WithArgs(123).WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("food", 123).WillReturnRows(mock.NewRows(columns))
-	mock.ExpectExec("INSERT INTO expence_category").WithArgs(123, "food").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("INSERT INTO expence_category").WithArgs(123, "food").WillReturnRows(mock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec("INSERT INTO operations").WithArgs(123, 123, "add_category", 1, 0, 0).WillReturnResult(sqlmock.NewResult(1, 1))
 
 	mock.ExpectExec("INSERT INTO users").WithArgs(123).WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("food", 123).WillReturnRows(mock.NewRows(columns).AddRow(1))
 	mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("food", 123).WillReturnRows(mock.NewRows(columns).AddRow(1))
 	mock.ExpectQuery("SELECT base_currency_id").WithArgs(123).WillReturnRows(mock.NewRows(columns).AddRow(1))
-	mock.ExpectQuery("SELECT rate").WithArgs(1).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT COALESCE\\(\\(SELECT rate FROM currency_rates").WithArgs(1, sqlmock.AnyArg(), 1).WillReturnRows(mock.NewRows(columns).AddRow(1))
 
 	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT limit_month").WithArgs(123).WillReturnRows(mock.NewRows([]string{"limit_month"}))
 	date, _ := helpers.StringToDate("09/10/2012")
-	mock.ExpectExec("INSERT INTO expences").WithArgs(123, 1, date, 10000).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("INSERT INTO expences").WithArgs(123, 1, date, 10000, 123, "", "{}", 1, 10000).WillReturnRows(mock.NewRows([]string{"id"}).AddRow(1))
 	mock.ExpectCommit()
+	mock.ExpectExec("INSERT INTO operations").WithArgs(123, 123, "add_expence", 1, 0, 0).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT COALESCE\\(default_month_limit, 0\\), COALESCE\\(current_month_limit, 0\\), limit_alerts, limit_alert_fired FROM users").WithArgs(123).WillReturnRows(
+		mock.NewRows([]string{"default_month_limit", "current_month_limit", "limit_alerts", "limit_alert_fired"}).AddRow(1000000, 990000, "{50,80,100}", 0))
 
 	sender.EXPECT().SendMessage("Category food is added", int64(123))
 	sender.EXPECT().SendMessage("Expence added", int64(123))
@@ -313,3 +341,1115 @@ func Test_OnAddExpenceCommand_ShouldAnswerWithExpAdded(t *testing.T) {
 	})
 	assert.NoError(t, err)
 }
+
+func Test_OnPlainTextExpence_ShouldAskForCategoryAndAddExpence(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, mocksRedis := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+
+	mocksRedis.ExpectKeys("123*")
+
+	columns := []string{"id"}
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(mock.NewRows([]string{"id", "timezone", "language"}).AddRow(123, "", ""))
+	mock.ExpectQuery("SELECT id, name FROM expence_category").WithArgs(123).WillReturnRows(mock.NewRows([]string{"id", "name"}))
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(mock.NewRows([]string{"id", "timezone", "language"}).AddRow(123, "", ""))
+	mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("food", 123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("food", 123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT id FROM currency").WithArgs("RUB").WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT COALESCE\\(\\(SELECT rate FROM currency_rates").WithArgs(1, sqlmock.AnyArg(), 1).WillReturnRows(mock.NewRows(columns).AddRow(1))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT limit_month").WithArgs(123).WillReturnRows(mock.NewRows([]string{"limit_month"}))
+	date, _ := helpers.StringToDate("12/10/2012")
+	mock.ExpectQuery("INSERT INTO expences").WithArgs(123, 1, date, 340050, 123, "", "{}", 1, 340050).WillReturnRows(mock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+	mock.ExpectExec("INSERT INTO operations").WithArgs(123, 123, "add_expence", 1, 0, 0).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT COALESCE\\(default_month_limit, 0\\), COALESCE\\(current_month_limit, 0\\), limit_alerts, limit_alert_fired FROM users").WithArgs(123).WillReturnRows(
+		mock.NewRows([]string{"default_month_limit", "current_month_limit", "limit_alerts", "limit_alert_fired"}).AddRow(1000000, 990000, "{50,80,100}", 0))
+
+	sender.EXPECT().SendMessage("Which category?", int64(123))
+	sender.EXPECT().SendMessage("Expence added", int64(123))
+
+	err = model.IncomingPlainTextMessage(context.Background(), PlainTextMessage{
+		Message: Message{
+			UserID: 123,
+		},
+		Text: "3 400,50 руб 12/10/2012",
+	})
+	assert.NoError(t, err)
+
+	err = model.IncomingPlainTextMessage(context.Background(), PlainTextMessage{
+		Message: Message{
+			UserID: 123,
+		},
+		Text: "food",
+	})
+	assert.NoError(t, err)
+}
+
+func Test_OnCategoryButton_ShouldAddExpence(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, mocksRedis := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+
+	mocksRedis.ExpectKeys("123*")
+
+	columns := []string{"id"}
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(mock.NewRows([]string{"id", "timezone", "language"}).AddRow(123, "", ""))
+	mock.ExpectQuery("SELECT id, name FROM expence_category").WithArgs(123).WillReturnRows(mock.NewRows([]string{"id", "name"}).AddRow(1, "food").AddRow(2, "taxi"))
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(mock.NewRows([]string{"id", "timezone", "language"}).AddRow(123, "", ""))
+	mock.ExpectQuery("SELECT name FROM expence_category").WithArgs(1, 123).WillReturnRows(mock.NewRows([]string{"name"}).AddRow("food"))
+	mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("food", 123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("food", 123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT id FROM currency").WithArgs("RUB").WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT COALESCE\\(\\(SELECT rate FROM currency_rates").WithArgs(1, sqlmock.AnyArg(), 1).WillReturnRows(mock.NewRows(columns).AddRow(1))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT limit_month").WithArgs(123).WillReturnRows(mock.NewRows([]string{"limit_month"}))
+	date, _ := helpers.StringToDate("12/10/2012")
+	mock.ExpectQuery("INSERT INTO expences").WithArgs(123, 1, date, 340050, 123, "", "{}", 1, 340050).WillReturnRows(mock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+	mock.ExpectExec("INSERT INTO operations").WithArgs(123, 123, "add_expence", 1, 0, 0).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT COALESCE\\(default_month_limit, 0\\), COALESCE\\(current_month_limit, 0\\), limit_alerts, limit_alert_fired FROM users").WithArgs(123).WillReturnRows(
+		mock.NewRows([]string{"default_month_limit", "current_month_limit", "limit_alerts", "limit_alert_fired"}).AddRow(1000000, 990000, "{50,80,100}", 0))
+
+	sender.EXPECT().SendMessageWithButtons("Which category?", int64(123), []domain.InlineButton{
+		{Text: "food", Data: "category:1"},
+		{Text: "taxi", Data: "category:2"},
+	})
+	sender.EXPECT().SendMessage("Expence added", int64(123))
+
+	err = model.IncomingPlainTextMessage(context.Background(), PlainTextMessage{
+		Message: Message{
+			UserID: 123,
+		},
+		Text: "3 400,50 руб 12/10/2012",
+	})
+	assert.NoError(t, err)
+
+	err = model.IncomingCallbackMessage(context.Background(), CallbackMessage{
+		Message: Message{
+			UserID: 123,
+		},
+		Data: "category:1",
+	})
+	assert.NoError(t, err)
+}
+
+func Test_OnCategoryButtonWithoutAmount_ShouldAnswerWithError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, _ := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(mock.NewRows([]string{"id", "timezone", "language"}).AddRow(123, "", ""))
+
+	mock.ExpectQuery("SELECT name FROM expence_category").WithArgs(1, 123).WillReturnRows(mock.NewRows([]string{"name"}).AddRow("food"))
+
+	sender.EXPECT().SendMessage(errNoPendingExpence.Error(), int64(123))
+
+	err = model.IncomingCallbackMessage(context.Background(), CallbackMessage{
+		Message: Message{
+			UserID: 123,
+		},
+		Data: "category:1",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_OnButtonOfDeletedCategory_ShouldAnswerCategoryNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, _ := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(mock.NewRows([]string{"id", "timezone", "language"}).AddRow(123, "", ""))
+
+	mock.ExpectQuery("SELECT name FROM expence_category").WithArgs(1, 123).WillReturnRows(mock.NewRows([]string{"name"}))
+
+	sender.EXPECT().SendMessage(errCategoryNotFound.Error(), int64(123))
+
+	err = model.IncomingCallbackMessage(context.Background(), CallbackMessage{
+		Message: Message{
+			UserID: 123,
+		},
+		Data: "category:1",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_OnListCommand_ShouldAnswerWithExpencesPage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, _ := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+
+	columns := []string{"id"}
+	mock.ExpectExec("INSERT INTO users").WithArgs(123).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT base_currency_id").WithArgs(123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT COALESCE\\(\\(SELECT rate FROM currency_rates").WithArgs(1, sqlmock.AnyArg(), 1).WillReturnRows(mock.NewRows(columns).AddRow(1))
+
+	date, _ := helpers.StringToDate("09/10/2012")
+	mock.ExpectQuery("SELECT expences.id").WithArgs(123).WillReturnRows(
+		mock.NewRows([]string{"id", "category_id", "name", "ts", "total", "note", "tags", "currency_id", "code", "original_total"}).
+			AddRow(2, 1, "food", date, 10050, "dinner with clients", "{vacation}", 2, "EUR", 125).
+			AddRow(1, 2, "taxi", date, 30000, "", "{}", 1, "RUB", 30000))
+
+	sender.EXPECT().SendMessage("Expences, page 1\n#2 09/10/2012 food: 100.50 (1.25 EUR) #vacation dinner with clients\n#1 09/10/2012 taxi: 300.00\n", int64(123))
+
+	err = model.IncomingCommandMessage(context.Background(), CommandMessage{
+		Message: Message{
+			UserID: 123,
+		},
+		CommandName: "list",
+	})
+	assert.NoError(t, err)
+}
+
+func Test_OnDeleteExpenceCommand_ShouldReturnTotalToMonthLimit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, mocksRedis := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+
+	mocksRedis.ExpectKeys("123*").SetVal([]string{})
+
+	mock.ExpectExec("INSERT INTO users").WithArgs(123).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectBegin()
+	mock.ExpectQuery("DELETE FROM expences").WithArgs(5, 123).WillReturnRows(
+		mock.NewRows([]string{"category_id", "ts", "total"}).AddRow(1, helpers.DateOf(time.Now()), 10000))
+	mock.ExpectQuery("SELECT limit_month").WithArgs(123).WillReturnRows(mock.NewRows([]string{"limit_month"}))
+	mock.ExpectExec("UPDATE users SET current_month_limit").WithArgs(10000, 123).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("UPDATE expence_category SET current_month_limit").WithArgs(-10000, 1).WillReturnRows(
+		mock.NewRows([]string{"current_month_limit", "name"}).AddRow(20000, "food"))
+	mock.ExpectCommit()
+
+	sender.EXPECT().SendMessage("Expence deleted", int64(123))
+
+	err = model.IncomingCommandMessage(context.Background(), CommandMessage{
+		Message: Message{
+			UserID: 123,
+		},
+		CommandName:      "delete_expence",
+		CommandArguments: "5",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.NoError(t, mocksRedis.ExpectationsWereMet())
+}
+
+func Test_OnDeleteUnknownExpence_ShouldAnswerWithNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, _ := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+
+	mock.ExpectExec("INSERT INTO users").WithArgs(123).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectBegin()
+	mock.ExpectQuery("DELETE FROM expences").WithArgs(5, 123).WillReturnRows(mock.NewRows([]string{"category_id", "ts", "total"}))
+	mock.ExpectRollback()
+
+	sender.EXPECT().SendMessage(errExpenceNotFound.Error(), int64(123))
+
+	err = model.IncomingCommandMessage(context.Background(), CommandMessage{
+		Message: Message{
+			UserID: 123,
+		},
+		CommandName:      "delete_expence",
+		CommandArguments: "5",
+	})
+	assert.NoError(t, err)
+}
+
+func Test_OnMergeCategoryCommand_ShouldMoveExpences(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, mocksRedis := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+
+	mocksRedis.ExpectKeys("123*").SetVal([]string{})
+
+	columns := []string{"id"}
+	mock.ExpectExec("INSERT INTO users").WithArgs(123).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("fod", 123).WillReturnRows(mock.NewRows(columns).AddRow(2))
+	mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("food", 123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE expence_category SET current_month_limit").WithArgs(2, helpers.GetStartOfCurrentMonth(), 1).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE expences SET category_id").WithArgs(1, 2, 123).WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec("UPDATE recurring_expences SET category_id").WithArgs(1, 2, 123).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE category_keywords SET category_id").WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("DELETE FROM expence_category").WithArgs(2, 123).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	sender.EXPECT().SendMessage("Category fod is merged into food", int64(123))
+
+	err = model.IncomingCommandMessage(context.Background(), CommandMessage{
+		Message: Message{
+			UserID: 123,
+		},
+		CommandName:      "merge_category",
+		CommandArguments: "fod food",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_OnDeleteCategoryWithExpences_ShouldRefuse(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, _ := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+
+	columns := []string{"id"}
+	mock.ExpectExec("INSERT INTO users").WithArgs(123).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("food", 123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT EXISTS").WithArgs(1).WillReturnRows(mock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	sender.EXPECT().SendMessage(errCategoryNotEmpty.Error(), int64(123))
+
+	err = model.IncomingCommandMessage(context.Background(), CommandMessage{
+		Message: Message{
+			UserID: 123,
+		},
+		CommandName:      "delete_category",
+		CommandArguments: "food",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_OnDeleteCategoryWithRecurringExpences_ShouldRefuse(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, _ := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+
+	columns := []string{"id"}
+	mock.ExpectExec("INSERT INTO users").WithArgs(123).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("food", 123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM expences").WithArgs(1).WillReturnRows(mock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM recurring_expences").WithArgs(1).WillReturnRows(mock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	sender.EXPECT().SendMessage(errCategoryHasRecurring.Error(), int64(123))
+
+	err = model.IncomingCommandMessage(context.Background(), CommandMessage{
+		Message: Message{
+			UserID: 123,
+		},
+		CommandName:      "delete_category",
+		CommandArguments: "food",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_OnReportCommand_ShouldAnswerWithIncomeAndBalance(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, mocksRedis := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+
+	startTs := helpers.StartOfMonth(helpers.DateOf(time.Now()))
+	endTs := startTs.AddDate(0, 1, 0)
+	mocksRedis.ExpectHGetAll(fmt.Sprintf("123%d_%d", startTs.Unix(), endTs.Unix())).SetVal(map[string]string{"food": "10000"})
+
+	columns := []string{"id"}
+	mock.ExpectExec("INSERT INTO users").WithArgs(123).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT base_currency_id").WithArgs(123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT COALESCE\\(\\(SELECT rate FROM currency_rates").WithArgs(1, sqlmock.AnyArg(), 1).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT COALESCE").WithArgs(123, startTs, endTs).WillReturnRows(mock.NewRows([]string{"sum"}).AddRow(50000))
+	mock.ExpectQuery("SELECT base_currency_id").WithArgs(123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT COALESCE\\(\\(SELECT rate FROM currency_rates").WithArgs(1, sqlmock.AnyArg(), 1).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT COALESCE\\(expences.added_by, 0\\)").WithArgs(123, startTs, endTs).WillReturnRows(
+		mock.NewRows([]string{"added_by", "name", "sum"}).AddRow(123, "", 10000))
+	mock.ExpectQuery("SELECT base_currency_id").WithArgs(123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT COALESCE\\(\\(SELECT rate FROM currency_rates").WithArgs(1, sqlmock.AnyArg(), 1).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT currency.id").WithArgs(123, startTs, endTs).WillReturnRows(
+		mock.NewRows([]string{"id", "code", "original_total", "total"}).
+			AddRow(2, "EUR", 125, 10000).
+			AddRow(1, "RUB", 0, 0))
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(mock.NewRows([]string{"id", "timezone", "language"}).AddRow(123, "", ""))
+	mock.ExpectQuery("SELECT COALESCE\\(SUM\\(-remainder\\), 0\\)").WithArgs(123, startTs, endTs, sqlmock.AnyArg()).WillReturnRows(mock.NewRows([]string{"sum"}).AddRow(0))
+
+	sender.EXPECT().SendFormattedMessage("<b>Last month expences</b>\n<pre>"+
+		"food     100.00 100.0% ██████████\n"+
+		"───────────────\n"+
+		"Total    100.00\n"+
+		"Income   500.00\n"+
+		"Balance  400.00\n"+
+		"───────────────\n"+
+		"EUR 1.25 100.00 100.0%</pre>", int64(123), "HTML")
+
+	err = model.IncomingCommandMessage(context.Background(), CommandMessage{
+		Message: Message{
+			UserID: 123,
+		},
+		CommandName:      "report",
+		CommandArguments: "month",
+	})
+	assert.NoError(t, err)
+}
+
+func Test_OnEditForeignCurrencyExpence_ShouldReplaceCurrencyWithBase(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, mocksRedis := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+
+	endTs := helpers.StartOfMonth(helpers.DateOf(time.Now()))
+	startTs := endTs.AddDate(0, -1, 0)
+
+	// трата 12.50 EUR прошлого месяца меняется на 100 в базовой валюте
+	columns := []string{"id"}
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(mock.NewRows([]string{"id", "timezone", "language"}).AddRow(123, "", ""))
+	mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("food", 123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("food", 123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT base_currency_id").WithArgs(123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT COALESCE\\(\\(SELECT rate FROM currency_rates").WithArgs(1, sqlmock.AnyArg(), 1).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT category_id, ts, total FROM expences").WithArgs(5, 123).WillReturnRows(
+		mock.NewRows([]string{"category_id", "ts", "total"}).AddRow(1, startTs, 98000))
+	mock.ExpectQuery("SELECT limit_month").WithArgs(123).WillReturnRows(mock.NewRows([]string{"limit_month"}))
+	mock.ExpectExec("UPDATE expences SET category_id = \\$1, ts = \\$2, total = \\$3, currency_id = \\$4, original_total = \\$5").
+		WithArgs(1, sqlmock.AnyArg(), 10000, 1, 10000, 5, 123).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mocksRedis.ExpectKeys("123*").SetVal([]string{})
+
+	sender.EXPECT().SendMessage("Expence changed", int64(123))
+
+	err = model.IncomingCommandMessage(context.Background(), CommandMessage{
+		Message: Message{
+			UserID: 123,
+		},
+		CommandName:      "edit_expence",
+		CommandArguments: "5 food 100 " + startTs.Format("02/01/2006"),
+	})
+	assert.NoError(t, err)
+
+	// после изменения траты в отчёте нет раздела с EUR
+	mocksRedis.ExpectHGetAll(fmt.Sprintf("123%d_%d", startTs.Unix(), endTs.Unix())).SetVal(map[string]string{"food": "10000"})
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(mock.NewRows([]string{"id", "timezone", "language"}).AddRow(123, "", ""))
+	mock.ExpectQuery("SELECT base_currency_id").WithArgs(123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT COALESCE\\(\\(SELECT rate FROM currency_rates").WithArgs(1, sqlmock.AnyArg(), 1).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT COALESCE").WithArgs(123, startTs, endTs).WillReturnRows(mock.NewRows([]string{"sum"}).AddRow(0))
+	mock.ExpectQuery("SELECT base_currency_id").WithArgs(123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT COALESCE\\(\\(SELECT rate FROM currency_rates").WithArgs(1, sqlmock.AnyArg(), 1).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT COALESCE\\(expences.added_by, 0\\)").WithArgs(123, startTs, endTs).WillReturnRows(
+		mock.NewRows([]string{"added_by", "name", "sum"}))
+	mock.ExpectQuery("SELECT base_currency_id").WithArgs(123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT COALESCE\\(\\(SELECT rate FROM currency_rates").WithArgs(1, sqlmock.AnyArg(), 1).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT currency.id").WithArgs(123, startTs, endTs).WillReturnRows(
+		mock.NewRows([]string{"id", "code", "original_total", "total"}).AddRow(1, "RUB", 10000, 10000))
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(mock.NewRows([]string{"id", "timezone", "language"}).AddRow(123, "", ""))
+	mock.ExpectQuery("SELECT COALESCE\\(SUM\\(-remainder\\), 0\\)").WithArgs(123, startTs, endTs, sqlmock.AnyArg()).WillReturnRows(mock.NewRows([]string{"sum"}).AddRow(0))
+
+	sender.EXPECT().SendFormattedMessage("<b>Previous month expences</b>\n<pre>"+
+		"food     100.00 100.0% ██████████\n"+
+		"───────────────\n"+
+		"Total    100.00\n"+
+		"Income      0.0\n"+
+		"Balance -100.00</pre>", int64(123), "HTML")
+
+	err = model.IncomingCommandMessage(context.Background(), CommandMessage{
+		Message: Message{
+			UserID: 123,
+		},
+		CommandName:      "report",
+		CommandArguments: "lastmonth",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.NoError(t, mocksRedis.ExpectationsWereMet())
+}
+
+func Test_OnLastMonthReport_ShouldUseRateAtEndOfMonth(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, mocksRedis := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+
+	endTs := helpers.StartOfMonth(helpers.DateOf(time.Now()))
+	startTs := endTs.AddDate(0, -1, 0)
+	// курс на последний день прошлого месяца, а не сегодняшний
+	rateDate := endTs.Add(-time.Nanosecond)
+	mocksRedis.ExpectHGetAll(fmt.Sprintf("123%d_%d", startTs.Unix(), endTs.Unix())).SetVal(map[string]string{"food": "20000"})
+
+	columns := []string{"id"}
+	mock.ExpectExec("INSERT INTO users").WithArgs(123).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT base_currency_id").WithArgs(123).WillReturnRows(mock.NewRows(columns).AddRow(2))
+	mock.ExpectQuery("SELECT COALESCE\\(\\(SELECT rate FROM currency_rates").WithArgs(2, rateDate, 2).WillReturnRows(mock.NewRows(columns).AddRow(0.02))
+	mock.ExpectQuery("SELECT COALESCE").WithArgs(123, startTs, endTs).WillReturnRows(mock.NewRows([]string{"sum"}).AddRow(2500000))
+	mock.ExpectQuery("SELECT base_currency_id").WithArgs(123).WillReturnRows(mock.NewRows(columns).AddRow(2))
+	mock.ExpectQuery("SELECT COALESCE\\(\\(SELECT rate FROM currency_rates").WithArgs(2, rateDate, 2).WillReturnRows(mock.NewRows(columns).AddRow(0.02))
+	mock.ExpectQuery("SELECT COALESCE\\(expences.added_by, 0\\)").WithArgs(123, startTs, endTs).WillReturnRows(
+		mock.NewRows([]string{"added_by", "name", "sum"}))
+	mock.ExpectQuery("SELECT base_currency_id").WithArgs(123).WillReturnRows(mock.NewRows(columns).AddRow(2))
+	mock.ExpectQuery("SELECT COALESCE\\(\\(SELECT rate FROM currency_rates").WithArgs(2, rateDate, 2).WillReturnRows(mock.NewRows(columns).AddRow(0.02))
+	mock.ExpectQuery("SELECT currency.id").WithArgs(123, startTs, endTs).WillReturnRows(
+		mock.NewRows([]string{"id", "code", "original_total", "total"}))
+	// лимит прошлого месяца превышен, хотя текущий месяц ещё в пределах лимита
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(mock.NewRows([]string{"id", "timezone", "language"}).AddRow(123, "", ""))
+	mock.ExpectQuery("SELECT COALESCE\\(SUM\\(-remainder\\), 0\\)").WithArgs(123, startTs, endTs, sqlmock.AnyArg()).WillReturnRows(mock.NewRows([]string{"sum"}).AddRow(50000))
+	mock.ExpectQuery("SELECT base_currency_id").WithArgs(123).WillReturnRows(mock.NewRows(columns).AddRow(2))
+	mock.ExpectQuery("SELECT COALESCE\\(\\(SELECT rate FROM currency_rates").WithArgs(2, rateDate, 2).WillReturnRows(mock.NewRows(columns).AddRow(0.02))
+
+	sender.EXPECT().SendFormattedMessage("<b>Previous month expences</b>\n<pre>"+
+		"food        200.00 100.0% ██████████\n"+
+		"──────────────────\n"+
+		"Total       200.00\n"+
+		"Income      500.00\n"+
+		"Balance     300.00\n"+
+		"Over budget  10.00</pre>", int64(123), "HTML")
+
+	err = model.IncomingCommandMessage(context.Background(), CommandMessage{
+		Message: Message{
+			UserID: 123,
+		},
+		CommandName:      "report",
+		CommandArguments: "lastmonth",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_OnAddExpenceOverCategoryLimit_ShouldAnswerWithCategory(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, _ := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+
+	columns := []string{"id"}
+	mock.ExpectExec("INSERT INTO users").WithArgs(123).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("food", 123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("food", 123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT base_currency_id").WithArgs(123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT COALESCE\\(\\(SELECT rate FROM currency_rates").WithArgs(1, sqlmock.AnyArg(), 1).WillReturnRows(mock.NewRows(columns).AddRow(1))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT limit_month").WithArgs(123).WillReturnRows(mock.NewRows([]string{"limit_month"}))
+	mock.ExpectQuery("UPDATE users SET current_month_limit").WithArgs(10000, 123).WillReturnRows(mock.NewRows([]string{"current_month_limit", "soft_limit"}).AddRow(90000, false))
+	mock.ExpectQuery("UPDATE expence_category SET current_month_limit").WithArgs(10000, 1).WillReturnRows(
+		mock.NewRows([]string{"current_month_limit", "name"}).AddRow(-5000, "food"))
+	mock.ExpectRollback()
+
+	sender.EXPECT().SendMessage("Month limit for category food exceeded", int64(123))
+
+	err = model.IncomingCommandMessage(context.Background(), CommandMessage{
+		Message: Message{
+			UserID: 123,
+		},
+		CommandName:      "add_expence",
+		CommandArguments: "food 100 " + helpers.GetStartOfCurrentDay().Format("02/01/2006"),
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_OnAddExpenceInOtherCurrency_ShouldKeepOriginalAmount(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, mocksRedis := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+
+	mocksRedis.ExpectKeys("123*").SetVal([]string{})
+
+	columns := []string{"id"}
+	date, _ := helpers.StringToDate("12/10/2012")
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(mock.NewRows([]string{"id", "timezone", "language"}).AddRow(123, "", ""))
+	mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("food", 123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("food", 123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT id FROM currency").WithArgs("EUR").WillReturnRows(mock.NewRows(columns).AddRow(3))
+	mock.ExpectQuery("SELECT COALESCE\\(\\(SELECT rate FROM currency_rates").WithArgs(3, date, 3).WillReturnRows(mock.NewRows(columns).AddRow(0.5))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT limit_month").WithArgs(123).WillReturnRows(mock.NewRows([]string{"limit_month"}))
+	mock.ExpectQuery("INSERT INTO expences").WithArgs(123, 1, date, 2500, 123, "", "{}", 3, 1250).WillReturnRows(mock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+	mock.ExpectExec("INSERT INTO operations").WithArgs(123, 123, "add_expence", 1, 0, 0).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT COALESCE\\(default_month_limit, 0\\), COALESCE\\(current_month_limit, 0\\), limit_alerts, limit_alert_fired FROM users").WithArgs(123).WillReturnRows(
+		mock.NewRows([]string{"default_month_limit", "current_month_limit", "limit_alerts", "limit_alert_fired"}).AddRow(1000000, 990000, "{50,80,100}", 0))
+
+	sender.EXPECT().SendMessage("Expence added", int64(123))
+
+	err = model.IncomingCommandMessage(context.Background(), CommandMessage{
+		Message: Message{
+			UserID: 123,
+		},
+		CommandName:      "add_expence",
+		CommandArguments: "food 12.50 eur 12/10/2012",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.NoError(t, mocksRedis.ExpectationsWereMet())
+}
+
+func Test_OnAddExpenceInUnknownCurrency_ShouldAnswerWithError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, _ := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+	// валюта указана в конфигурации, но её нет в базе
+	model.SetCurrencies([]domain.Currency{{Code: "USD"}, {Code: "GBP"}})
+
+	columns := []string{"id"}
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(mock.NewRows([]string{"id", "timezone", "language"}).AddRow(123, "", ""))
+	mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("food", 123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("food", 123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT id FROM currency").WithArgs("GBP").WillReturnRows(mock.NewRows(columns))
+
+	sender.EXPECT().SendMessage("currency was not found - use USD/CNY/EUR/RUB", int64(123))
+
+	err = model.IncomingCommandMessage(context.Background(), CommandMessage{
+		Message: Message{
+			UserID: 123,
+		},
+		CommandName:      "add_expence",
+		CommandArguments: "food 12.50 GBP 12/10/2012",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_OnAddExpenceWithoutDate_ShouldAddForToday(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, mocksRedis := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+
+	mocksRedis.ExpectKeys("123*").SetVal([]string{})
+
+	columns := []string{"id"}
+	today := helpers.DateOf(time.Now())
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(mock.NewRows([]string{"id", "timezone", "language"}).AddRow(123, "", ""))
+	mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("food", 123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("food", 123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT base_currency_id").WithArgs(123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT COALESCE\\(\\(SELECT rate FROM currency_rates").WithArgs(1, today, 1).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT limit_month").WithArgs(123).WillReturnRows(mock.NewRows([]string{"limit_month"}))
+	mock.ExpectQuery("UPDATE users SET current_month_limit").WithArgs(10000, 123).WillReturnRows(mock.NewRows([]string{"current_month_limit", "soft_limit"}).AddRow(90000, false))
+	mock.ExpectQuery("UPDATE expence_category SET current_month_limit").WithArgs(10000, 1).WillReturnRows(
+		mock.NewRows([]string{"current_month_limit", "name"}))
+	mock.ExpectQuery("INSERT INTO expences").WithArgs(123, 1, today, 10000, 123, "lunch", "{\"work\"}", 1, 10000).WillReturnRows(mock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+	mock.ExpectExec("INSERT INTO operations").WithArgs(123, 123, "add_expence", 1, 0, 0).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT COALESCE\\(default_month_limit, 0\\), COALESCE\\(current_month_limit, 0\\), limit_alerts, limit_alert_fired FROM users").WithArgs(123).WillReturnRows(
+		mock.NewRows([]string{"default_month_limit", "current_month_limit", "limit_alerts", "limit_alert_fired"}).AddRow(1000000, 990000, "{50,80,100}", 0))
+
+	sender.EXPECT().SendMessage("Expence added", int64(123))
+
+	err = model.IncomingCommandMessage(context.Background(), CommandMessage{
+		Message: Message{
+			UserID: 123,
+		},
+		CommandName:      "add_expence",
+		CommandArguments: "food 100 #work lunch",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.NoError(t, mocksRedis.ExpectationsWereMet())
+}
+
+func Test_OnAddExpenceWithNoteOfThreeLetterWord_ShouldNotReadItAsCurrency(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, mocksRedis := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+	model.SetCurrencies([]domain.Currency{{Code: "USD"}, {Code: "EUR"}})
+
+	mocksRedis.ExpectKeys("123*").SetVal([]string{})
+
+	// "new" - не валюта, а начало комментария
+	columns := []string{"id"}
+	today := helpers.DateOf(time.Now())
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(mock.NewRows([]string{"id", "timezone", "language"}).AddRow(123, "", ""))
+	mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("food", 123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("food", 123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT base_currency_id").WithArgs(123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT COALESCE\\(\\(SELECT rate FROM currency_rates").WithArgs(1, today, 1).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT limit_month").WithArgs(123).WillReturnRows(mock.NewRows([]string{"limit_month"}))
+	mock.ExpectQuery("UPDATE users SET current_month_limit").WithArgs(10000, 123).WillReturnRows(mock.NewRows([]string{"current_month_limit", "soft_limit"}).AddRow(90000, false))
+	mock.ExpectQuery("UPDATE expence_category SET current_month_limit").WithArgs(10000, 1).WillReturnRows(
+		mock.NewRows([]string{"current_month_limit", "name"}))
+	mock.ExpectQuery("INSERT INTO expences").WithArgs(123, 1, today, 10000, 123, "new year dinner", "{}", 1, 10000).WillReturnRows(mock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+	mock.ExpectExec("INSERT INTO operations").WithArgs(123, 123, "add_expence", 1, 0, 0).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT COALESCE\\(default_month_limit, 0\\), COALESCE\\(current_month_limit, 0\\), limit_alerts, limit_alert_fired FROM users").WithArgs(123).WillReturnRows(
+		mock.NewRows([]string{"default_month_limit", "current_month_limit", "limit_alerts", "limit_alert_fired"}).AddRow(1000000, 990000, "{50,80,100}", 0))
+
+	sender.EXPECT().SendMessage("Expence added", int64(123))
+
+	err = model.IncomingCommandMessage(context.Background(), CommandMessage{
+		Message: Message{
+			UserID: 123,
+		},
+		CommandName:      "add_expence",
+		CommandArguments: "food 100 new year dinner",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.NoError(t, mocksRedis.ExpectationsWereMet())
+}
+
+func Test_OnAddExpenceWithWrongDate_ShouldAnswerWithError(t *testing.T) {
+	tests := []struct {
+		name string
+		args string
+		want string
+	}{
+		{"no such day", "food 100 31/02/2012", errDateWrongFormat.Error()},
+		{"no such day after currency", "food 100 EUR 2012-02-30", errDateWrongFormat.Error()},
+		{"far in the future", "food 100 01.01.2099", errDateTooFar.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+
+			_ = logger.InitLogger("data/zap_config.json")
+
+			usersDB := database.NewUsersDB(db)
+			categoriesDB := database.NewCategoriesDB(db)
+			currenciesDB := database.NewCurrenciesDB(db)
+			expencesDB := database.NewExpencesDB(db)
+			recurringDB := database.NewRecurringExpencesDB(db)
+			incomesDB := database.NewIncomesDB(db)
+			operationsDB := database.NewOperationsDB(db)
+
+			rdb, _ := redismock.NewClientMock()
+			reportDB := database.NewReportCacheDb(rdb)
+
+			ctrl := gomock.NewController(t)
+			sender := mocks.NewMockMessageSender(ctrl)
+			r := &ReportRequestProducer{}
+			e := &ExpencesGetter{}
+
+			storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+			model := New(sender, storageModel)
+
+			columns := []string{"id"}
+			mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(mock.NewRows([]string{"id", "timezone", "language"}).AddRow(123, "", ""))
+			mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("food", 123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+
+			sender.EXPECT().SendMessage(tt.want, int64(123))
+
+			err = model.IncomingCommandMessage(context.Background(), CommandMessage{
+				Message: Message{
+					UserID: 123,
+				},
+				CommandName:      "add_expence",
+				CommandArguments: tt.args,
+			})
+			assert.NoError(t, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
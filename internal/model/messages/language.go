@@ -0,0 +1,184 @@
+package messages
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/common"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/helpers"
+)
+
+// language - язык ответов бота
+type language string
+
+const (
+	langEnglish language = "en"
+	langRussian language = "ru"
+)
+
+// язык, если пользователь не выбрал свой и Telegram его не передал
+const defaultLanguage = langEnglish
+
+// languageNames - поддерживаемые языки в порядке вывода в подсказках
+var languageNames = []struct {
+	Lang language
+	Name string
+}{
+	{langEnglish, "English"},
+	{langRussian, "Русский"},
+}
+
+var errLanguageNotFound = fmt.Errorf("language was not found - use en/ru")
+
+// catalog - переводы ответов бота; ключ - английский текст или формат fmt.Sprintf,
+// английский текст и есть ответ на английском
+var catalog = map[language]map[string]string{
+	langRussian: catalogRussian,
+}
+
+// languageCtxKey - ключ языка ответов в контексте обработки сообщения
+type languageCtxKey struct{}
+
+func withLanguage(ctx context.Context, lang language) context.Context {
+	return context.WithValue(ctx, languageCtxKey{}, lang)
+}
+
+// languageFrom - язык ответов для обрабатываемого сообщения; вне обработки сообщения - язык по умолчанию
+func languageFrom(ctx context.Context) language {
+	if lang, ok := ctx.Value(languageCtxKey{}).(language); ok {
+		return lang
+	}
+	return defaultLanguage
+}
+
+// parseLanguage - язык по коду: en, ru, а также коды Telegram вида ru-RU
+func parseLanguage(code string) (language, bool) {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if i := strings.IndexAny(code, "-_"); i >= 0 {
+		code = code[:i]
+	}
+	for _, name := range languageNames {
+		if name.Lang == language(code) {
+			return name.Lang, true
+		}
+	}
+	return "", false
+}
+
+// userLanguage - язык, выбранный через /language, иначе язык из настроек Telegram, иначе язык по умолчанию
+func userLanguage(chosen string, telegramCode string) language {
+	if lang, found := parseLanguage(chosen); found {
+		return lang
+	}
+	if lang, found := parseLanguage(telegramCode); found {
+		return lang
+	}
+	return defaultLanguage
+}
+
+func (l language) name() string {
+	for _, name := range languageNames {
+		if name.Lang == l {
+			return name.Name
+		}
+	}
+	return string(l)
+}
+
+// tr - перевод текста, с аргументами - перевод формата
+func (l language) tr(format string, args ...interface{}) string {
+	if translated, found := catalog[l][format]; found {
+		format = translated
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// errorText - текст ошибки для ответа пользователю
+func (l language) errorText(err error) string {
+	limitExceededError := &common.LimitExceededError{}
+	if errors.As(err, &limitExceededError) {
+		if limitExceededError.Category != "" {
+			return l.tr("Month limit for category %s exceeded", limitExceededError.Category)
+		}
+		return l.tr("Month limit exceeded")
+	}
+	return l.tr(err.Error())
+}
+
+// amount - сумма из копеек: 1234.56, по-русски 1 234,56 с неразрывными пробелами между разрядами
+func (l language) amount(sub int64) string {
+	if sub < 0 {
+		return "-" + l.amount(-sub)
+	}
+	amount := helpers.ConvertSubToAmount(sub)
+	if l != langRussian {
+		return amount
+	}
+
+	point := strings.Index(amount, ".")
+	integer, fraction := amount[:point], amount[point+1:]
+
+	var rvSb strings.Builder
+	for i, digit := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			rvSb.WriteString(" ")
+		}
+		rvSb.WriteRune(digit)
+	}
+	return rvSb.String() + "," + fraction
+}
+
+// percent - доля в процентах с одним знаком после запятой, шириной 5 символов
+func (l language) percent(value float64) string {
+	percent := fmt.Sprintf("%5.1f", value)
+	if l == langRussian {
+		percent = strings.Replace(percent, ".", ",", 1)
+	}
+	return percent
+}
+
+// date - дата: 12/10/2026, по-русски 12.10.2026
+func (l language) date(t time.Time) string {
+	if l == langRussian {
+		return t.Format("02.01.2006")
+	}
+	return t.Format("02/01/2006")
+}
+
+// dateTime - дата и время: 12/10/2026 15:04, по-русски 12.10.2026 15:04
+func (l language) dateTime(t time.Time) string {
+	return l.date(t) + t.Format(" 15:04")
+}
+
+// SetLanguage - язык ответов бота; без аргументов - текущий язык
+func (s *Model) SetLanguage(ctx context.Context, userID int64, text string) (string, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "set_language_command")
+	defer span.Finish()
+
+	current := languageFrom(ctx)
+
+	code := strings.TrimSpace(text)
+	if code == "" {
+		return current.tr("Language: %s\nTo change send '/%s %s'",
+			current.name(), CommandNameMap[LanguageCmd].Command, CommandNameMap[LanguageCmd].Format), nil
+	}
+
+	lang, found := parseLanguage(code)
+	if !found || strings.Contains(code, " ") {
+		return "", errLanguageNotFound
+	}
+
+	if err := s.storage.SetUserLanguage(ctx, userID, string(lang)); err != nil {
+		return "", errServer
+	}
+
+	// ответ уже на новом языке
+	return lang.tr("Language set to %s", lang.name()), nil
+}
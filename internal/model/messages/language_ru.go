@@ -0,0 +1,199 @@
+package messages
+
+import (
+	"fmt"
+
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/helpers"
+)
+
+// catalogRussian - ответы бота на русском; числа в ответах вынесены за слово ("Импортировано трат: 5"),
+// чтобы не склонять слова по числу
+var catalogRussian = map[string]string{
+	// ошибки
+	errWrongCommandFormat.Error(): fmt.Sprintf("неверный формат команды - см. '/%s'", CommandNameMap[GetHelpCmd].Command),
+	errCategoryNotFound.Error(): fmt.Sprintf("категория не найдена - добавьте её: '/%s %s'",
+		CommandNameMap[AddCategoryCmd].Command, CommandNameMap[AddCategoryCmd].Format),
+	errDateWrongFormat.Error():  "неверный формат даты - используйте дд/мм/гггг, дд.мм.гггг, дд/мм, гггг-мм-дд, сегодня, вчера или день недели",
+	errDateTooFar.Error():       "дата слишком далеко в будущем",
+	errCurrencyNotFound.Error(): "валюта не найдена - используйте USD/CNY/EUR/RUB",
+	errLimitIsTooSmall.Error():  "слишком маленький лимит",
+	errResetLimit.Error():       "не удалось сбросить лимит",
+	errServer.Error():           "ошибка сервера",
+	errExpenceNotFound.Error():  fmt.Sprintf("трата не найдена - см. '/%s'", CommandNameMap[ListExpencesCmd].Command),
+	errCategoryExists.Error():   "категория с таким названием уже есть",
+	errCategoryNotEmpty.Error(): fmt.Sprintf("в категории есть траты - перенесите их: '/%s %s' или удалите: '/%s %s'",
+		CommandNameMap[MergeCategoryCmd].Command, CommandNameMap[MergeCategoryCmd].Format,
+		CommandNameMap[DeleteExpenceCmd].Command, CommandNameMap[DeleteExpenceCmd].Format),
+	errCategoryHasRecurring.Error(): fmt.Sprintf("у категории есть регулярные траты - перенесите их: '/%s %s' или отмените: '/%s %s'",
+		CommandNameMap[MergeCategoryCmd].Command, CommandNameMap[MergeCategoryCmd].Format,
+		CommandNameMap[CancelRecurringCmd].Command, CommandNameMap[CancelRecurringCmd].Format),
+	errNoPendingExpence.Error(): "нет траты, ожидающей категорию - сначала отправьте сумму",
+	errBudgetNotFound.Error():   "бюджет с таким кодом приглашения не найден",
+	errBudgetHasMembers.Error(): "к вашему бюджету присоединились другие пользователи - вступить в другой бюджет нельзя",
+	errLeaveOwnBudget.Error():   "это ваш собственный бюджет - выйти из него могут участники, но не владелец",
+	errEmptySearch.Error(): fmt.Sprintf("нечего искать - используйте '/%s %s'",
+		CommandNameMap[SearchExpencesCmd].Command, CommandNameMap[SearchExpencesCmd].Format),
+	errImportNotCSV.Error():                "импортировать можно только банковские выписки в CSV",
+	errImportTooLarge.Error():              fmt.Sprintf("файл слишком большой - максимальный размер %d МБ", MaxDocumentSize>>20),
+	errImportUnknownFormat.Error():         "неизвестный формат выписки - не найдены столбцы даты и суммы",
+	errNoPendingImport.Error():             "нет выписки, ожидающей импорта - сначала загрузите CSV-файл",
+	errReportPeriodWrongFormat.Error():     "неверный период отчёта - используйте day/week/month/lastmonth/year, <N>d или <дд/мм/гггг> <дд/мм/гггг>",
+	errScheduleWrongFormat.Error():         "неверное расписание - используйте формат cron ('0 9 1 * *') или @daily/@weekly/@monthly",
+	errRecurringNotFound.Error():           fmt.Sprintf("регулярная трата не найдена - см. '/%s'", CommandNameMap[ListRecurringCmd].Command),
+	errTimezoneNotFound.Error():            "часовой пояс не найден - используйте название вида Europe/Moscow или отправьте геопозицию",
+	errNothingToUndo.Error():               "нечего отменять",
+	errLimitModeWrongFormat.Error():        "неверный режим лимита - используйте hard или soft",
+	errLimitAlertsWrongFormat.Error():      fmt.Sprintf("неверные пороги уведомлений - используйте проценты месячного лимита от 1 до %d, например '50 80 100', или off", maxLimitAlert),
+	errLanguageNotFound.Error():            "язык не найден - используйте en/ru",
+	helpers.ErrInvalidAmount.Error():       "неверная сумма",
+	errAmountNotPositive.Error():           "сумма должна быть больше нуля",
+	"Month limit exceeded":                 "Месячный лимит превышен",
+	"Month limit for category %s exceeded": "Месячный лимит категории %s превышен",
+
+	// описания команд для /help
+	"Start bot":              "Запустить бота",
+	"Reset all expence data": "Удалить все данные о тратах",
+	"Add new category":       "Добавить категорию",
+	"Add new expence with optional currency, date (today by default), tags and note": "Добавить трату; валюта, дата (по умолчанию сегодня), метки и комментарий необязательны",
+	"Add new income": "Добавить доход",
+	"Get income, expence and balance report for period, or expences with tag":                 "Отчёт о доходах, тратах и балансе за период или о тратах с меткой",
+	"Get chart of expences by category and by day for period":                                 "График трат по категориям и по дням за период",
+	"Export expences for period as CSV file":                                                  "Выгрузить траты за период в CSV-файл",
+	"Put imported statement rows with keyword into category (upload CSV statement to import)": "Относить строки выписки с ключевым словом к категории (для импорта загрузите выписку в CSV)",
+	"Show budget members and invite code to share the budget":                                 "Участники бюджета и код приглашения для совместного бюджета",
+	"Join shared budget by invite code":                                                       "Вступить в общий бюджет по коду приглашения",
+	"Leave shared budget and return to your own":                                              "Выйти из общего бюджета и вернуться к своему",
+	"Change currency": "Сменить валюту",
+	"Set timezone for dates, reports and month limit reset, or share location": "Часовой пояс для дат, отчётов и сброса месячного лимита; можно отправить геопозицию",
+	"Set language of replies":                "Язык ответов бота",
+	"Set month limit":                        "Установить месячный лимит",
+	"Reset month limit":                      "Сбросить месячный лимит",
+	"Set hard or soft month limit mode":      "Жёсткий или мягкий режим месячного лимита",
+	"Set percents of month limit to warn at": "Пороги уведомлений в процентах месячного лимита",
+	"Set month limit for category":           "Установить месячный лимит категории",
+	"List expences with their ids":           "Список трат с их номерами",
+	"Find expences by note":                  "Найти траты по комментарию",
+	"Edit expence":                           "Изменить трату",
+	"Delete expence":                         "Удалить трату",
+	"List categories":                        "Список категорий",
+	"Rename category":                        "Переименовать категорию",
+	"Move expences to another category and delete the first one":    "Перенести траты в другую категорию и удалить первую",
+	"Delete category without expences":                              "Удалить категорию без трат",
+	"Add expence charged by schedule":                               "Добавить трату, списываемую по расписанию",
+	"List recurring expences":                                       "Список регулярных трат",
+	"Pause recurring expence":                                       "Приостановить регулярную трату",
+	"Resume recurring expence":                                      "Возобновить регулярную трату",
+	"Cancel recurring expence":                                      "Отменить регулярную трату",
+	"Undo last added expence or category, limit or currency change": "Отменить последнее добавление траты или категории, изменение лимита или валюты",
+	"Cancel current question of the bot":                            "Отменить текущий вопрос бота",
+	"Get help":                                                      "Справка",
+	"Available commands:":                                           "Доступные команды:",
+
+	// ответы
+	"Welcomen!":                                 "Добро пожаловать!",
+	"Data erased!":                              "Данные удалены!",
+	"Category %s is added":                      "Категория %s добавлена",
+	"Category %s is already added":              "Категория %s уже добавлена",
+	"No categories! Use '/%s %s'":               "Категорий нет! Добавьте: '/%s %s'",
+	"Categories:":                               "Категории:",
+	"Category %s is renamed to %s":              "Категория %s переименована в %s",
+	"Category %s is merged into %s":             "Категория %s объединена с %s",
+	"Category %s is deleted":                    "Категория %s удалена",
+	"Month limit for category %s is set":        "Месячный лимит категории %s установлен",
+	"Expence added":                             "Трата добавлена",
+	"Expence changed":                           "Трата изменена",
+	"Expence deleted":                           "Трата удалена",
+	"No expences!":                              "Трат нет!",
+	"Expences, page %d":                         "Траты, страница %d",
+	"Next page: /%s %d":                         "Следующая страница: /%s %d",
+	"Income added":                              "Доход добавлен",
+	"Currency not found":                        "Валюта не найдена",
+	"Currency successfully changed":             "Валюта изменена",
+	"Month limit updated!":                      "Месячный лимит обновлён!",
+	"Limit alerts: %s\nTo change send '/%s %s'": "Уведомления о лимите: %s\nЧтобы изменить, отправьте '/%s %s'",
+	"Limit alerts set: %s":                      "Уведомления о лимите: %s",
+	"off":                                       "выключены",
+	"Limit mode: %s\nTo change send '/%s %s'":   "Режим лимита: %s\nЧтобы изменить, отправьте '/%s %s'",
+	"Limit mode set: %s":                        "Режим лимита: %s",
+	"Month limit exceeded by %s":                "Месячный лимит превышен на %s",
+	"Warning: %d%% of the month limit is spent": "Внимание: потрачено %d%% месячного лимита",
+	"Month limit reseted":                       "Месячный лимит сброшен",
+	"Which category?":                           "Какая категория?",
+	"Which category: %s?":                       "Какая категория: %s?",
+	"How much was spent on %s?":                 "Сколько потрачено на %s?",
+	"Which amount: %s?":                         "Какая сумма: %s?",
+	"Which source of income?":                   "Какой источник дохода?",
+	"How much?":                                 "Сколько?",
+	"Which date?":                               "Какая дата?",
+	"Which date? Send - for today":              "Какая дата? Отправьте - для сегодняшней",
+	"Cancelled":                                 "Отменено",
+	"Last added expence removed":                "Последняя добавленная трата удалена",
+	"Last added category removed":               "Последняя добавленная категория удалена",
+	"Month limit restored":                      "Прежний месячный лимит восстановлен",
+	"Currency restored":                         "Прежняя валюта восстановлена",
+	"Nothing to cancel":                         "Нечего отменять",
+
+	// отчёты
+	"All time expences":       "Траты за всё время",
+	"Expences from %s to %s":  "Траты с %s по %s",
+	"Last day expences":       "Траты за день",
+	"Last week expences":      "Траты за неделю",
+	"Last month expences":     "Траты за месяц",
+	"Previous month expences": "Траты за прошлый месяц",
+	"Last year expences":      "Траты за год",
+	"Last %d days expences":   "Траты за последние дни: %d",
+	"Total":                   "Итого",
+	"Income":                  "Доход",
+	"Over budget":             "Сверх лимита",
+	"Balance":                 "Баланс",
+	"other":                   "другое",
+	"%s tagged #%s":           "%s с меткой #%s",
+	"No expences tagged #%s!": "Трат с меткой #%s нет!",
+	"No expences found!":      "Трат не найдено!",
+	"Expences with '%s'":      "Траты с '%s'",
+
+	// общий бюджет
+	"Budget members:":                        "Участники бюджета:",
+	"(owner)":                                "(владелец)",
+	"Invite code: %s\nTo join send '/%s %s'": "Код приглашения: %s\nЧтобы вступить, отправьте '/%s %s'",
+	"You are back in your own budget":        "Вы вернулись в свой бюджет",
+	"You have joined the budget":             "Вы вступили в бюджет",
+	"You have left the budget":               "Вы вышли из бюджета",
+	"unknown":                                "неизвестно",
+
+	// регулярные траты
+	"Recurring expence #%d added, next charge: %s":   "Регулярная трата #%d добавлена, следующее списание: %s",
+	"No recurring expences!":                         "Регулярных трат нет!",
+	"Recurring expences":                             "Регулярные траты",
+	"next: %s":                                       "следующее: %s",
+	"paused":                                         "приостановлена",
+	"Recurring expence #%d paused":                   "Регулярная трата #%d приостановлена",
+	"Recurring expence #%d resumed, next charge: %s": "Регулярная трата #%d возобновлена, следующее списание: %s",
+	"Recurring expence #%d cancelled":                "Регулярная трата #%d отменена",
+	"Recurring expence #%d posted: %s %s on %s":      "Регулярная трата #%d списана: %s %s за %s",
+	"Recurring expence #%d was not posted: %s":       "Регулярная трата #%d не списана: %s",
+
+	// импорт выписок
+	"Import":                        "Импортировать",
+	"Cancel":                        "Отмена",
+	"Imported %d expences":          "Импортировано трат: %d",
+	", %d already imported skipped": ", пропущено уже импортированных: %d",
+	"Import cancelled":              "Импорт отменён",
+	"Imported rows with '%s' will be added to %s": "Строки выписки с '%s' будут добавлены в %s",
+	"Statement %s: %d expences, total %s":         "Выписка %s: трат - %d, всего %s",
+	"... and %d more":                             "... и ещё %d",
+	"Rows without category will not be imported - add categories or '/%s %s' and upload the file again:": "Строки без категории не будут импортированы - добавьте категории или '/%s %s' и загрузите файл снова:",
+	"Skipped rows without date or expence amount: %d":                                                    "Пропущено строк без даты или суммы траты: %d",
+
+	// часовой пояс и язык
+	"server time": "время сервера",
+	"Timezone: %s, local time %s\nTo change send '/%s %s' or share your location": "Часовой пояс: %s, местное время %s\nЧтобы изменить, отправьте '/%s %s' или геопозицию",
+	"Timezone set to %s, local time %s":                                           "Часовой пояс %s установлен, местное время %s",
+	"Daylight saving time is not taken into account - use '/%s %s' for it":        "Летнее время не учитывается - для него используйте '/%s %s'",
+	"Language: %s\nTo change send '/%s %s'":                                       "Язык: %s\nЧтобы изменить, отправьте '/%s %s'",
+	"Language set to %s":                                                          "Язык ответов: %s",
+
+	// режим лимита
+	"soft, expences over the limit are saved with a warning": "мягкий, траты сверх лимита сохраняются с предупреждением",
+	"hard, expences over the limit are rejected":             "жёсткий, траты сверх лимита отклоняются",
+}
@@ -0,0 +1,96 @@
+package messages
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/opentracing/opentracing-go"
+)
+
+// наибольший порог уведомления; больше 100% траты набираются только в мягком режиме лимита
+const maxLimitAlert = 999
+
+var errLimitAlertsWrongFormat = fmt.Errorf("wrong limit alerts - use percents of month limit from 1 to %d like '50 80 100', or off", maxLimitAlert)
+
+// SetLimitAlerts - пороги уведомлений о тратах в процентах лимита месяца; без аргументов - текущие пороги
+func (s *Model) SetLimitAlerts(ctx context.Context, userID int64, text string) (string, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "set_limit_alerts_command")
+	defer span.Finish()
+
+	lang := languageFrom(ctx)
+
+	if strings.TrimSpace(text) == "" {
+		alerts, err := s.storage.GetLimitAlerts(ctx, userID)
+		if err != nil {
+			return "", errServer
+		}
+		return lang.tr("Limit alerts: %s\nTo change send '/%s %s'",
+			formatLimitAlerts(lang, alerts), CommandNameMap[LimitAlertsCmd].Command, CommandNameMap[LimitAlertsCmd].Format), nil
+	}
+
+	alerts, err := parseLimitAlerts(text)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.storage.SetLimitAlerts(ctx, userID, alerts); err != nil {
+		return "", errServer
+	}
+
+	return lang.tr("Limit alerts set: %s", formatLimitAlerts(lang, alerts)), nil
+}
+
+// parseLimitAlerts - пороги по возрастанию без повторов; off - уведомления выключены
+func parseLimitAlerts(text string) ([]int64, error) {
+	args := strings.Fields(text)
+	if len(args) == 1 && strings.EqualFold(args[0], "off") {
+		return []int64{}, nil
+	}
+
+	seen := make(map[int64]bool, len(args))
+	alerts := make([]int64, 0, len(args))
+	for _, arg := range args {
+		percent, err := strconv.ParseInt(strings.TrimSuffix(arg, "%"), 10, 64)
+		if err != nil || percent < 1 || percent > maxLimitAlert {
+			return nil, errLimitAlertsWrongFormat
+		}
+		if !seen[percent] {
+			seen[percent] = true
+			alerts = append(alerts, percent)
+		}
+	}
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i] < alerts[j] })
+	return alerts, nil
+}
+
+func formatLimitAlerts(lang language, alerts []int64) string {
+	if len(alerts) == 0 {
+		return lang.tr("off")
+	}
+	percents := make([]string, 0, len(alerts))
+	for _, alert := range alerts {
+		percents = append(percents, strconv.FormatInt(alert, 10)+"%")
+	}
+	return strings.Join(percents, ", ")
+}
+
+// withLimitAlert - ответ о добавленных тратах с уведомлением о достигнутом пороге лимита месяца
+// и о превышении лимита, если в мягком режиме траты сохранены сверх него
+func (s *Model) withLimitAlert(ctx context.Context, userID int64, answer string) string {
+	alert, err := s.storage.TakeLimitAlert(ctx, userID)
+	if err != nil {
+		return answer
+	}
+
+	lang := languageFrom(ctx)
+	if alert.Threshold != 0 {
+		answer += "\n" + lang.tr("Warning: %d%% of the month limit is spent", alert.Threshold)
+	}
+	if alert.Overspend != 0 {
+		answer += "\n" + lang.tr("Month limit exceeded by %s", lang.amount(alert.Overspend))
+	}
+	return answer
+}
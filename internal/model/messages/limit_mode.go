@@ -0,0 +1,53 @@
+package messages
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/opentracing/opentracing-go"
+)
+
+const (
+	limitModeHard = "hard"
+	limitModeSoft = "soft"
+)
+
+var errLimitModeWrongFormat = fmt.Errorf("wrong limit mode - use hard or soft")
+
+// SetLimitMode - режим лимита: в жёстком траты сверх лимита отклоняются, в мягком сохраняются с предупреждением;
+// без аргументов - текущий режим
+func (s *Model) SetLimitMode(ctx context.Context, userID int64, text string) (string, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "set_limit_mode_command")
+	defer span.Finish()
+
+	lang := languageFrom(ctx)
+	mode := strings.ToLower(strings.TrimSpace(text))
+
+	if mode == "" {
+		soft, err := s.storage.GetLimitMode(ctx, userID)
+		if err != nil {
+			return "", errServer
+		}
+		return lang.tr("Limit mode: %s\nTo change send '/%s %s'",
+			formatLimitMode(lang, soft), CommandNameMap[LimitModeCmd].Command, CommandNameMap[LimitModeCmd].Format), nil
+	}
+
+	if mode != limitModeHard && mode != limitModeSoft {
+		return "", errLimitModeWrongFormat
+	}
+
+	soft := mode == limitModeSoft
+	if err := s.storage.SetLimitMode(ctx, userID, soft); err != nil {
+		return "", errServer
+	}
+
+	return lang.tr("Limit mode set: %s", formatLimitMode(lang, soft)), nil
+}
+
+func formatLimitMode(lang language, soft bool) string {
+	if soft {
+		return lang.tr("soft, expences over the limit are saved with a warning")
+	}
+	return lang.tr("hard, expences over the limit are rejected")
+}
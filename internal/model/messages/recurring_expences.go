@@ -0,0 +1,273 @@
+package messages
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/robfig/cron/v3"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/common"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/domain"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/helpers"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/logger"
+	"go.uber.org/zap"
+)
+
+// сколько пропущенных списаний одного расписания проводится за один проход,
+// остальные будут проведены на следующих проходах
+const maxRecurringCatchUp = 31
+
+var errScheduleWrongFormat = fmt.Errorf("wrong schedule format - use cron format ('0 9 1 * *') or @daily/@weekly/@monthly")
+var errRecurringNotFound = fmt.Errorf(fmt.Sprintf("recurring expence was not found - use '/%s'", CommandNameMap[ListRecurringCmd].Command))
+
+// добавление регулярной траты: /add_recurring rent 30000 @monthly
+func (s *Model) AddRecurringExpence(ctx context.Context, userID int64, now time.Time, text string) (string, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "add_recurring_expence_command")
+	defer span.Finish()
+
+	// расписание может содержать пробелы, поэтому забирает всё после суммы
+	commandArgs := strings.SplitN(text, " ", 3)
+
+	// проверка, что 3 аргумента
+	if len(commandArgs) != 3 {
+		return "", errWrongCommandFormat
+	}
+
+	if !s.storage.IsCategoryExists(ctx, userID, commandArgs[0]) {
+		return "", errCategoryNotFound
+	}
+
+	total, err := helpers.ConvertStringAmountToSub(commandArgs[1])
+	if err != nil {
+		return "", err
+	}
+
+	scheduleSpec := strings.TrimSpace(commandArgs[2])
+	schedule, err := cron.ParseStandard(scheduleSpec)
+	if err != nil {
+		return "", errScheduleWrongFormat
+	}
+
+	nextTs := nextRecurringTs(schedule, now, now.Location())
+	id, err := s.storage.AddRecurringExpence(ctx, userID, commandArgs[0], total, scheduleSpec, nextTs)
+	if err != nil {
+		return "", errServer
+	}
+
+	return languageFrom(ctx).tr("Recurring expence #%d added, next charge: %s", id, languageFrom(ctx).dateTime(nextTs.In(now.Location()))), nil
+}
+
+// вывод регулярных трат
+func (s *Model) ListRecurringExpences(ctx context.Context, userID int64, now time.Time) (string, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "list_recurring_expences_command")
+	defer span.Finish()
+
+	expences, err := s.storage.GetRecurringExpences(ctx, userID)
+	if err != nil {
+		return "", errServer
+	}
+
+	lang := languageFrom(ctx)
+	if len(expences) == 0 {
+		return lang.tr("No recurring expences!"), nil
+	}
+
+	var rvSb strings.Builder
+	rvSb.WriteString(lang.tr("Recurring expences") + "\n")
+	for _, expence := range expences {
+		status := lang.tr("next: %s", lang.dateTime(expence.NextTimestamp.In(now.Location())))
+		if expence.Paused {
+			status = lang.tr("paused")
+		}
+		rvSb.WriteString(fmt.Sprintf("#%d %s: %s (%s), %s\n",
+			expence.ID,
+			expence.CategoryName,
+			lang.amount(expence.Total),
+			expence.Schedule,
+			status))
+	}
+
+	return rvSb.String(), nil
+}
+
+// приостановка регулярной траты
+func (s *Model) PauseRecurringExpence(ctx context.Context, userID int64, text string) (string, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "pause_recurring_expence_command")
+	defer span.Finish()
+
+	expence, err := s.findRecurringExpence(ctx, userID, text)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.storage.SetRecurringExpenceState(ctx, userID, expence.ID, true, expence.NextTimestamp); err != nil {
+		return "", recurringChangeError(err)
+	}
+
+	return languageFrom(ctx).tr("Recurring expence #%d paused", expence.ID), nil
+}
+
+// возобновление регулярной траты; списания за время паузы не проводятся
+func (s *Model) ResumeRecurringExpence(ctx context.Context, userID int64, now time.Time, text string) (string, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "resume_recurring_expence_command")
+	defer span.Finish()
+
+	expence, err := s.findRecurringExpence(ctx, userID, text)
+	if err != nil {
+		return "", err
+	}
+
+	schedule, err := cron.ParseStandard(expence.Schedule)
+	if err != nil {
+		return "", errScheduleWrongFormat
+	}
+
+	nextTs := nextRecurringTs(schedule, now, now.Location())
+	if err := s.storage.SetRecurringExpenceState(ctx, userID, expence.ID, false, nextTs); err != nil {
+		return "", recurringChangeError(err)
+	}
+
+	return languageFrom(ctx).tr("Recurring expence #%d resumed, next charge: %s", expence.ID, languageFrom(ctx).dateTime(nextTs.In(now.Location()))), nil
+}
+
+// отмена регулярной траты
+func (s *Model) CancelRecurringExpence(ctx context.Context, userID int64, text string) (string, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "cancel_recurring_expence_command")
+	defer span.Finish()
+
+	id, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		return "", errWrongCommandFormat
+	}
+
+	if err := s.storage.DeleteRecurringExpence(ctx, userID, id); err != nil {
+		return "", recurringChangeError(err)
+	}
+
+	return languageFrom(ctx).tr("Recurring expence #%d cancelled", id), nil
+}
+
+func (s *Model) findRecurringExpence(ctx context.Context, userID int64, text string) (domain.RecurringExpence, error) {
+	id, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		return domain.RecurringExpence{}, errWrongCommandFormat
+	}
+
+	expences, err := s.storage.GetRecurringExpences(ctx, userID)
+	if err != nil {
+		return domain.RecurringExpence{}, errServer
+	}
+
+	for _, expence := range expences {
+		if expence.ID == id {
+			return expence, nil
+		}
+	}
+	return domain.RecurringExpence{}, errRecurringNotFound
+}
+
+// nextRecurringTs - следующее списание после after по расписанию в часовом поясе пользователя;
+// next_ts хранится по часам сервера, как и время, с которым его сравнивают при проведении
+func nextRecurringTs(schedule cron.Schedule, after time.Time, loc *time.Location) time.Time {
+	return schedule.Next(after.In(loc)).In(time.Local)
+}
+
+func recurringChangeError(err error) error {
+	if errors.Is(err, common.ErrRecurringExpenceNotFound) {
+		return errRecurringNotFound
+	}
+	return errServer
+}
+
+func (s *Model) WaitRecurringExpences(ctx context.Context, wg *sync.WaitGroup, ch <-chan struct{}) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		for {
+			select {
+			case <-ch:
+				func() {
+					postCtx, cancel := context.WithCancel(ctx)
+					defer cancel()
+
+					s.PostRecurringExpences(postCtx, time.Now())
+				}()
+			case <-ctx.Done():
+				logger.Info("Stopping listening to recurring expence service...")
+				return
+			}
+		}
+	}()
+}
+
+// PostRecurringExpences - проведение всех списаний, срок которых наступил к now, включая пропущенные
+func (s *Model) PostRecurringExpences(ctx context.Context, now time.Time) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "post_recurring_expences")
+	defer span.Finish()
+
+	expences, err := s.storage.GetDueRecurringExpences(ctx, now)
+	if err != nil {
+		return
+	}
+
+	for _, expence := range expences {
+		schedule, err := cron.ParseStandard(expence.Schedule)
+		if err != nil {
+			logger.Error("recurring expence schedule error:", zap.Int64("id", expence.ID), zap.Error(err))
+			continue
+		}
+
+		// уведомление приходит без сообщения пользователя, поэтому часовой пояс и язык берутся из настроек
+		user, _ := s.storage.GetUserBudget(ctx, expence.UserID)
+		loc := helpers.LoadLocation(user.Timezone)
+
+		var due []time.Time
+		nextTs := expence.NextTimestamp
+		for len(due) < maxRecurringCatchUp && !nextTs.After(now) {
+			due = append(due, nextTs)
+			nextTs = nextRecurringTs(schedule, nextTs, loc)
+		}
+
+		// следующее списание переносится до проведения трат: если перенести не удалось,
+		// траты не проводятся, иначе следующий проход провёл бы их повторно
+		if err := s.storage.SetRecurringExpenceState(ctx, expence.UserID, expence.ID, false, nextTs); err != nil {
+			logger.Error("recurring expence update error:", zap.Int64("id", expence.ID), zap.Error(err))
+			continue
+		}
+
+		for _, date := range due {
+			s.postRecurringExpence(ctx, user, expence, date)
+		}
+	}
+}
+
+func (s *Model) postRecurringExpence(ctx context.Context, user domain.User, expence domain.RecurringExpence, date time.Time) {
+	// язык только выбранный через /language
+	ctx = withLanguage(ctx, userLanguage(user.Language, ""))
+	lang := languageFrom(ctx)
+
+	// трата записывается датой списания в часовом поясе пользователя, как и введённые вручную
+	date = helpers.DateOf(date.In(helpers.LoadLocation(user.Timezone)))
+
+	answer := lang.tr("Recurring expence #%d posted: %s %s on %s",
+		expence.ID,
+		expence.CategoryName,
+		lang.amount(expence.Total),
+		lang.date(date))
+
+	if err := storeExpenceError(s.storage.PostRecurringExpence(ctx, expence, date)); err != nil {
+		answer = lang.tr("Recurring expence #%d was not posted: %s", expence.ID, lang.errorText(err))
+	} else {
+		answer = s.withLimitAlert(ctx, expence.UserID, answer)
+	}
+
+	if err := s.tgClient.SendMessage(answer, expence.UserID); err != nil {
+		logger.Error("recurring expence notification error:", zap.Error(err))
+	}
+}
@@ -0,0 +1,223 @@
+package messages
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-redis/redismock/v8"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/database"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/logger"
+	mocks "gitlab.ozon.dev/akosykh114/telegram-bot/internal/mocks/messages"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/storage"
+)
+
+func Test_OnAddRecurringCommand_WithWrongSchedule_ShouldAnswerWithError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, _ := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+
+	columns := []string{"id"}
+	mock.ExpectExec("INSERT INTO users").WithArgs(123).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("rent", 123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+
+	sender.EXPECT().SendMessage(errScheduleWrongFormat.Error(), int64(123))
+
+	err = model.IncomingCommandMessage(context.Background(), CommandMessage{
+		Message: Message{
+			UserID: 123,
+		},
+		CommandName:      "add_recurring",
+		CommandArguments: "rent 30000 every first day",
+	})
+	assert.NoError(t, err)
+}
+
+func Test_PostRecurringExpences_ShouldPostMissedChargesAndNotify(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, mocksRedis := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+
+	// бот не работал 1 и 2 октября - должны быть проведены оба списания
+	due := time.Date(2012, 10, 1, 0, 0, 0, 0, time.UTC)
+	now := time.Date(2012, 10, 2, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery("SELECT recurring_expences.id").WithArgs(false, now).WillReturnRows(
+		mock.NewRows([]string{"id", "user_id", "category_id", "name", "total", "schedule", "paused", "next_ts"}).
+			AddRow(7, 123, 1, "coffee", 25000, "@daily", false, due))
+
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(mock.NewRows([]string{"id", "timezone", "language"}).AddRow(123, "", ""))
+	mock.ExpectExec("UPDATE recurring_expences").WithArgs(false, time.Date(2012, 10, 3, 0, 0, 0, 0, time.UTC).In(time.Local), 7, 123).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	columns := []string{"id"}
+	for day := 1; day <= 2; day++ {
+		mocksRedis.ExpectKeys("123*").SetVal([]string{})
+
+		mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("coffee", 123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+		mock.ExpectQuery("SELECT base_currency_id").WithArgs(123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+		mock.ExpectQuery("SELECT COALESCE\\(\\(SELECT rate FROM currency_rates").WithArgs(1, sqlmock.AnyArg(), 1).WillReturnRows(mock.NewRows(columns).AddRow(1))
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT limit_month").WithArgs(123).WillReturnRows(mock.NewRows([]string{"limit_month"}))
+		mock.ExpectQuery("INSERT INTO expences").WithArgs(123, 1, time.Date(2012, 10, day, 0, 0, 0, 0, time.UTC), 25000, 123, "", "{}", 1, 25000).WillReturnRows(mock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT COALESCE\\(default_month_limit, 0\\), COALESCE\\(current_month_limit, 0\\), limit_alerts, limit_alert_fired FROM users").WithArgs(123).WillReturnRows(
+			mock.NewRows([]string{"default_month_limit", "current_month_limit", "limit_alerts", "limit_alert_fired"}).AddRow(1000000, 990000, "{50,80,100}", 0))
+	}
+
+	gomock.InOrder(
+		sender.EXPECT().SendMessage("Recurring expence #7 posted: coffee 250.00 on 01/10/2012", int64(123)),
+		sender.EXPECT().SendMessage("Recurring expence #7 posted: coffee 250.00 on 02/10/2012", int64(123)),
+	)
+
+	model.PostRecurringExpences(context.Background(), now)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_PostRecurringExpences_ShouldFollowScheduleInUserTimezone(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, mocksRedis := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+
+	// 8 утра по Токио - это 23 часа по UTC предыдущего дня
+	due := time.Date(2012, 9, 30, 23, 0, 0, 0, time.UTC)
+	now := time.Date(2012, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery("SELECT recurring_expences.id").WithArgs(false, now).WillReturnRows(
+		mock.NewRows([]string{"id", "user_id", "category_id", "name", "total", "schedule", "paused", "next_ts"}).
+			AddRow(7, 123, 1, "coffee", 25000, "0 8 * * *", false, due))
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(mock.NewRows([]string{"id", "timezone", "language"}).AddRow(123, "Asia/Tokyo", ""))
+	// следующее списание - снова в 8 утра по Токио, а не по часам сервера
+	mock.ExpectExec("UPDATE recurring_expences").WithArgs(false, time.Date(2012, 10, 1, 23, 0, 0, 0, time.UTC).In(time.Local), 7, 123).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	columns := []string{"id"}
+	mocksRedis.ExpectKeys("123*").SetVal([]string{})
+	mock.ExpectQuery("SELECT id FROM expence_category").WithArgs("coffee", 123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT base_currency_id").WithArgs(123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT COALESCE\\(\\(SELECT rate FROM currency_rates").WithArgs(1, sqlmock.AnyArg(), 1).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT limit_month").WithArgs(123).WillReturnRows(mock.NewRows([]string{"limit_month"}))
+	mock.ExpectQuery("INSERT INTO expences").WithArgs(123, 1, time.Date(2012, 10, 1, 0, 0, 0, 0, time.UTC), 25000, 123, "", "{}", 1, 25000).WillReturnRows(mock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT COALESCE\\(default_month_limit, 0\\), COALESCE\\(current_month_limit, 0\\), limit_alerts, limit_alert_fired FROM users").WithArgs(123).WillReturnRows(
+		mock.NewRows([]string{"default_month_limit", "current_month_limit", "limit_alerts", "limit_alert_fired"}).AddRow(1000000, 990000, "{50,80,100}", 0))
+
+	sender.EXPECT().SendMessage("Recurring expence #7 posted: coffee 250.00 on 01/10/2012", int64(123))
+
+	model.PostRecurringExpences(context.Background(), now)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_PostRecurringExpences_WhenStateUpdateFails_ShouldNotPost(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, _ := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+
+	due := time.Date(2012, 10, 1, 0, 0, 0, 0, time.UTC)
+	now := time.Date(2012, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery("SELECT recurring_expences.id").WithArgs(false, now).WillReturnRows(
+		mock.NewRows([]string{"id", "user_id", "category_id", "name", "total", "schedule", "paused", "next_ts"}).
+			AddRow(7, 123, 1, "coffee", 25000, "@daily", false, due))
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(mock.NewRows([]string{"id", "timezone", "language"}).AddRow(123, "", ""))
+	// дата следующего списания осталась прежней - трата будет проведена на следующем проходе
+	mock.ExpectExec("UPDATE recurring_expences").WithArgs(false, time.Date(2012, 10, 2, 0, 0, 0, 0, time.UTC).In(time.Local), 7, 123).WillReturnError(sql.ErrConnDone)
+
+	model.PostRecurringExpences(context.Background(), now)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,108 @@
+package messages
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/charts"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/domain"
+)
+
+// периоды длиннее года (траты за всё время) рисуются по дням, где есть траты
+const chartMaxDays = 366
+
+const chartOtherCategory = "other"
+
+// ключ дня при сопоставлении сумм из базы с днями периода
+const chartDayFormat = "2006-01-02"
+
+// GetChart - картинка со столбцами по категориям и графиком трат по дням за период,
+// вместе с подписью-легендой
+func (s *Model) GetChart(ctx context.Context, userID int64, now time.Time, text string) ([]byte, string, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_chart_command")
+	defer span.Finish()
+
+	lang := languageFrom(ctx)
+
+	period, err := parseReportPeriod(lang, text, now)
+	if err != nil {
+		return nil, "", err
+	}
+
+	totalMap := s.storage.GetExpencesMap(ctx, userID, period.Start, period.End)
+
+	daily, err := s.storage.GetDailyExpences(ctx, userID, period.Start, period.End)
+	if err != nil {
+		return nil, "", errServer
+	}
+
+	if len(totalMap) == 0 && len(daily) == 0 {
+		return nil, lang.tr("No expences!"), nil
+	}
+
+	rows := chartCategories(lang, totalMap)
+	categoryTotals := make([]int64, 0, len(rows))
+	for _, row := range rows {
+		categoryTotals = append(categoryTotals, row.Total)
+	}
+
+	img := charts.DrawExpences(categoryTotals, chartDailyTotals(daily, period))
+	data, err := charts.EncodePNG(img)
+	if err != nil {
+		return nil, "", errServer
+	}
+
+	return data, formatChartCaption(lang, period.Title, rows), nil
+}
+
+// chartCategories - крупнейшие категории, остальные объединяются в один столбец
+func chartCategories(lang language, totalMap map[string]int64) []reportRow {
+	rows, _ := sortReportRows(totalMap)
+	if len(rows) <= len(charts.Palette) {
+		return rows
+	}
+
+	other := reportRow{Category: lang.tr(chartOtherCategory)}
+	for _, row := range rows[len(charts.Palette)-1:] {
+		other.Total += row.Total
+	}
+	return append(rows[:len(charts.Palette)-1], other)
+}
+
+// chartDailyTotals - суммы по каждому дню периода, дни без трат нулевые
+func chartDailyTotals(daily []domain.Expence, period reportPeriod) []int64 {
+	if len(daily) == 0 {
+		return nil
+	}
+
+	totals := make(map[string]int64, len(daily))
+	for _, expence := range daily {
+		totals[expence.Timestamp.Format(chartDayFormat)] += expence.Total
+	}
+
+	start, end := period.Start, period.End
+	if end.Sub(start) > chartMaxDays*24*time.Hour {
+		first, last := daily[0].Timestamp, daily[len(daily)-1].Timestamp
+		start = time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, start.Location())
+		end = time.Date(last.Year(), last.Month(), last.Day(), 0, 0, 0, 0, start.Location()).AddDate(0, 0, 1)
+	}
+
+	rv := make([]int64, 0)
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		rv = append(rv, totals[day.Format(chartDayFormat)])
+	}
+	return rv
+}
+
+// formatChartCaption - легенда: цвет столбца, категория и сумма
+func formatChartCaption(lang language, title string, rows []reportRow) string {
+	var rvSb strings.Builder
+	rvSb.WriteString(title)
+	for i, row := range rows {
+		rvSb.WriteString(fmt.Sprintf("\n%s %s: %s", charts.PaletteEmoji[i], row.Category, lang.amount(row.Total)))
+	}
+	return rvSb.String()
+}
@@ -0,0 +1,126 @@
+package messages
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-redis/redismock/v8"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/charts"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/database"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/domain"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/helpers"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/logger"
+	mocks "gitlab.ozon.dev/akosykh114/telegram-bot/internal/mocks/messages"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/storage"
+)
+
+func Test_ChartCategories_ShouldGroupSmallestIntoOther(t *testing.T) {
+	totalMap := map[string]int64{}
+	for i := 1; i <= 10; i++ {
+		totalMap[fmt.Sprintf("cat%02d", i)] = int64(i * 100)
+	}
+
+	rows := chartCategories(langEnglish, totalMap)
+
+	assert.Len(t, rows, len(charts.Palette))
+	assert.Equal(t, reportRow{Category: "cat10", Total: 1000}, rows[0])
+	assert.Equal(t, reportRow{Category: "other", Total: 300 + 200 + 100}, rows[len(rows)-1])
+}
+
+func Test_ChartDailyTotals_ShouldFillDaysWithoutExpences(t *testing.T) {
+	start := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	period := reportPeriod{Start: start, End: start.AddDate(0, 0, 5)}
+
+	totals := chartDailyTotals([]domain.Expence{
+		{Timestamp: start.AddDate(0, 0, 1), Total: 100},
+		{Timestamp: start.AddDate(0, 0, 3), Total: 300},
+	}, period)
+
+	assert.Equal(t, []int64{0, 100, 0, 300, 0}, totals)
+}
+
+func Test_ChartDailyTotals_AllTime_ShouldStartFromFirstExpence(t *testing.T) {
+	period, err := parseReportPeriod(langEnglish, "", time.Now())
+	assert.NoError(t, err)
+
+	first := time.Date(2026, 9, 1, 0, 0, 0, 0, period.Start.Location())
+	totals := chartDailyTotals([]domain.Expence{
+		{Timestamp: first, Total: 100},
+		{Timestamp: first.AddDate(0, 0, 2), Total: 300},
+	}, period)
+
+	assert.Equal(t, []int64{100, 0, 300}, totals)
+}
+
+func Test_FormatChartCaption(t *testing.T) {
+	caption := formatChartCaption(langEnglish, "Last week expences", []reportRow{
+		{Category: "food", Total: 100000},
+		{Category: "taxi", Total: 25000},
+	})
+
+	assert.Equal(t, "Last week expences\n🟥 food: 1000.00\n🟧 taxi: 250.00", caption)
+}
+
+func Test_OnChartCommand_ShouldSendPhoto(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, mocksRedis := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+
+	startTs := helpers.StartOfWeek(helpers.DateOf(time.Now()))
+	endTs := startTs.AddDate(0, 0, 7)
+	mocksRedis.ExpectHGetAll(fmt.Sprintf("123%d_%d", startTs.Unix(), endTs.Unix())).SetVal(map[string]string{"food": "10000", "taxi": "2500"})
+
+	columns := []string{"id"}
+	mock.ExpectExec("INSERT INTO users").WithArgs(123).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT base_currency_id").WithArgs(123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT COALESCE\\(\\(SELECT rate FROM currency_rates").WithArgs(1, sqlmock.AnyArg(), 1).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT date_trunc").WithArgs(123, startTs, endTs).WillReturnRows(
+		mock.NewRows([]string{"day", "sum"}).AddRow(startTs, 12500))
+
+	sender.EXPECT().SendPhoto(gomock.Any(), "Last week expences\n🟥 food: 100.00\n🟧 taxi: 25.00", int64(123)).DoAndReturn(
+		func(image []byte, caption string, userID int64) error {
+			img, err := png.Decode(bytes.NewReader(image))
+			assert.NoError(t, err)
+			assert.Equal(t, charts.DrawExpences([]int64{10000, 2500}, []int64{12500, 0, 0, 0, 0, 0, 0}), img)
+			return nil
+		})
+
+	err = model.IncomingCommandMessage(context.Background(), CommandMessage{
+		Message: Message{
+			UserID: 123,
+		},
+		CommandName:      "chart",
+		CommandArguments: "week",
+	})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,151 @@
+package messages
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// ширина самого длинного столбца диаграммы в символах
+const reportBarWidth = 10
+
+// доли символа для столбцов диаграммы, по восьмым
+var reportBarEighths = []string{"", "▏", "▎", "▍", "▌", "▋", "▊", "▉"}
+
+type reportRow struct {
+	Category string
+	Total    int64
+}
+
+// sortReportRows - категории по убыванию суммы, при равенстве по имени, и общая сумма трат
+func sortReportRows(totalMap map[string]int64) ([]reportRow, int64) {
+	rows := make([]reportRow, 0, len(totalMap))
+	var total int64
+	for k, v := range totalMap {
+		rows = append(rows, reportRow{Category: k, Total: v})
+		total += v
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Total != rows[j].Total {
+			return rows[i].Total > rows[j].Total
+		}
+		return rows[i].Category < rows[j].Category
+	})
+	return rows, total
+}
+
+// reportSummaryRow - итоговая строка под таблицей категорий
+type reportSummaryRow struct {
+	Name   string
+	Amount string
+}
+
+// formatReport - отчёт в HTML-разметке Telegram: категории по убыванию суммы,
+// доля каждой категории, текстовая диаграмма и итоги в моноширинном блоке;
+// траты по участникам показываются, если в бюджет добавляли траты несколько человек,
+// траты в других валютах - исходной суммой и суммой в базовой валюте;
+// overspend - превышение лимитов месяцев, которые затрагивает период
+func formatReport(lang language, title string, totalMap map[string]int64, income int64, overspend int64, members []reportRow, currencies []reportRow) string {
+	rows, expencesTotal := sortReportRows(totalMap)
+
+	summary := []reportSummaryRow{
+		{Name: lang.tr("Total"), Amount: lang.amount(expencesTotal)},
+		{Name: lang.tr("Income"), Amount: lang.amount(income)},
+		{Name: lang.tr("Balance"), Amount: lang.amount(income - expencesTotal)},
+	}
+	if overspend > 0 {
+		summary = append(summary, reportSummaryRow{Name: lang.tr("Over budget"), Amount: lang.amount(overspend)})
+	}
+	if len(members) < 2 {
+		members = nil
+	}
+	return formatReportTable(lang, title, rows, expencesTotal, summary, members, currencies)
+}
+
+// formatTagReport - отчёт по метке; доходы метками не отмечаются, поэтому без дохода и баланса
+func formatTagReport(lang language, title string, totalMap map[string]int64) string {
+	rows, expencesTotal := sortReportRows(totalMap)
+
+	summary := []reportSummaryRow{
+		{Name: lang.tr("Total"), Amount: lang.amount(expencesTotal)},
+	}
+	return formatReportTable(lang, title, rows, expencesTotal, summary)
+}
+
+// formatReportTable - таблица категорий, итоги и дополнительные разделы с долей от суммы трат
+func formatReportTable(lang language, title string, rows []reportRow, expencesTotal int64, summary []reportSummaryRow, sections ...[]reportRow) string {
+	var nameWidth, amountWidth int
+	for _, row := range summary {
+		nameWidth = maxInt(nameWidth, utf8.RuneCountInString(row.Name))
+		amountWidth = maxInt(amountWidth, utf8.RuneCountInString(row.Amount))
+	}
+	for _, section := range append([][]reportRow{rows}, sections...) {
+		for _, row := range section {
+			nameWidth = maxInt(nameWidth, utf8.RuneCountInString(row.Category))
+			amountWidth = maxInt(amountWidth, utf8.RuneCountInString(lang.amount(row.Total)))
+		}
+	}
+
+	var maxTotal int64
+	if len(rows) > 0 {
+		maxTotal = rows[0].Total
+	}
+
+	var rvSb strings.Builder
+	rvSb.WriteString(fmt.Sprintf("<b>%s</b>\n<pre>", html.EscapeString(title)))
+	for _, row := range rows {
+		rvSb.WriteString(fmt.Sprintf("%s %*s %s%% %s\n",
+			padRight(row.Category, nameWidth),
+			amountWidth, lang.amount(row.Total),
+			lang.percent(percentOf(row.Total, expencesTotal)),
+			reportBar(row.Total, maxTotal)))
+	}
+	rvSb.WriteString(strings.Repeat("─", nameWidth+amountWidth+1) + "\n")
+	for _, row := range summary {
+		rvSb.WriteString(fmt.Sprintf("%s %*s\n", padRight(row.Name, nameWidth), amountWidth, row.Amount))
+	}
+	for _, section := range sections {
+		if len(section) == 0 {
+			continue
+		}
+		rvSb.WriteString(strings.Repeat("─", nameWidth+amountWidth+1) + "\n")
+		for _, row := range section {
+			rvSb.WriteString(fmt.Sprintf("%s %*s %s%%\n",
+				padRight(row.Category, nameWidth),
+				amountWidth, lang.amount(row.Total),
+				lang.percent(percentOf(row.Total, expencesTotal))))
+		}
+	}
+
+	return strings.TrimSuffix(rvSb.String(), "\n") + "</pre>"
+}
+
+// padRight - дополнение пробелами до ширины в символах, имя экранируется для HTML
+func padRight(name string, width int) string {
+	return html.EscapeString(name) + strings.Repeat(" ", width-utf8.RuneCountInString(name))
+}
+
+func percentOf(part int64, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) * 100 / float64(total)
+}
+
+// reportBar - столбец длиной пропорционально самой большой категории
+func reportBar(total int64, maxTotal int64) string {
+	if maxTotal <= 0 || total <= 0 {
+		return ""
+	}
+	eighths := total * reportBarWidth * 8 / maxTotal
+	return strings.Repeat("█", int(eighths/8)) + reportBarEighths[eighths%8]
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
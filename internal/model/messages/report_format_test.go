@@ -0,0 +1,81 @@
+package messages
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FormatReport_ShouldSortByTotalAndShowShares(t *testing.T) {
+	report := formatReport(langEnglish, "Last month expences", map[string]int64{
+		"taxi":     25000,
+		"продукты": 100000,
+		"<cafe>":   75000,
+	}, 150000, 0, nil, nil)
+
+	assert.Equal(t, "<b>Last month expences</b>\n<pre>"+
+		"продукты 1000.00  50.0% ██████████\n"+
+		"&lt;cafe&gt;    750.00  37.5% ███████▌\n"+
+		"taxi      250.00  12.5% ██▌\n"+
+		"────────────────\n"+
+		"Total    2000.00\n"+
+		"Income   1500.00\n"+
+		"Balance  -500.00</pre>", report)
+}
+
+func Test_FormatReport_ShouldShowMembersOfSharedBudget(t *testing.T) {
+	report := formatReport(langEnglish, "Last month expences", map[string]int64{
+		"food": 100000,
+	}, 50000, 0, []reportRow{
+		{Category: "@alice", Total: 75000},
+		{Category: "@bob", Total: 25000},
+	}, nil)
+
+	assert.Equal(t, "<b>Last month expences</b>\n<pre>"+
+		"food    1000.00 100.0% ██████████\n"+
+		"───────────────\n"+
+		"Total   1000.00\n"+
+		"Income   500.00\n"+
+		"Balance -500.00\n"+
+		"───────────────\n"+
+		"@alice   750.00  75.0%\n"+
+		"@bob     250.00  25.0%</pre>", report)
+}
+
+func Test_FormatReport_ShouldShowOriginalCurrencies(t *testing.T) {
+	report := formatReport(langEnglish, "Last month expences", map[string]int64{
+		"food": 100000,
+	}, 0, 0, nil, []reportRow{
+		{Category: "EUR 12.50", Total: 98000},
+	})
+
+	assert.Equal(t, "<b>Last month expences</b>\n<pre>"+
+		"food       1000.00 100.0% ██████████\n"+
+		"──────────────────\n"+
+		"Total      1000.00\n"+
+		"Income         0.0\n"+
+		"Balance   -1000.00\n"+
+		"──────────────────\n"+
+		"EUR 12.50   980.00  98.0%</pre>", report)
+}
+
+func Test_FormatReport_ShouldFlagMonthOverBudget(t *testing.T) {
+	report := formatReport(langEnglish, "Month expences", map[string]int64{
+		"food": 120000,
+	}, 0, 20000, nil, nil)
+
+	assert.Equal(t, "<b>Month expences</b>\n<pre>"+
+		"food         1200.00 100.0% ██████████\n"+
+		"────────────────────\n"+
+		"Total        1200.00\n"+
+		"Income           0.0\n"+
+		"Balance     -1200.00\n"+
+		"Over budget   200.00</pre>", report)
+}
+
+func Test_ReportBar(t *testing.T) {
+	assert.Equal(t, "██████████", reportBar(100, 100))
+	assert.Equal(t, "█████", reportBar(50, 100))
+	assert.Equal(t, "▉", reportBar(9, 100))
+	assert.Equal(t, "", reportBar(0, 100))
+}
@@ -0,0 +1,91 @@
+package messages
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/helpers"
+)
+
+// reportPeriod - период отчёта [Start, End)
+type reportPeriod struct {
+	Start time.Time
+	End   time.Time
+	Title string
+}
+
+// последние N дней, включая сегодняшний: 7d, 30d
+var regexpLastDays = regexp.MustCompile(`^(\d{1,3})d$`)
+
+var errReportPeriodWrongFormat = fmt.Errorf("wrong report period - use day/week/month/lastmonth/year, <N>d or <dd/mm/yyyy> <dd/mm/yyyy>")
+
+// parseReportPeriod - разбор периода отчёта: day, week, month, lastmonth, year, 7d, "01/09/2026 30/09/2026".
+// Периоды отсчитываются от дня now по времени пользователя; границы - полночь в UTC, как и даты трат.
+// Заголовок отчёта - на языке lang
+func parseReportPeriod(lang language, text string, now time.Time) (reportPeriod, error) {
+	args := strings.Fields(text)
+	startOfDay := helpers.DateOf(now)
+
+	switch len(args) {
+	case 0:
+		// у трат может быть дата в будущем, поэтому верхней границы по сути нет
+		return reportPeriod{
+			Start: time.Date(1970, 1, 1, 0, 0, 0, 0, startOfDay.Location()),
+			End:   time.Date(9999, 1, 1, 0, 0, 0, 0, startOfDay.Location()),
+			Title: lang.tr("All time expences"),
+		}, nil
+	case 2:
+		from, err := helpers.ParseDate(args[0], now)
+		if err != nil {
+			return reportPeriod{}, errDateWrongFormat
+		}
+		to, err := helpers.ParseDate(args[1], now)
+		if err != nil {
+			return reportPeriod{}, errDateWrongFormat
+		}
+		if to.Before(from) {
+			return reportPeriod{}, errReportPeriodWrongFormat
+		}
+		return reportPeriod{
+			Start: from,
+			End:   to.AddDate(0, 0, 1),
+			Title: lang.tr("Expences from %s to %s", lang.date(from), lang.date(to)),
+		}, nil
+	case 1:
+	default:
+		return reportPeriod{}, errReportPeriodWrongFormat
+	}
+
+	switch args[0] {
+	case "day":
+		return reportPeriod{Start: startOfDay, End: startOfDay.AddDate(0, 0, 1), Title: lang.tr("Last day expences")}, nil
+	case "week":
+		start := helpers.StartOfWeek(startOfDay)
+		return reportPeriod{Start: start, End: start.AddDate(0, 0, 7), Title: lang.tr("Last week expences")}, nil
+	case "month":
+		start := helpers.StartOfMonth(startOfDay)
+		return reportPeriod{Start: start, End: start.AddDate(0, 1, 0), Title: lang.tr("Last month expences")}, nil
+	case "lastmonth":
+		end := helpers.StartOfMonth(startOfDay)
+		return reportPeriod{Start: end.AddDate(0, -1, 0), End: end, Title: lang.tr("Previous month expences")}, nil
+	case "year":
+		start := helpers.StartOfYear(startOfDay)
+		return reportPeriod{Start: start, End: start.AddDate(1, 0, 0), Title: lang.tr("Last year expences")}, nil
+	}
+
+	if m := regexpLastDays.FindStringSubmatch(args[0]); m != nil {
+		days, _ := strconv.Atoi(m[1])
+		if days > 0 {
+			return reportPeriod{
+				Start: startOfDay.AddDate(0, 0, 1-days),
+				End:   startOfDay.AddDate(0, 0, 1),
+				Title: lang.tr("Last %d days expences", days),
+			}, nil
+		}
+	}
+
+	return reportPeriod{}, errReportPeriodWrongFormat
+}
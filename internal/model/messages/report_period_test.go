@@ -0,0 +1,52 @@
+package messages
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/helpers"
+)
+
+func Test_ParseReportPeriod(t *testing.T) {
+	now := time.Now()
+	today := helpers.DateOf(now)
+	tomorrow := today.AddDate(0, 0, 1)
+	month := helpers.StartOfMonth(today)
+
+	tests := []struct {
+		name      string
+		text      string
+		wantStart time.Time
+		wantEnd   time.Time
+	}{
+		{"day", "day", today, tomorrow},
+		{"week", "week", helpers.StartOfWeek(today), helpers.StartOfWeek(today).AddDate(0, 0, 7)},
+		{"month", "month", month, month.AddDate(0, 1, 0)},
+		{"previous month", "lastmonth", month.AddDate(0, -1, 0), month},
+		{"last 7 days", "7d", today.AddDate(0, 0, -6), tomorrow},
+		{"last 30 days", "30d", today.AddDate(0, 0, -29), tomorrow},
+		{
+			"explicit range includes last day",
+			"01/09/2026 30/09/2026",
+			time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			period, err := parseReportPeriod(langEnglish, tt.text, now)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStart, period.Start)
+			assert.Equal(t, tt.wantEnd, period.End)
+		})
+	}
+}
+
+func Test_ParseReportPeriod_WrongFormat(t *testing.T) {
+	for _, text := range []string{"decade", "0d", "30/09/2026 01/09/2026", "01/09/2026 30/09/2026 extra"} {
+		_, err := parseReportPeriod(langEnglish, text, time.Now())
+		assert.Error(t, err, text)
+	}
+}
@@ -0,0 +1,81 @@
+package messages
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/helpers"
+)
+
+var errTimezoneNotFound = fmt.Errorf("timezone was not found - use a name like Europe/Berlin or share your location")
+
+// LocationMessage - геопозиция, которой поделился пользователь
+type LocationMessage struct {
+	Message   Message
+	Latitude  float64
+	Longitude float64
+}
+
+// IncomingLocationMessage - часовой пояс по геопозиции
+func (s *Model) IncomingLocationMessage(ctx context.Context, msg LocationMessage) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "incoming_location_process")
+	defer span.Finish()
+
+	user := s.ensureUser(ctx, msg.Message.chatID())
+	s.ensureMember(ctx, msg.Message)
+	ctx, _ = chatContext(ctx, user, msg.Message)
+	lang := languageFrom(ctx)
+
+	answer, err := s.setTimezone(ctx, msg.Message.chatID(), helpers.LongitudeTimezone(msg.Longitude))
+	if err != nil {
+		answer = lang.errorText(err)
+	} else {
+		answer += "\n" + lang.tr("Daylight saving time is not taken into account - use '/%s %s' for it",
+			CommandNameMap[TimezoneCmd].Command, CommandNameMap[TimezoneCmd].Format)
+	}
+
+	return s.tgClient.SendMessage(answer, msg.Message.chatID())
+}
+
+// SetTimezone - часовой пояс, в котором считаются даты трат, периоды отчётов и сброс месячного лимита;
+// без аргументов - текущий часовой пояс
+func (s *Model) SetTimezone(ctx context.Context, userID int64, now time.Time, text string) (string, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "set_timezone_command")
+	defer span.Finish()
+
+	lang := languageFrom(ctx)
+
+	timezone := strings.TrimSpace(text)
+	if timezone == "" {
+		current := now.Location().String()
+		if now.Location() == time.Local {
+			current = lang.tr("server time")
+		}
+		return lang.tr("Timezone: %s, local time %s\nTo change send '/%s %s' or share your location",
+			current, lang.dateTime(now),
+			CommandNameMap[TimezoneCmd].Command, CommandNameMap[TimezoneCmd].Format), nil
+	}
+	if strings.Contains(timezone, " ") {
+		return "", errWrongCommandFormat
+	}
+
+	return s.setTimezone(ctx, userID, timezone)
+}
+
+func (s *Model) setTimezone(ctx context.Context, userID int64, timezone string) (string, error) {
+	// "Local" - часовой пояс сервера, пользователю его не выбрать
+	loc, err := time.LoadLocation(timezone)
+	if err != nil || loc == time.Local {
+		return "", errTimezoneNotFound
+	}
+
+	if err := s.storage.SetUserTimezone(ctx, userID, loc.String()); err != nil {
+		return "", errServer
+	}
+
+	lang := languageFrom(ctx)
+	return lang.tr("Timezone set to %s, local time %s", loc, lang.dateTime(time.Now().In(loc))), nil
+}
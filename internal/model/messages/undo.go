@@ -0,0 +1,43 @@
+package messages
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/opentracing/opentracing-go"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/common"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/domain"
+)
+
+var errNothingToUndo = fmt.Errorf("nothing to undo")
+
+// ответы об отменённом изменении по его виду
+var undoAnswers = map[string]string{
+	domain.OperationAddExpence:     "Last added expence removed",
+	domain.OperationAddCategory:    "Last added category removed",
+	domain.OperationMonthLimit:     "Month limit restored",
+	domain.OperationChangeCurrency: "Currency restored",
+}
+
+// Undo - отмена последнего изменения бюджета: добавления траты или категории, изменения лимита или смены валюты;
+// в общем бюджете участник memberID отменяет только свои изменения, списания регулярных трат не отменяются
+func (s *Model) Undo(ctx context.Context, userID int64, memberID int64) (string, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "undo_command")
+	defer span.Finish()
+
+	operation, err := s.storage.Undo(ctx, userID, memberID)
+	switch {
+	case err == common.ErrNothingToUndo:
+		return "", errNothingToUndo
+	case err == common.ErrExpenceNotFound:
+		return "", errExpenceNotFound
+	case err == common.ErrCategoryNotEmpty:
+		return "", errCategoryNotEmpty
+	case err == common.ErrCategoryHasRecurring:
+		return "", errCategoryHasRecurring
+	case err != nil:
+		return "", errServer
+	}
+
+	return languageFrom(ctx).tr(undoAnswers[operation.Kind]), nil
+}
@@ -0,0 +1,228 @@
+package messages
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-redis/redismock/v8"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/database"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/helpers"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/logger"
+	mocks "gitlab.ozon.dev/akosykh114/telegram-bot/internal/mocks/messages"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/storage"
+)
+
+func Test_OnUndoCommand_ShouldDeleteAddedExpence(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, mocksRedis := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+
+	mocksRedis.ExpectKeys("123*").SetVal([]string{})
+
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(mock.NewRows([]string{"id", "timezone", "language"}).AddRow(123, "", ""))
+	mock.ExpectQuery("SELECT id, kind, object_id, month_limit, month_limit_delta FROM operations").WithArgs(123, 123).WillReturnRows(
+		mock.NewRows([]string{"id", "kind", "object_id", "month_limit", "month_limit_delta"}).AddRow(3, "add_expence", 5, 0, 0))
+	mock.ExpectBegin()
+	mock.ExpectQuery("DELETE FROM expences").WithArgs(5, 123).WillReturnRows(
+		mock.NewRows([]string{"category_id", "ts", "total"}).AddRow(1, helpers.DateOf(time.Now()), 10000))
+	mock.ExpectQuery("SELECT limit_month").WithArgs(123).WillReturnRows(mock.NewRows([]string{"limit_month"}))
+	mock.ExpectExec("UPDATE users SET current_month_limit").WithArgs(10000, 123).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("UPDATE expence_category SET current_month_limit").WithArgs(-10000, 1).WillReturnRows(
+		mock.NewRows([]string{"current_month_limit", "name"}).AddRow(20000, "food"))
+	mock.ExpectCommit()
+	mock.ExpectExec("DELETE FROM operations").WithArgs(3, 123).WillReturnResult(sqlmock.NewResult(1, 1))
+
+	sender.EXPECT().SendMessage("Last added expence removed", int64(123))
+
+	err = model.IncomingCommandMessage(context.Background(), CommandMessage{
+		Message: Message{
+			UserID: 123,
+		},
+		CommandName: "undo",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.NoError(t, mocksRedis.ExpectationsWereMet())
+}
+
+func Test_OnUndoAfterSetLimit_ShouldRestoreLimit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, mocksRedis := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+
+	mocksRedis.ExpectKeys("123*").SetVal([]string{})
+
+	columns := []string{"id"}
+	budgetRows := func() *sqlmock.Rows {
+		return mock.NewRows([]string{"id", "timezone", "language"}).AddRow(123, "", "")
+	}
+
+	// лимит 10000.00, потрачено 4000.00; новый лимит 5000.00 добавляет к остатку -1000.00
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(budgetRows())
+	mock.ExpectQuery("SELECT base_currency_id").WithArgs(123).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT COALESCE\\(\\(SELECT rate FROM currency_rates").WithArgs(1, sqlmock.AnyArg(), 1).WillReturnRows(mock.NewRows(columns).AddRow(1))
+	mock.ExpectQuery("SELECT COALESCE\\(default_month_limit, 0\\), COALESCE\\(current_month_limit, 0\\) FROM users").WithArgs(123).WillReturnRows(
+		mock.NewRows([]string{"default_month_limit", "current_month_limit"}).AddRow(1000000, 600000))
+	mock.ExpectExec("UPDATE users SET default_month_limit").WithArgs(500000, 500000, 0, 123).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO operations").WithArgs(123, 123, "month_limit", 0, 1000000, -100000).WillReturnResult(sqlmock.NewResult(1, 1))
+
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(budgetRows())
+	mock.ExpectQuery("SELECT id, kind, object_id, month_limit, month_limit_delta FROM operations").WithArgs(123, 123).WillReturnRows(
+		mock.NewRows([]string{"id", "kind", "object_id", "month_limit", "month_limit_delta"}).AddRow(4, "month_limit", 0, 1000000, -100000))
+	mock.ExpectExec("UPDATE users SET default_month_limit = \\$1, current_month_limit = current_month_limit - \\$2").WithArgs(1000000, -100000, 123).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("DELETE FROM operations").WithArgs(4, 123).WillReturnResult(sqlmock.NewResult(1, 1))
+
+	gomock.InOrder(
+		sender.EXPECT().SendMessage("Month limit updated!", int64(123)),
+		sender.EXPECT().SendMessage("Month limit restored", int64(123)),
+	)
+
+	for _, command := range []CommandMessage{
+		{Message: Message{UserID: 123}, CommandName: "set_limit", CommandArguments: "5000"},
+		{Message: Message{UserID: 123}, CommandName: "undo"},
+	} {
+		err = model.IncomingCommandMessage(context.Background(), command)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.NoError(t, mocksRedis.ExpectationsWereMet())
+}
+
+func Test_OnUndoWithEmptyJournal_ShouldAnswerNothingToUndo(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, _ := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(123).WillReturnRows(mock.NewRows([]string{"id", "timezone", "language"}).AddRow(123, "", ""))
+	mock.ExpectQuery("SELECT id, kind, object_id, month_limit, month_limit_delta FROM operations").WithArgs(123, 123).WillReturnRows(
+		mock.NewRows([]string{"id", "kind", "object_id", "month_limit", "month_limit_delta"}))
+
+	sender.EXPECT().SendMessage("nothing to undo", int64(123))
+
+	err = model.IncomingCommandMessage(context.Background(), CommandMessage{
+		Message: Message{
+			UserID: 123,
+		},
+		CommandName: "undo",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_OnUndoInSharedBudget_ShouldSkipChangesOfOtherMembers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	_ = logger.InitLogger("data/zap_config.json")
+
+	usersDB := database.NewUsersDB(db)
+	categoriesDB := database.NewCategoriesDB(db)
+	currenciesDB := database.NewCurrenciesDB(db)
+	expencesDB := database.NewExpencesDB(db)
+	recurringDB := database.NewRecurringExpencesDB(db)
+	incomesDB := database.NewIncomesDB(db)
+	operationsDB := database.NewOperationsDB(db)
+
+	rdb, _ := redismock.NewClientMock()
+	reportDB := database.NewReportCacheDb(rdb)
+
+	ctrl := gomock.NewController(t)
+	sender := mocks.NewMockMessageSender(ctrl)
+	r := &ReportRequestProducer{}
+	e := &ExpencesGetter{}
+
+	storageModel := storage.New(usersDB, categoriesDB, currenciesDB, expencesDB, recurringDB, incomesDB, operationsDB, reportDB, r, e)
+	model := New(sender, storageModel)
+
+	// в бюджете 7 есть только изменения других участников, журнал выбирается по участнику 456
+	mock.ExpectQuery("SELECT COALESCE\\(budget_id, id\\), timezone, language FROM users").WithArgs(456).WillReturnRows(mock.NewRows([]string{"id", "timezone", "language"}).AddRow(7, "", ""))
+	mock.ExpectQuery("SELECT id, kind, object_id, month_limit, month_limit_delta FROM operations WHERE member_id = \\$1 AND user_id = \\$2").WithArgs(456, 7).WillReturnRows(
+		mock.NewRows([]string{"id", "kind", "object_id", "month_limit", "month_limit_delta"}))
+
+	sender.EXPECT().SendMessage("nothing to undo", int64(456))
+
+	err = model.IncomingCommandMessage(context.Background(), CommandMessage{
+		Message: Message{
+			UserID: 456,
+		},
+		CommandName: "undo",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
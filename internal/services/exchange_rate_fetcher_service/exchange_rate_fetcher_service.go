@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/domain"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/helpers"
 	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/logger"
 	"go.uber.org/zap"
 )
@@ -16,6 +17,8 @@ import (
 type ExchangeResponse struct {
 	IsSuccess bool               `json:"success"`
 	Rates     map[string]float64 `json:"rates"`
+	// день, на который api отдал курсы: 2022-11-25
+	Date string `json:"date"`
 }
 
 type ServiceConfigurer interface {
@@ -114,19 +117,29 @@ func (s *ExchangeFetcherService) fetchData(ctx context.Context, request *http.Re
 		return err
 	}
 
-	s.fillAvailableCurrenciesWithUpdatedRates(exchangeResponse.Rates)
+	s.fillAvailableCurrenciesWithUpdatedRates(exchangeResponse.Rates, parseRatesDate(exchangeResponse.Date))
 	return nil
 }
 
-func (s *ExchangeFetcherService) fillAvailableCurrenciesWithUpdatedRates(rates map[string]float64) {
+func (s *ExchangeFetcherService) fillAvailableCurrenciesWithUpdatedRates(rates map[string]float64, date time.Time) {
 	for i, c := range s.availableCurrencies {
+		c.Date = date
 		if _, found := rates[c.Code]; found {
 			c.Rate = rates[c.Code]
-			s.availableCurrencies[i] = c
 		}
+		s.availableCurrencies[i] = c
 	}
 }
 
+// parseRatesDate - день курсов из ответа api; если api его не прислал - сегодняшний день
+func parseRatesDate(date string) time.Time {
+	rv, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return helpers.GetStartOfCurrentDay()
+	}
+	return rv
+}
+
 func getCurrencyCodesStr(currencies []domain.Currency) string {
 	keys := make([]string, len(currencies))
 
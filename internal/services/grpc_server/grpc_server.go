@@ -23,24 +23,43 @@ func formatServiceLog(log string) string {
 
 type GrpcReportServer struct {
 	pb.UnimplementedReportSenderServer
-	expencesChan chan []domain.Expence
+
+	// таблица ожидающих ответа запросов отчёта: request id -> канал вызывающего
+	pendingMu sync.Mutex
+	pending   map[int64]chan []domain.Expence
 }
 
 func New() *GrpcReportServer {
 	rv := &GrpcReportServer{
-		expencesChan: make(chan []domain.Expence),
+		pending: make(map[int64]chan []domain.Expence),
 	}
 	return rv
 }
 
-func (s *GrpcReportServer) GetReportExpencesChan() chan []domain.Expence {
-	return s.expencesChan
+// WaitReportExpences регистрирует ожидание отчёта с указанным request id
+// и возвращает канал, в который придут траты только этого запроса
+func (s *GrpcReportServer) WaitReportExpences(requestID int64) <-chan []domain.Expence {
+	ch := make(chan []domain.Expence, 1)
+
+	s.pendingMu.Lock()
+	s.pending[requestID] = ch
+	s.pendingMu.Unlock()
+
+	return ch
+}
+
+// CancelReportExpences удаляет запрос из таблицы ожидания
+func (s *GrpcReportServer) CancelReportExpences(requestID int64) {
+	s.pendingMu.Lock()
+	delete(s.pending, requestID)
+	s.pendingMu.Unlock()
 }
 
 func (s *GrpcReportServer) SendReport(ctx context.Context, reportMsg *pb.Report) (*pb.ReportResponse, error) {
 	logger.Info(formatServiceLog("new message..."))
 
 	userId := reportMsg.UserId.GetValue()
+	requestID := reportMsg.RequestId.GetValue()
 	var expences []domain.Expence
 	for _, v := range reportMsg.Expences {
 		e := &domain.Expence{
@@ -50,17 +69,25 @@ func (s *GrpcReportServer) SendReport(ctx context.Context, reportMsg *pb.Report)
 			CategoryName: v.CategoryName.GetValue(),
 			Timestamp:    time.Unix(v.Ts.GetValue(), 0),
 			Total:        v.Total.Value,
+			Note:         v.Note.GetValue(),
+			Tags:         v.GetTags(),
 		}
 		expences = append(expences, *e)
 	}
 
-	select {
-	case <-ctx.Done():
+	s.pendingMu.Lock()
+	ch, found := s.pending[requestID]
+	delete(s.pending, requestID)
+	s.pendingMu.Unlock()
+
+	if !found {
+		logger.Warn(formatServiceLog("no pending request for report"), zap.Int64("request_id", requestID))
 		return &pb.ReportResponse{ResponseCode: wrapperspb.Int64(1)}, nil
-	case s.expencesChan <- expences:
-		//
 	}
 
+	// канал буферизирован на один отчёт, поэтому отправка не блокируется
+	ch <- expences
+
 	return &pb.ReportResponse{ResponseCode: wrapperspb.Int64(1)}, nil
 }
 
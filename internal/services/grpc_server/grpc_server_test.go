@@ -0,0 +1,71 @@
+package grpcserver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	pb "gitlab.ozon.dev/akosykh114/telegram-bot/api"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/logger"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func reportMsg(requestID int64, userID int64, categoryName string) *pb.Report {
+	return &pb.Report{
+		RequestId: wrapperspb.Int64(requestID),
+		UserId:    wrapperspb.Int64(userID),
+		Expences: []*pb.Expence{
+			{
+				Id:           wrapperspb.Int64(1),
+				CategoryId:   wrapperspb.Int64(1),
+				CategoryName: wrapperspb.String(categoryName),
+				Ts:           wrapperspb.Int64(0),
+				Total:        wrapperspb.Int64(100),
+				Note:         wrapperspb.String("dinner with clients"),
+				Tags:         []string{"vacation"},
+			},
+		},
+	}
+}
+
+func Test_OnConcurrentReports_ShouldDeliverEachToItsRequester(t *testing.T) {
+	logger.Logger = zap.NewNop()
+
+	s := New()
+	first := s.WaitReportExpences(1)
+	second := s.WaitReportExpences(2)
+
+	// ответы приходят в обратном порядке
+	_, err := s.SendReport(context.Background(), reportMsg(2, 456, "taxi"))
+	assert.NoError(t, err)
+	_, err = s.SendReport(context.Background(), reportMsg(1, 123, "food"))
+	assert.NoError(t, err)
+
+	firstExpences := <-first
+	secondExpences := <-second
+
+	assert.Equal(t, int64(123), firstExpences[0].UserID)
+	assert.Equal(t, "food", firstExpences[0].CategoryName)
+	assert.Equal(t, "dinner with clients", firstExpences[0].Note)
+	assert.Equal(t, []string{"vacation"}, firstExpences[0].Tags)
+	assert.Equal(t, int64(456), secondExpences[0].UserID)
+	assert.Equal(t, "taxi", secondExpences[0].CategoryName)
+}
+
+func Test_OnUnknownRequestID_ShouldDropReport(t *testing.T) {
+	logger.Logger = zap.NewNop()
+
+	s := New()
+	ch := s.WaitReportExpences(1)
+	s.CancelReportExpences(1)
+
+	_, err := s.SendReport(context.Background(), reportMsg(1, 123, "food"))
+	assert.NoError(t, err)
+
+	select {
+	case <-ch:
+		t.Fatal("cancelled request must not receive a report")
+	default:
+	}
+}
@@ -33,8 +33,10 @@ func (s *LimitUpdaterService) StartService(ctx context.Context, wg *sync.WaitGro
 		defer wg.Done()
 
 		c := cron.New()
-		if _, err := c.AddFunc("@monthly", func() {
-			logger.Info(formatServiceLog("updating limits..."))
+		// месяц у пользователей начинается в полночь по их часовому поясу,
+		// поэтому начало месяца проверяется каждые 15 минут: есть пояса со смещением 30 и 45 минут
+		if _, err := c.AddFunc("*/15 * * * *", func() {
+			logger.Info(formatServiceLog("checking month limits..."))
 			s.monthLimitChan <- struct{}{}
 		}); err != nil {
 			logger.Error("cron func error", zap.Error(err))
@@ -0,0 +1,58 @@
+package recurringexpenceservice
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/robfig/cron/v3"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/logger"
+	"go.uber.org/zap"
+)
+
+type RecurringExpenceService struct {
+	postChan chan struct{}
+}
+
+func New() (*RecurringExpenceService, chan struct{}) {
+	rv := &RecurringExpenceService{
+		postChan: make(chan struct{}),
+	}
+	return rv, rv.postChan
+}
+
+func formatServiceLog(log string) string {
+	return fmt.Sprintf("<RecExpServ>: %s", log)
+}
+
+func (s *RecurringExpenceService) StartService(ctx context.Context, wg *sync.WaitGroup) {
+	logger.Info(formatServiceLog("Starting service..."))
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		// первое списание сразу при старте - для трат, срок которых наступил, пока бот не работал
+		select {
+		case s.postChan <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+
+		c := cron.New()
+		if _, err := c.AddFunc("@every 1m", func() {
+			logger.Info(formatServiceLog("posting recurring expences..."))
+			s.postChan <- struct{}{}
+		}); err != nil {
+			logger.Error("cron func error", zap.Error(err))
+			return
+		}
+
+		c.Start()
+
+		<-ctx.Done()
+		c.Stop()
+
+		logger.Info(formatServiceLog("Stopping..."))
+	}()
+}
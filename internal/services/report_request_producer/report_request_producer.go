@@ -15,6 +15,9 @@ import (
 var (
 	KafkaTopic = "report-topic"
 	BrokerList = []string{"localhost:9092"}
+
+	// заголовок сообщения, по которому ответ генератора сопоставляется с запросом
+	RequestIDHeader = "request_id"
 )
 
 type ReportRequestProducer struct {
@@ -55,6 +58,11 @@ func formatServiceLog(log string) string {
 	return "<Report Request Producer>: " + log
 }
 
+// значение сообщения - период отчёта "<начало>,<конец>" в unix-времени
+func formatReportPeriod(report domain.ReportRequest) string {
+	return strconv.FormatInt(report.Timestamp.Unix(), 10) + "," + strconv.FormatInt(report.EndTimestamp.Unix(), 10)
+}
+
 func (r *ReportRequestProducer) StartService(ctx context.Context, wg *sync.WaitGroup) {
 	logger.Info(formatServiceLog("Starting producer..."))
 
@@ -68,7 +76,13 @@ func (r *ReportRequestProducer) StartService(ctx context.Context, wg *sync.WaitG
 				msg := sarama.ProducerMessage{
 					Topic: KafkaTopic,
 					Key:   sarama.StringEncoder(strconv.FormatInt(report.UserID, 10)),
-					Value: sarama.StringEncoder(strconv.FormatInt(report.Timestamp.Unix(), 10)),
+					Value: sarama.StringEncoder(formatReportPeriod(report)),
+					Headers: []sarama.RecordHeader{
+						{
+							Key:   []byte(RequestIDHeader),
+							Value: []byte(strconv.FormatInt(report.RequestID, 10)),
+						},
+					},
 				}
 				r.producer.Input() <- &msg
 				successMsg := <-r.producer.Successes()
@@ -2,12 +2,19 @@ package storage
 
 import (
 	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/opentracing/opentracing-go"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/common"
 	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/domain"
+	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/helpers"
 	"gitlab.ozon.dev/akosykh114/telegram-bot/internal/logger"
 	"go.uber.org/zap"
 )
@@ -19,24 +26,79 @@ type UsersDatabase interface {
 	ChangeCurrency(ctx context.Context, user domain.User, currency domain.Currency) error
 	GetUserBaseCurrency(ctx context.Context, user domain.User) (domain.Currency, error)
 	SetUserLimit(ctx context.Context, user domain.User) error
-	UpdateMonthLimits(ctx context.Context) error
+	GetUserLimits(ctx context.Context, user domain.User) (domain.User, error)
+	GetOverspend(ctx context.Context, user domain.User, start time.Time, end time.Time, month time.Time) (int64, error)
+	UndoUserLimit(ctx context.Context, operation domain.Operation) error
+	GetLimitAlerts(ctx context.Context, user domain.User) (domain.User, error)
+	SetLimitAlerts(ctx context.Context, user domain.User) error
+	FireLimitAlert(ctx context.Context, user domain.User) (bool, error)
+	GetLimitMode(ctx context.Context, user domain.User) (domain.User, error)
+	SetLimitMode(ctx context.Context, user domain.User) error
+	UpdateMonthLimits(ctx context.Context, timezone string, month time.Time) error
 	ResetUserLimit(ctx context.Context, user domain.User) error
+	GetUserBudget(ctx context.Context, user domain.User) (domain.User, error)
+	SetUserTimezone(ctx context.Context, user domain.User) error
+	SetUserLanguage(ctx context.Context, user domain.User) error
+	GetTimezones(ctx context.Context) ([]string, error)
+	SetUserName(ctx context.Context, user domain.User) error
+	GetBudgetInviteCode(ctx context.Context, budget domain.Budget) (string, error)
+	CreateBudget(ctx context.Context, budget domain.Budget) error
+	JoinBudget(ctx context.Context, user domain.User, inviteCode string) (int64, error)
+	LeaveBudget(ctx context.Context, user domain.User) error
+	GetBudgetMembers(ctx context.Context, budget domain.Budget) ([]domain.User, error)
 }
 
 type CategoriesDatabase interface {
 	IsCategoryExists(ctx context.Context, category domain.ExpenceCategory) (int64, error)
-	AddCategory(ctx context.Context, category domain.ExpenceCategory) error
+	AddCategory(ctx context.Context, category domain.ExpenceCategory) (int64, error)
+	GetUserCategories(ctx context.Context, user domain.User) ([]domain.ExpenceCategory, error)
+	GetCategory(ctx context.Context, category domain.ExpenceCategory) (domain.ExpenceCategory, error)
+	RenameCategory(ctx context.Context, category domain.ExpenceCategory) error
+	MergeCategory(ctx context.Context, from domain.ExpenceCategory, into domain.ExpenceCategory) error
+	DeleteCategory(ctx context.Context, category domain.ExpenceCategory) error
+	SetCategoryLimit(ctx context.Context, category domain.ExpenceCategory) error
+	AddCategoryKeyword(ctx context.Context, keyword domain.CategoryKeyword) error
+	GetUserCategoryKeywords(ctx context.Context, user domain.User) ([]domain.CategoryKeyword, error)
 }
 
 type CurrunciesDatabase interface {
 	IsCurrencyExists(ctx context.Context, currency domain.Currency) (domain.Currency, error)
-	GetCurrencyRate(ctx context.Context, currency domain.Currency) (domain.Currency, error)
+	GetCurrencyRateAt(ctx context.Context, currency domain.Currency, date time.Time) (domain.Currency, error)
 	UpdateRates(ctx context.Context, currencies []domain.Currency) error
 }
 
 type ExpencesDatabase interface {
-	AddExpence(ctx context.Context, expence domain.Expence) error
-	GetUserExpences(ctx context.Context, user domain.User, limitTs time.Time) ([]domain.Expence, error)
+	AddExpence(ctx context.Context, expence domain.Expence) (int64, error)
+	GetUserExpences(ctx context.Context, user domain.User, startTs time.Time, endTs time.Time) ([]domain.Expence, error)
+	GetUserExpencesPage(ctx context.Context, user domain.User, offset uint64, limit uint64) ([]domain.Expence, error)
+	GetUserDailyTotals(ctx context.Context, user domain.User, startTs time.Time, endTs time.Time) ([]domain.Expence, error)
+	GetUserExpenceRows(ctx context.Context, user domain.User, startTs time.Time, endTs time.Time) ([]domain.Expence, error)
+	ImportExpences(ctx context.Context, user domain.User, expences []domain.Expence) (int64, error)
+	GetUserMemberTotals(ctx context.Context, user domain.User, startTs time.Time, endTs time.Time) ([]domain.MemberTotal, error)
+	GetUserCurrencyTotals(ctx context.Context, user domain.User, startTs time.Time, endTs time.Time) ([]domain.Expence, error)
+	SearchUserExpences(ctx context.Context, user domain.User, text string, limit uint64) ([]domain.Expence, error)
+	GetUserTagTotals(ctx context.Context, user domain.User, tag string, startTs time.Time, endTs time.Time) ([]domain.Expence, error)
+	UpdateExpence(ctx context.Context, expence domain.Expence) error
+	DeleteExpence(ctx context.Context, expence domain.Expence) error
+}
+
+type IncomesDatabase interface {
+	AddIncome(ctx context.Context, income domain.Income) error
+	GetUserIncomeTotal(ctx context.Context, user domain.User, startTs time.Time, endTs time.Time) (int64, error)
+}
+
+type RecurringExpencesDatabase interface {
+	AddRecurringExpence(ctx context.Context, expence domain.RecurringExpence) (int64, error)
+	GetUserRecurringExpences(ctx context.Context, user domain.User) ([]domain.RecurringExpence, error)
+	GetDueRecurringExpences(ctx context.Context, ts time.Time) ([]domain.RecurringExpence, error)
+	UpdateRecurringExpence(ctx context.Context, expence domain.RecurringExpence) error
+	DeleteRecurringExpence(ctx context.Context, expence domain.RecurringExpence) error
+}
+
+type OperationsDatabase interface {
+	AddOperation(ctx context.Context, operation domain.Operation) error
+	GetLastOperation(ctx context.Context, member domain.Operation) (domain.Operation, error)
+	DeleteOperation(ctx context.Context, operation domain.Operation) error
 }
 
 type ReportRequester interface {
@@ -44,12 +106,13 @@ type ReportRequester interface {
 }
 
 type ExpencesGetter interface {
-	GetReportExpencesChan() chan []domain.Expence
+	WaitReportExpences(requestID int64) <-chan []domain.Expence
+	CancelReportExpences(requestID int64)
 }
 
 type ReportCacheDatabase interface {
-	GetUserExpences(ctx context.Context, user domain.User, limitTs time.Time) (map[string]int64, error)
-	SetUserExpences(ctx context.Context, user domain.User, expencesMap map[string]string, limitTs time.Time) error
+	GetUserExpences(ctx context.Context, user domain.User, startTs time.Time, endTs time.Time) (map[string]int64, error)
+	SetUserExpences(ctx context.Context, user domain.User, expencesMap map[string]string, startTs time.Time, endTs time.Time) error
 	DeleteUserReports(ctx context.Context, user domain.User) error
 }
 
@@ -58,9 +121,15 @@ type Storage struct {
 	CategoriesDB      CategoriesDatabase
 	CurrunciesDB      CurrunciesDatabase
 	ExpencesDB        ExpencesDatabase
+	RecurringDB       RecurringExpencesDatabase
+	IncomesDB         IncomesDatabase
+	OperationsDB      OperationsDatabase
 	ReportCDB         ReportCacheDatabase
 	ReportReq         ReportRequester
 	ExpencesGetterObj ExpencesGetter
+
+	// последний выданный идентификатор запроса отчёта
+	lastRequestID int64
 }
 
 func New(
@@ -68,6 +137,9 @@ func New(
 	categoriesDB CategoriesDatabase,
 	currunciesDB CurrunciesDatabase,
 	expencesDB ExpencesDatabase,
+	recurringDB RecurringExpencesDatabase,
+	incomesDB IncomesDatabase,
+	operationsDB OperationsDatabase,
 	reportCDB ReportCacheDatabase,
 	reportRequester ReportRequester,
 	expencesGetter ExpencesGetter,
@@ -77,9 +149,13 @@ func New(
 		CategoriesDB:      categoriesDB,
 		CurrunciesDB:      currunciesDB,
 		ExpencesDB:        expencesDB,
+		RecurringDB:       recurringDB,
+		IncomesDB:         incomesDB,
+		OperationsDB:      operationsDB,
 		ReportCDB:         reportCDB,
 		ReportReq:         reportRequester,
 		ExpencesGetterObj: expencesGetter,
+		lastRequestID:     time.Now().UnixNano(),
 	}
 }
 
@@ -113,7 +189,152 @@ func (s *Storage) ResetUser(ctx context.Context, userID int64) bool {
 	return true
 }
 
-func (s *Storage) ChangeCurrency(ctx context.Context, userID int64, currency string) bool {
+// GetUserBudget - бюджет, в котором ведёт учёт пользователь, часовой пояс и язык пользователя; false - пользователь не добавлен
+func (s *Storage) GetUserBudget(ctx context.Context, userID int64) (domain.User, bool) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_user_budget_storage")
+	defer span.Finish()
+
+	user, err := s.UsersDB.GetUserBudget(ctx, domain.User{UserID: userID})
+	if err != nil {
+		return domain.User{UserID: userID, BudgetID: userID}, false
+	}
+	return user, true
+}
+
+// SetUserTimezone - часовой пояс, по которому считаются даты, периоды отчётов и сброс лимитов
+func (s *Storage) SetUserTimezone(ctx context.Context, userID int64, timezone string) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "set_user_timezone_storage")
+	defer span.Finish()
+
+	if err := s.UsersDB.SetUserTimezone(ctx, domain.User{UserID: userID, Timezone: timezone}); err != nil {
+		logger.Warn("SetUserTimezone storage error:", zap.Error(err))
+		return err
+	}
+
+	// периоды отчётов в кэше посчитаны в старом часовом поясе
+	if err := s.ReportCDB.DeleteUserReports(ctx, domain.User{UserID: userID}); err != nil {
+		logger.Warn("SetUserTimezone storage error:", zap.Error(err))
+	}
+	return nil
+}
+
+// SetUserLanguage - язык ответов бота
+func (s *Storage) SetUserLanguage(ctx context.Context, userID int64, language string) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "set_user_language_storage")
+	defer span.Finish()
+
+	if err := s.UsersDB.SetUserLanguage(ctx, domain.User{UserID: userID, Language: language}); err != nil {
+		logger.Warn("SetUserLanguage storage error:", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// SetUserName - имя пользователя для списка участников бюджета и отчёта по участникам
+func (s *Storage) SetUserName(ctx context.Context, userID int64, name string) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "set_user_name_storage")
+	defer span.Finish()
+
+	if err := s.UsersDB.SetUserName(ctx, domain.User{UserID: userID, Name: name}); err != nil {
+		logger.Warn("SetUserName storage error:", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetBudgetInviteCode - код приглашения в бюджет пользователя; бюджет создаётся при первом обращении
+func (s *Storage) GetBudgetInviteCode(ctx context.Context, budgetID int64) (string, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_budget_invite_code_storage")
+	defer span.Finish()
+
+	code, err := s.UsersDB.GetBudgetInviteCode(ctx, domain.Budget{ID: budgetID})
+	if err == nil {
+		return code, nil
+	}
+	if err != sql.ErrNoRows {
+		logger.Warn("GetBudgetInviteCode storage error:", zap.Error(err))
+		return "", err
+	}
+
+	code, err = newInviteCode()
+	if err != nil {
+		return "", err
+	}
+	if err := s.UsersDB.CreateBudget(ctx, domain.Budget{ID: budgetID, InviteCode: code}); err != nil {
+		logger.Warn("GetBudgetInviteCode storage error:", zap.Error(err))
+		return "", err
+	}
+	return code, nil
+}
+
+func (s *Storage) JoinBudget(ctx context.Context, userID int64, inviteCode string) (int64, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "join_budget_storage")
+	defer span.Finish()
+
+	budgetID, err := s.UsersDB.JoinBudget(ctx, domain.User{UserID: userID}, inviteCode)
+	if err != nil {
+		logger.Warn("JoinBudget storage error:", zap.Error(err))
+		return 0, err
+	}
+	return budgetID, nil
+}
+
+func (s *Storage) LeaveBudget(ctx context.Context, userID int64) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "leave_budget_storage")
+	defer span.Finish()
+
+	if err := s.UsersDB.LeaveBudget(ctx, domain.User{UserID: userID}); err != nil {
+		logger.Warn("LeaveBudget storage error:", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (s *Storage) GetBudgetMembers(ctx context.Context, budgetID int64) ([]domain.User, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_budget_members_storage")
+	defer span.Finish()
+
+	members, err := s.UsersDB.GetBudgetMembers(ctx, domain.Budget{ID: budgetID})
+	if err != nil {
+		logger.Warn("GetBudgetMembers storage error:", zap.Error(err))
+		return nil, err
+	}
+	return members, nil
+}
+
+// GetMemberTotals - суммы трат бюджета за период [startTs, endTs) по участникам в базовой валюте бюджета
+func (s *Storage) GetMemberTotals(ctx context.Context, userID int64, startTs time.Time, endTs time.Time) ([]domain.MemberTotal, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_member_totals_storage")
+	defer span.Finish()
+
+	rate, err := s.getCurrencyRate(ctx, userID, "", reportRateDate(endTs))
+	if err != nil {
+		logger.Warn("GetMemberTotals storage error:", zap.Error(err))
+		return nil, err
+	}
+
+	totals, err := s.ExpencesDB.GetUserMemberTotals(ctx, domain.User{UserID: userID}, startTs, endTs)
+	if err != nil {
+		logger.Warn("GetMemberTotals storage error:", zap.Error(err))
+		return nil, err
+	}
+
+	for i := range totals {
+		totals[i].Total = int64(float64(totals[i].Total) * rate)
+	}
+	return totals, nil
+}
+
+// newInviteCode - случайный код приглашения из 8 символов
+func newInviteCode() (string, error) {
+	buf := make([]byte, 5)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base32.StdEncoding.EncodeToString(buf), nil
+}
+
+func (s *Storage) ChangeCurrency(ctx context.Context, userID int64, memberID int64, currency string) bool {
 	span, ctx := opentracing.StartSpanFromContext(ctx, "change_currency_storage")
 	defer span.Finish()
 
@@ -121,11 +342,18 @@ func (s *Storage) ChangeCurrency(ctx context.Context, userID int64, currency str
 	if err != nil {
 		return false
 	}
+	prevCurrency, err := s.UsersDB.GetUserBaseCurrency(ctx, domain.User{UserID: userID})
+	if err != nil {
+		logger.Warn("change currency storage error:", zap.Error(err))
+		return false
+	}
 	if err := s.UsersDB.ChangeCurrency(ctx, domain.User{UserID: userID}, curr); err != nil {
 		logger.Warn("change currency storage error:", zap.Error(err))
 		return false
 	}
 
+	s.addOperation(ctx, domain.Operation{UserID: userID, MemberID: memberID, Kind: domain.OperationChangeCurrency, ObjectID: int64(prevCurrency.ID)})
+
 	err = s.ReportCDB.DeleteUserReports(ctx, domain.User{UserID: userID})
 	if err != nil {
 		logger.Warn("ChangeCurrency delete user reports error:", zap.Error(err))
@@ -149,198 +377,1084 @@ func (s *Storage) IsCategoryExists(ctx context.Context, userID int64, cat string
 	return idx != -1
 }
 
-func (s *Storage) AddCategory(ctx context.Context, userID int64, cat string) bool {
+func (s *Storage) AddCategory(ctx context.Context, userID int64, memberID int64, cat string) bool {
 	span, ctx := opentracing.StartSpanFromContext(ctx, "add_category_storage")
 	defer span.Finish()
 
 	if !s.IsCategoryExists(ctx, userID, cat) {
-		err := s.CategoriesDB.AddCategory(ctx, domain.ExpenceCategory{UserID: userID, Name: cat})
+		id, err := s.CategoriesDB.AddCategory(ctx, domain.ExpenceCategory{UserID: userID, Name: cat})
 		if err != nil {
 			logger.Warn("add_category storage error:", zap.Error(err))
 			return false
 		}
+		s.addOperation(ctx, domain.Operation{UserID: userID, MemberID: memberID, Kind: domain.OperationAddCategory, ObjectID: id})
 		return true
 	}
 	return false
 }
 
-func (s *Storage) AddExpence(ctx context.Context, userID int64, cat string, total int64, date time.Time) error {
-	span, ctx := opentracing.StartSpanFromContext(ctx, "add_expence_storage")
+func (s *Storage) GetCategories(ctx context.Context, userID int64) ([]string, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_categories_storage")
 	defer span.Finish()
 
-	categoryID, err := s.CategoriesDB.IsCategoryExists(ctx, domain.ExpenceCategory{
-		UserID: userID,
-		Name:   cat,
-	})
+	categories, err := s.CategoriesDB.GetUserCategories(ctx, domain.User{UserID: userID})
 	if err != nil {
-		logger.Warn("AddExpence storage error:", zap.Error(err))
-		return err
+		logger.Warn("get_categories storage error:", zap.Error(err))
+		return nil, err
+	}
+
+	names := make([]string, 0, len(categories))
+	for _, category := range categories {
+		names = append(names, category.Name)
+	}
+	return names, nil
+}
+
+// GetUserCategories - категории бюджета с их id, по названию
+func (s *Storage) GetUserCategories(ctx context.Context, userID int64) ([]domain.ExpenceCategory, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_user_categories_storage")
+	defer span.Finish()
+
+	categories, err := s.CategoriesDB.GetUserCategories(ctx, domain.User{UserID: userID})
+	if err != nil {
+		logger.Warn("GetUserCategories storage error:", zap.Error(err))
+		return nil, err
 	}
+	return categories, nil
+}
 
-	baseCurrency, err := s.UsersDB.GetUserBaseCurrency(ctx, domain.User{UserID: userID})
+// GetCategoryName - название категории бюджета по id
+func (s *Storage) GetCategoryName(ctx context.Context, userID int64, categoryID int64) (string, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_category_name_storage")
+	defer span.Finish()
+
+	category, err := s.CategoriesDB.GetCategory(ctx, domain.ExpenceCategory{ID: categoryID, UserID: userID})
 	if err != nil {
-		logger.Warn("AddExpence storage error:", zap.Error(err))
-		return err
+		if err != common.ErrCategoryNotFound {
+			logger.Warn("GetCategoryName storage error:", zap.Error(err))
+		}
+		return "", err
 	}
+	return category.Name, nil
+}
+
+func (s *Storage) RenameCategory(ctx context.Context, userID int64, oldName string, newName string) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "rename_category_storage")
+	defer span.Finish()
 
-	baseCurrency, err = s.CurrunciesDB.GetCurrencyRate(ctx, domain.Currency{ID: baseCurrency.ID})
+	category, err := s.getCategory(ctx, userID, oldName)
 	if err != nil {
-		logger.Warn("AddExpence storage error:", zap.Error(err))
 		return err
 	}
+	if s.IsCategoryExists(ctx, userID, newName) {
+		return common.ErrCategoryExists
+	}
 
-	expence := domain.Expence{
-		UserID:     userID,
-		CategoryID: categoryID,
-		Timestamp:  date,
-		Total:      int64(float64(total) / baseCurrency.Rate),
+	category.Name = newName
+	if err := s.CategoriesDB.RenameCategory(ctx, category); err != nil {
+		logger.Warn("RenameCategory storage error:", zap.Error(err))
+		return err
 	}
 
-	err = s.ExpencesDB.AddExpence(ctx, expence)
+	err = s.ReportCDB.DeleteUserReports(ctx, domain.User{UserID: userID})
 	if err != nil {
-		logger.Warn("AddExpence storage error:", zap.Error(err))
+		logger.Warn("RenameCategory storage error:", zap.Error(err))
+	}
+
+	return nil
+}
+
+// MergeCategory - перенос трат из категории from в into, категория from удаляется
+func (s *Storage) MergeCategory(ctx context.Context, userID int64, from string, into string) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "merge_category_storage")
+	defer span.Finish()
+
+	fromCategory, err := s.getCategory(ctx, userID, from)
+	if err != nil {
+		return err
+	}
+	intoCategory, err := s.getCategory(ctx, userID, into)
+	if err != nil {
+		return err
+	}
+
+	if err := s.CategoriesDB.MergeCategory(ctx, fromCategory, intoCategory); err != nil {
+		logger.Warn("MergeCategory storage error:", zap.Error(err))
 		return err
 	}
 
 	err = s.ReportCDB.DeleteUserReports(ctx, domain.User{UserID: userID})
 	if err != nil {
-		logger.Warn("AddExpence storage error:", zap.Error(err))
+		logger.Warn("MergeCategory storage error:", zap.Error(err))
 	}
 
 	return nil
 }
 
-func (s *Storage) GetExpencesMap(ctx context.Context, userID int64, limitTs time.Time) map[string]int64 {
-	span, ctx := opentracing.StartSpanFromContext(ctx, "get_expence_map_storage")
+// DeleteCategory - удаление категории; категория с тратами не удаляется
+func (s *Storage) DeleteCategory(ctx context.Context, userID int64, cat string) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "delete_category_storage")
 	defer span.Finish()
 
-	// обращение к кэшу за отчётом по пользователю
-	rv, err := s.ReportCDB.GetUserExpences(ctx, domain.User{UserID: userID}, limitTs)
+	category, err := s.getCategory(ctx, userID, cat)
 	if err != nil {
-		logger.Warn("cache get report error", zap.Error(err))
+		return err
 	}
-	// если произошёл cache-miss
-	if rv == nil {
-		baseCurrency, err := s.UsersDB.GetUserBaseCurrency(ctx, domain.User{UserID: userID})
-		if err != nil {
-			logger.Warn("GetExpencesMap storage error:", zap.Error(err))
-			return nil
-		}
 
-		baseCurrency, err = s.CurrunciesDB.GetCurrencyRate(ctx, domain.Currency{ID: baseCurrency.ID})
-		if err != nil {
-			logger.Warn("GetExpencesMap storage error:", zap.Error(err))
-			return nil
-		}
+	if err := s.CategoriesDB.DeleteCategory(ctx, category); err != nil {
+		logger.Warn("DeleteCategory storage error:", zap.Error(err))
+		return err
+	}
 
-		rv = make(map[string]int64, 0)
-		rvForCache := make(map[string]string, 0)
+	return nil
+}
 
-		//expences, err := s.ExpencesDB.GetUserExpences(ctx, domain.User{UserID: userID}, limitTs)
+// SetCategoryLimit - лимит категории на месяц; сумма задана в базовой валюте пользователя
+func (s *Storage) SetCategoryLimit(ctx context.Context, userID int64, cat string, total int64) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "set_category_limit_storage")
+	defer span.Finish()
 
-		s.ReportReq.GetReportRequestChan() <- domain.ReportRequest{
-			UserID:    userID,
-			Timestamp: limitTs,
-		}
+	category, err := s.getCategory(ctx, userID, cat)
+	if err != nil {
+		return err
+	}
 
-		var expences []domain.Expence
+	rate, err := s.getCurrencyRate(ctx, userID, "", time.Now())
+	if err != nil {
+		logger.Warn("SetCategoryLimit storage error:", zap.Error(err))
+		return err
+	}
 
-		select {
-		case <-ctx.Done():
-			return nil
-		case expences = <-s.ExpencesGetterObj.GetReportExpencesChan():
-			//
-		}
+	category.DefaultMonthLimit = int64(float64(total) / rate)
+	if err := s.CategoriesDB.SetCategoryLimit(ctx, category); err != nil {
+		logger.Warn("SetCategoryLimit storage error:", zap.Error(err))
+		return err
+	}
+	return nil
+}
 
-		if err != nil {
-			logger.Warn("GetExpencesMap storage error:", zap.Error(err))
-			return rv
-		}
-		for _, val := range expences {
-			rv[val.CategoryName] += int64(float64(val.Total) * baseCurrency.Rate)
-			rvForCache[val.CategoryName] = strconv.FormatInt(rv[val.CategoryName], 10)
-		}
-		logger.Info("report", zap.String("size", strconv.Itoa(len(rv))))
-		err = s.ReportCDB.SetUserExpences(ctx, domain.User{UserID: userID}, rvForCache, limitTs)
-		if err != nil {
-			logger.Warn("SetExpencesMap storage error:", zap.Error(err))
-		}
+// AddImportRule - правило импорта выписки: строки с keyword в описании попадают в категорию cat
+func (s *Storage) AddImportRule(ctx context.Context, userID int64, keyword string, cat string) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "add_import_rule_storage")
+	defer span.Finish()
+
+	category, err := s.getCategory(ctx, userID, cat)
+	if err != nil {
+		return err
 	}
 
-	return rv
+	err = s.CategoriesDB.AddCategoryKeyword(ctx, domain.CategoryKeyword{
+		CategoryID: category.ID,
+		Keyword:    strings.ToLower(keyword),
+	})
+	if err != nil {
+		logger.Warn("AddImportRule storage error:", zap.Error(err))
+		return err
+	}
+	return nil
 }
 
-func (s *Storage) SetUserLimit(ctx context.Context, userID int64, total int64) error {
-	span, ctx := opentracing.StartSpanFromContext(ctx, "set_user_limit_storage")
+func (s *Storage) GetImportRules(ctx context.Context, userID int64) ([]domain.CategoryKeyword, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_import_rules_storage")
 	defer span.Finish()
 
-	baseCurrency, err := s.UsersDB.GetUserBaseCurrency(ctx, domain.User{UserID: userID})
+	keywords, err := s.CategoriesDB.GetUserCategoryKeywords(ctx, domain.User{UserID: userID})
 	if err != nil {
-		logger.Warn("SetUserLimit storage error:", zap.Error(err))
-		return nil
+		logger.Warn("GetImportRules storage error:", zap.Error(err))
+		return nil, err
 	}
+	return keywords, nil
+}
+
+func (s *Storage) getCategory(ctx context.Context, userID int64, cat string) (domain.ExpenceCategory, error) {
+	category := domain.ExpenceCategory{UserID: userID, Name: cat}
 
-	baseCurrency, err = s.CurrunciesDB.GetCurrencyRate(ctx, domain.Currency{ID: baseCurrency.ID})
+	categoryID, err := s.CategoriesDB.IsCategoryExists(ctx, category)
 	if err != nil {
-		logger.Warn("SetUserLimit storage error:", zap.Error(err))
-		return nil
+		logger.Warn("get category storage error:", zap.Error(err))
+		return category, common.ErrCategoryNotFound
 	}
 
-	return s.UsersDB.SetUserLimit(ctx, domain.User{
-		UserID:            userID,
-		DefaultMonthLimit: int64(float64(total) / baseCurrency.Rate),
-		CurrentMonthLimit: int64(float64(total) / baseCurrency.Rate),
-	})
+	category.ID = categoryID
+	return category, nil
 }
 
-func (s *Storage) ResetUserLimit(ctx context.Context, userID int64) error {
-	return s.UsersDB.ResetUserLimit(ctx, domain.User{UserID: userID})
-}
+// AddExpence - сохранение траты в бюджет userID участником memberID;
+// сумма задана в currency, либо в базовой валюте бюджета, если currency пустая
+func (s *Storage) AddExpence(ctx context.Context, userID int64, memberID int64, cat string, total int64, currency string, date time.Time, note string, tags []string) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "add_expence_storage")
+	defer span.Finish()
 
-func (s *Storage) WaitNewExchangeRates(ctx context.Context, wg *sync.WaitGroup, ch <-chan []domain.Currency) {
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
+	id, err := s.addExpence(ctx, userID, memberID, cat, total, currency, date, note, tags)
+	if err != nil {
+		return err
+	}
 
-		for {
-			select {
-			case res := <-ch:
-				func() {
-					storageCtx, cancel := context.WithCancel(ctx)
-					defer cancel()
+	s.addOperation(ctx, domain.Operation{UserID: userID, MemberID: memberID, Kind: domain.OperationAddExpence, ObjectID: id})
+	return nil
+}
 
-					if err := s.CurrunciesDB.UpdateRates(storageCtx, res); err != nil {
-						logger.Error("SetUserLimit storage error:", zap.Error(err))
-					}
-				}()
-			case <-ctx.Done():
-				logger.Info("Stopping listening to rate service...")
-				return
-			}
-		}
-	}()
+// PostRecurringExpence - списание регулярной траты владельцем бюджета; в журнал /undo не попадает -
+// это не изменение, сделанное участником
+func (s *Storage) PostRecurringExpence(ctx context.Context, expence domain.RecurringExpence, date time.Time) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "post_recurring_expence_storage")
+	defer span.Finish()
+
+	_, err := s.addExpence(ctx, expence.UserID, expence.UserID, expence.CategoryName, expence.Total, "", date, "", nil)
+	return err
 }
 
-func (s *Storage) WaitNewMonthLimit(ctx context.Context, wg *sync.WaitGroup, ch <-chan struct{}) {
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
+// addExpence - сохранение траты без записи в журнал; возвращает id траты
+func (s *Storage) addExpence(ctx context.Context, userID int64, memberID int64, cat string, total int64, currency string, date time.Time, note string, tags []string) (int64, error) {
+	categoryID, err := s.CategoriesDB.IsCategoryExists(ctx, domain.ExpenceCategory{
+		UserID: userID,
+		Name:   cat,
+	})
+	if err != nil {
+		logger.Warn("AddExpence storage error:", zap.Error(err))
+		return 0, err
+	}
 
-		for {
-			select {
-			case <-ch:
-				func() {
-					storageCtx, cancel := context.WithCancel(ctx)
-					defer cancel()
+	// сумма сохраняется в системной валюте по курсу на день траты, введённые валюта и сумма - рядом с ней
+	expenceCurrency, err := s.getCurrency(ctx, userID, currency, date)
+	if err != nil {
+		logger.Warn("AddExpence storage error:", zap.Error(err))
+		return 0, err
+	}
 
-					if err := s.UsersDB.UpdateMonthLimits(storageCtx); err != nil {
-						logger.Error("Update month limits error:", zap.Error(err))
-					}
-				}()
-			case <-ctx.Done():
-				logger.Info("Stopping listening to limit updater service...")
-				return
-			}
-		}
-	}()
+	expence := domain.Expence{
+		UserID:        userID,
+		CategoryID:    categoryID,
+		Timestamp:     date,
+		Total:         int64(float64(total) / expenceCurrency.Rate),
+		CurrencyID:    expenceCurrency.ID,
+		OriginalTotal: total,
+		Note:          note,
+		Tags:          tags,
+		AddedBy:       memberID,
+	}
+
+	id, err := s.ExpencesDB.AddExpence(ctx, expence)
+	if err != nil {
+		logger.Warn("AddExpence storage error:", zap.Error(err))
+		return 0, err
+	}
+
+	err = s.ReportCDB.DeleteUserReports(ctx, domain.User{UserID: userID})
+	if err != nil {
+		logger.Warn("AddExpence storage error:", zap.Error(err))
+	}
+
+	return id, nil
+}
+
+// ImportExpences - добавление трат из выписки участника memberID; категории заданы именем, суммы - в базовой валюте бюджета.
+// Возвращает количество добавленных трат, уже импортированные строки пропускаются
+func (s *Storage) ImportExpences(ctx context.Context, userID int64, memberID int64, expences []domain.Expence) (int64, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "import_expences_storage")
+	defer span.Finish()
+
+	categories, err := s.CategoriesDB.GetUserCategories(ctx, domain.User{UserID: userID})
+	if err != nil {
+		logger.Warn("ImportExpences storage error:", zap.Error(err))
+		return 0, err
+	}
+	categoryIDs := make(map[string]int64, len(categories))
+	for _, category := range categories {
+		categoryIDs[category.Name] = category.ID
+	}
+
+	// курс базовой валюты на день каждой траты, по одному запросу на день
+	baseCurrencies := make(map[time.Time]domain.Currency)
+
+	rows := make([]domain.Expence, 0, len(expences))
+	for _, expence := range expences {
+		categoryID, found := categoryIDs[expence.CategoryName]
+		if !found {
+			return 0, common.ErrCategoryNotFound
+		}
+		day := time.Date(expence.Timestamp.Year(), expence.Timestamp.Month(), expence.Timestamp.Day(), 0, 0, 0, 0, expence.Timestamp.Location())
+		baseCurrency, found := baseCurrencies[day]
+		if !found {
+			baseCurrency, err = s.getCurrency(ctx, userID, "", day)
+			if err != nil {
+				logger.Warn("ImportExpences storage error:", zap.Error(err))
+				return 0, err
+			}
+			baseCurrencies[day] = baseCurrency
+		}
+		expence.CategoryID = categoryID
+		expence.CurrencyID = baseCurrency.ID
+		expence.OriginalTotal = expence.Total
+		expence.Total = int64(float64(expence.Total) / baseCurrency.Rate)
+		expence.AddedBy = memberID
+		rows = append(rows, expence)
+	}
+
+	added, err := s.ExpencesDB.ImportExpences(ctx, domain.User{UserID: userID}, rows)
+	if err != nil {
+		logger.Warn("ImportExpences storage error:", zap.Error(err))
+		return 0, err
+	}
+
+	err = s.ReportCDB.DeleteUserReports(ctx, domain.User{UserID: userID})
+	if err != nil {
+		logger.Warn("ImportExpences storage error:", zap.Error(err))
+	}
+
+	return added, nil
+}
+
+// ListExpences - страница трат пользователя, суммы в базовой валюте пользователя
+func (s *Storage) ListExpences(ctx context.Context, userID int64, offset uint64, limit uint64) ([]domain.Expence, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "list_expences_storage")
+	defer span.Finish()
+
+	baseCurrency, err := s.getCurrency(ctx, userID, "", time.Now())
+	if err != nil {
+		logger.Warn("ListExpences storage error:", zap.Error(err))
+		return nil, err
+	}
+
+	expences, err := s.ExpencesDB.GetUserExpencesPage(ctx, domain.User{UserID: userID}, offset, limit)
+	if err != nil {
+		logger.Warn("ListExpences storage error:", zap.Error(err))
+		return nil, err
+	}
+
+	hideBaseOriginals(expences, baseCurrency)
+	return expences, nil
+}
+
+// SearchExpences - последние limit трат с text в комментарии, суммы в базовой валюте пользователя
+func (s *Storage) SearchExpences(ctx context.Context, userID int64, text string, limit uint64) ([]domain.Expence, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "search_expences_storage")
+	defer span.Finish()
+
+	baseCurrency, err := s.getCurrency(ctx, userID, "", time.Now())
+	if err != nil {
+		logger.Warn("SearchExpences storage error:", zap.Error(err))
+		return nil, err
+	}
+
+	expences, err := s.ExpencesDB.SearchUserExpences(ctx, domain.User{UserID: userID}, text, limit)
+	if err != nil {
+		logger.Warn("SearchExpences storage error:", zap.Error(err))
+		return nil, err
+	}
+
+	hideBaseOriginals(expences, baseCurrency)
+	return expences, nil
+}
+
+// GetCurrencyTotals - суммы трат, введённых не в базовой валюте пользователя: исходная сумма
+// по каждой валюте и она же в базовой валюте
+func (s *Storage) GetCurrencyTotals(ctx context.Context, userID int64, startTs time.Time, endTs time.Time) ([]domain.Expence, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_currency_totals_storage")
+	defer span.Finish()
+
+	baseCurrency, err := s.getCurrency(ctx, userID, "", reportRateDate(endTs))
+	if err != nil {
+		logger.Warn("GetCurrencyTotals storage error:", zap.Error(err))
+		return nil, err
+	}
+
+	totals, err := s.ExpencesDB.GetUserCurrencyTotals(ctx, domain.User{UserID: userID}, startTs, endTs)
+	if err != nil {
+		logger.Warn("GetCurrencyTotals storage error:", zap.Error(err))
+		return nil, err
+	}
+
+	rv := make([]domain.Expence, 0, len(totals))
+	for _, total := range totals {
+		if total.CurrencyID == baseCurrency.ID {
+			continue
+		}
+		total.Total = int64(float64(total.Total) * baseCurrency.Rate)
+		rv = append(rv, total)
+	}
+	return rv, nil
+}
+
+// GetTagExpencesMap - суммы трат с меткой tag по категориям в базовой валюте пользователя;
+// отчёты по меткам строятся запросом к базе, без генератора отчётов и кэша
+func (s *Storage) GetTagExpencesMap(ctx context.Context, userID int64, tag string, startTs time.Time, endTs time.Time) (map[string]int64, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_tag_expences_map_storage")
+	defer span.Finish()
+
+	rate, err := s.getCurrencyRate(ctx, userID, "", reportRateDate(endTs))
+	if err != nil {
+		logger.Warn("GetTagExpencesMap storage error:", zap.Error(err))
+		return nil, err
+	}
+
+	expences, err := s.ExpencesDB.GetUserTagTotals(ctx, domain.User{UserID: userID}, tag, startTs, endTs)
+	if err != nil {
+		logger.Warn("GetTagExpencesMap storage error:", zap.Error(err))
+		return nil, err
+	}
+
+	rv := make(map[string]int64, len(expences))
+	for _, expence := range expences {
+		rv[expence.CategoryName] += int64(float64(expence.Total) * rate)
+	}
+	return rv, nil
+}
+
+// EditExpence - замена категории, суммы и даты траты; сумма задана в базовой валюте пользователя
+func (s *Storage) EditExpence(ctx context.Context, userID int64, expenceID int64, cat string, total int64, date time.Time) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "edit_expence_storage")
+	defer span.Finish()
+
+	categoryID, err := s.CategoriesDB.IsCategoryExists(ctx, domain.ExpenceCategory{
+		UserID: userID,
+		Name:   cat,
+	})
+	if err != nil {
+		logger.Warn("EditExpence storage error:", zap.Error(err))
+		return err
+	}
+
+	// новая сумма задаётся в базовой валюте, поэтому и введённые валюта и сумма заменяются ею
+	baseCurrency, err := s.getCurrency(ctx, userID, "", date)
+	if err != nil {
+		logger.Warn("EditExpence storage error:", zap.Error(err))
+		return err
+	}
+
+	err = s.ExpencesDB.UpdateExpence(ctx, domain.Expence{
+		ID:            expenceID,
+		UserID:        userID,
+		CategoryID:    categoryID,
+		Timestamp:     date,
+		Total:         int64(float64(total) / baseCurrency.Rate),
+		CurrencyID:    baseCurrency.ID,
+		OriginalTotal: total,
+	})
+	if err != nil {
+		logger.Warn("EditExpence storage error:", zap.Error(err))
+		return err
+	}
+
+	err = s.ReportCDB.DeleteUserReports(ctx, domain.User{UserID: userID})
+	if err != nil {
+		logger.Warn("EditExpence storage error:", zap.Error(err))
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteExpence(ctx context.Context, userID int64, expenceID int64) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "delete_expence_storage")
+	defer span.Finish()
+
+	err := s.ExpencesDB.DeleteExpence(ctx, domain.Expence{ID: expenceID, UserID: userID})
+	if err != nil {
+		logger.Warn("DeleteExpence storage error:", zap.Error(err))
+		return err
+	}
+
+	err = s.ReportCDB.DeleteUserReports(ctx, domain.User{UserID: userID})
+	if err != nil {
+		logger.Warn("DeleteExpence storage error:", zap.Error(err))
+	}
+
+	return nil
+}
+
+// getCurrencyRate - курс валюты currency на день date, либо базовой валюты пользователя, если currency пустая
+func (s *Storage) getCurrencyRate(ctx context.Context, userID int64, currency string, date time.Time) (float64, error) {
+	baseCurrency, err := s.getCurrency(ctx, userID, currency, date)
+	if err != nil {
+		return 0, err
+	}
+	return baseCurrency.Rate, nil
+}
+
+// getCurrency - id и курс на день date валюты currency, либо базовой валюты пользователя, если currency пустая
+func (s *Storage) getCurrency(ctx context.Context, userID int64, currency string, date time.Time) (domain.Currency, error) {
+	var baseCurrency domain.Currency
+	var err error
+	if currency != "" {
+		baseCurrency, err = s.CurrunciesDB.IsCurrencyExists(ctx, domain.Currency{Code: currency})
+		if err == sql.ErrNoRows {
+			return baseCurrency, common.ErrCurrencyNotFound
+		}
+	} else {
+		baseCurrency, err = s.UsersDB.GetUserBaseCurrency(ctx, domain.User{UserID: userID})
+	}
+	if err != nil {
+		return baseCurrency, err
+	}
+
+	return s.CurrunciesDB.GetCurrencyRateAt(ctx, domain.Currency{ID: baseCurrency.ID}, date)
+}
+
+// reportRateDate - день курса для отчёта за период, заканчивающийся endTs: последний день периода,
+// а для ещё не закончившегося периода - сегодня. Так отчёт за прошлый период не меняется вместе с курсом
+func reportRateDate(endTs time.Time) time.Time {
+	now := time.Now()
+	if endTs.After(now) {
+		return now
+	}
+	return endTs.Add(-time.Nanosecond)
+}
+
+// hideBaseOriginals - пересчёт сумм в базовую валюту пользователя; исходная сумма остаётся
+// только у трат, введённых в другой валюте
+func hideBaseOriginals(expences []domain.Expence, baseCurrency domain.Currency) {
+	for i := range expences {
+		expences[i].Total = int64(float64(expences[i].Total) * baseCurrency.Rate)
+		if expences[i].CurrencyID == baseCurrency.ID {
+			expences[i].CurrencyCode = ""
+			expences[i].OriginalTotal = 0
+		}
+	}
+}
+
+// AddRecurringExpence - новое расписание; сумма задана в базовой валюте пользователя на момент списания
+func (s *Storage) AddRecurringExpence(ctx context.Context, userID int64, cat string, total int64, schedule string, nextTs time.Time) (int64, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "add_recurring_expence_storage")
+	defer span.Finish()
+
+	category, err := s.getCategory(ctx, userID, cat)
+	if err != nil {
+		return -1, err
+	}
+
+	id, err := s.RecurringDB.AddRecurringExpence(ctx, domain.RecurringExpence{
+		UserID:        userID,
+		CategoryID:    category.ID,
+		Total:         total,
+		Schedule:      schedule,
+		NextTimestamp: nextTs,
+	})
+	if err != nil {
+		logger.Warn("AddRecurringExpence storage error:", zap.Error(err))
+		return -1, err
+	}
+	return id, nil
+}
+
+func (s *Storage) GetRecurringExpences(ctx context.Context, userID int64) ([]domain.RecurringExpence, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_recurring_expences_storage")
+	defer span.Finish()
+
+	expences, err := s.RecurringDB.GetUserRecurringExpences(ctx, domain.User{UserID: userID})
+	if err != nil {
+		logger.Warn("GetRecurringExpences storage error:", zap.Error(err))
+		return nil, err
+	}
+	return expences, nil
+}
+
+// GetDueRecurringExpences - расписания всех пользователей, по которым пора списать трату
+func (s *Storage) GetDueRecurringExpences(ctx context.Context, ts time.Time) ([]domain.RecurringExpence, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_due_recurring_expences_storage")
+	defer span.Finish()
+
+	expences, err := s.RecurringDB.GetDueRecurringExpences(ctx, ts)
+	if err != nil {
+		logger.Warn("GetDueRecurringExpences storage error:", zap.Error(err))
+		return nil, err
+	}
+	return expences, nil
+}
+
+// SetRecurringExpenceState - пауза/возобновление расписания и время следующего списания
+func (s *Storage) SetRecurringExpenceState(ctx context.Context, userID int64, id int64, paused bool, nextTs time.Time) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "set_recurring_expence_state_storage")
+	defer span.Finish()
+
+	err := s.RecurringDB.UpdateRecurringExpence(ctx, domain.RecurringExpence{
+		ID:            id,
+		UserID:        userID,
+		Paused:        paused,
+		NextTimestamp: nextTs,
+	})
+	if err != nil {
+		logger.Warn("SetRecurringExpenceState storage error:", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (s *Storage) DeleteRecurringExpence(ctx context.Context, userID int64, id int64) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "delete_recurring_expence_storage")
+	defer span.Finish()
+
+	err := s.RecurringDB.DeleteRecurringExpence(ctx, domain.RecurringExpence{ID: id, UserID: userID})
+	if err != nil {
+		logger.Warn("DeleteRecurringExpence storage error:", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// AddIncome - сохранение дохода; сумма задана в базовой валюте пользователя
+func (s *Storage) AddIncome(ctx context.Context, userID int64, source string, total int64, date time.Time) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "add_income_storage")
+	defer span.Finish()
+
+	rate, err := s.getCurrencyRate(ctx, userID, "", date)
+	if err != nil {
+		logger.Warn("AddIncome storage error:", zap.Error(err))
+		return err
+	}
+
+	err = s.IncomesDB.AddIncome(ctx, domain.Income{
+		UserID:    userID,
+		Source:    source,
+		Timestamp: date,
+		Total:     int64(float64(total) / rate),
+	})
+	if err != nil {
+		logger.Warn("AddIncome storage error:", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetIncomeTotal - сумма доходов за период [startTs, endTs) в базовой валюте пользователя
+func (s *Storage) GetIncomeTotal(ctx context.Context, userID int64, startTs time.Time, endTs time.Time) (int64, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_income_total_storage")
+	defer span.Finish()
+
+	rate, err := s.getCurrencyRate(ctx, userID, "", reportRateDate(endTs))
+	if err != nil {
+		logger.Warn("GetIncomeTotal storage error:", zap.Error(err))
+		return 0, err
+	}
+
+	total, err := s.IncomesDB.GetUserIncomeTotal(ctx, domain.User{UserID: userID}, startTs, endTs)
+	if err != nil {
+		logger.Warn("GetIncomeTotal storage error:", zap.Error(err))
+		return 0, err
+	}
+	return int64(float64(total) * rate), nil
+}
+
+// GetExpenceRows - траты за период [startTs, endTs) в хранимой валюте
+// и курс для пересчёта в базовую валюту пользователя
+func (s *Storage) GetExpenceRows(ctx context.Context, userID int64, startTs time.Time, endTs time.Time) ([]domain.Expence, float64, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_expence_rows_storage")
+	defer span.Finish()
+
+	rate, err := s.getCurrencyRate(ctx, userID, "", reportRateDate(endTs))
+	if err != nil {
+		logger.Warn("GetExpenceRows storage error:", zap.Error(err))
+		return nil, 0, err
+	}
+
+	expences, err := s.ExpencesDB.GetUserExpenceRows(ctx, domain.User{UserID: userID}, startTs, endTs)
+	if err != nil {
+		logger.Warn("GetExpenceRows storage error:", zap.Error(err))
+		return nil, 0, err
+	}
+	return expences, rate, nil
+}
+
+// GetDailyExpences - суммы трат по дням за период [startTs, endTs) в базовой валюте пользователя
+func (s *Storage) GetDailyExpences(ctx context.Context, userID int64, startTs time.Time, endTs time.Time) ([]domain.Expence, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_daily_expences_storage")
+	defer span.Finish()
+
+	rate, err := s.getCurrencyRate(ctx, userID, "", reportRateDate(endTs))
+	if err != nil {
+		logger.Warn("GetDailyExpences storage error:", zap.Error(err))
+		return nil, err
+	}
+
+	expences, err := s.ExpencesDB.GetUserDailyTotals(ctx, domain.User{UserID: userID}, startTs, endTs)
+	if err != nil {
+		logger.Warn("GetDailyExpences storage error:", zap.Error(err))
+		return nil, err
+	}
+
+	for i := range expences {
+		expences[i].Total = int64(float64(expences[i].Total) * rate)
+	}
+	return expences, nil
+}
+
+// GetExpencesMap - траты по категориям за период [startTs, endTs) в базовой валюте пользователя
+func (s *Storage) GetExpencesMap(ctx context.Context, userID int64, startTs time.Time, endTs time.Time) map[string]int64 {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_expence_map_storage")
+	defer span.Finish()
+
+	// обращение к кэшу за отчётом по пользователю
+	rv, err := s.ReportCDB.GetUserExpences(ctx, domain.User{UserID: userID}, startTs, endTs)
+	if err != nil {
+		logger.Warn("cache get report error", zap.Error(err))
+	}
+	// если произошёл cache-miss
+	if rv == nil {
+		baseCurrency, err := s.getCurrency(ctx, userID, "", reportRateDate(endTs))
+		if err != nil {
+			logger.Warn("GetExpencesMap storage error:", zap.Error(err))
+			return nil
+		}
+
+		rv = make(map[string]int64, 0)
+		rvForCache := make(map[string]string, 0)
+
+		//expences, err := s.ExpencesDB.GetUserExpences(ctx, domain.User{UserID: userID}, startTs, endTs)
+
+		// ответ генератора отчётов сопоставляется с запросом по request id,
+		// чтобы параллельные запросы разных пользователей не перепутались
+		requestID := atomic.AddInt64(&s.lastRequestID, 1)
+		reportChan := s.ExpencesGetterObj.WaitReportExpences(requestID)
+		defer s.ExpencesGetterObj.CancelReportExpences(requestID)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case s.ReportReq.GetReportRequestChan() <- domain.ReportRequest{
+			RequestID:    requestID,
+			UserID:       userID,
+			Timestamp:    startTs,
+			EndTimestamp: endTs,
+		}:
+			//
+		}
+
+		var expences []domain.Expence
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case expences = <-reportChan:
+			//
+		}
+
+		if err != nil {
+			logger.Warn("GetExpencesMap storage error:", zap.Error(err))
+			return rv
+		}
+		for _, val := range expences {
+			rv[val.CategoryName] += int64(float64(val.Total) * baseCurrency.Rate)
+			rvForCache[val.CategoryName] = strconv.FormatInt(rv[val.CategoryName], 10)
+		}
+		logger.Info("report", zap.String("size", strconv.Itoa(len(rv))))
+		err = s.ReportCDB.SetUserExpences(ctx, domain.User{UserID: userID}, rvForCache, startTs, endTs)
+		if err != nil {
+			logger.Warn("SetExpencesMap storage error:", zap.Error(err))
+		}
+	}
+
+	return rv
+}
+
+func (s *Storage) SetUserLimit(ctx context.Context, userID int64, memberID int64, total int64) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "set_user_limit_storage")
+	defer span.Finish()
+
+	baseCurrency, err := s.getCurrency(ctx, userID, "", time.Now())
+	if err != nil {
+		logger.Warn("SetUserLimit storage error:", zap.Error(err))
+		return nil
+	}
+
+	prev, err := s.UsersDB.GetUserLimits(ctx, domain.User{UserID: userID})
+	if err != nil {
+		logger.Warn("SetUserLimit storage error:", zap.Error(err))
+		return err
+	}
+
+	limit := int64(float64(total) / baseCurrency.Rate)
+	err = s.UsersDB.SetUserLimit(ctx, domain.User{
+		UserID:            userID,
+		DefaultMonthLimit: limit,
+		CurrentMonthLimit: limit,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.addOperation(ctx, domain.Operation{
+		UserID:          userID,
+		MemberID:        memberID,
+		Kind:            domain.OperationMonthLimit,
+		MonthLimit:      prev.DefaultMonthLimit,
+		MonthLimitDelta: limit - prev.CurrentMonthLimit,
+	})
+	return nil
+}
+
+func (s *Storage) ResetUserLimit(ctx context.Context, userID int64, memberID int64) error {
+	prev, err := s.UsersDB.GetUserLimits(ctx, domain.User{UserID: userID})
+	if err != nil {
+		logger.Warn("ResetUserLimit storage error:", zap.Error(err))
+		return err
+	}
+
+	if err := s.UsersDB.ResetUserLimit(ctx, domain.User{UserID: userID}); err != nil {
+		return err
+	}
+
+	s.addOperation(ctx, domain.Operation{
+		UserID:          userID,
+		MemberID:        memberID,
+		Kind:            domain.OperationMonthLimit,
+		MonthLimit:      prev.DefaultMonthLimit,
+		MonthLimitDelta: prev.DefaultMonthLimit - prev.CurrentMonthLimit,
+	})
+	return nil
+}
+
+// GetLimitAlerts - пороги уведомлений в процентах лимита месяца
+func (s *Storage) GetLimitAlerts(ctx context.Context, userID int64) ([]int64, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_limit_alerts_storage")
+	defer span.Finish()
+
+	user, err := s.UsersDB.GetLimitAlerts(ctx, domain.User{UserID: userID})
+	if err != nil {
+		logger.Warn("GetLimitAlerts storage error:", zap.Error(err))
+		return nil, err
+	}
+	return user.LimitAlerts, nil
+}
+
+func (s *Storage) SetLimitAlerts(ctx context.Context, userID int64, alerts []int64) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "set_limit_alerts_storage")
+	defer span.Finish()
+
+	if err := s.UsersDB.SetLimitAlerts(ctx, domain.User{UserID: userID, LimitAlerts: alerts}); err != nil {
+		logger.Warn("SetLimitAlerts storage error:", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// TakeLimitAlert - наибольший порог уведомлений, впервые в этом месяце достигнутый тратами, и превышение лимита месяца.
+// Порог сразу отмечается сработавшим в базе, так уведомление приходит раз в месяц и не повторяется после перезапуска
+func (s *Storage) TakeLimitAlert(ctx context.Context, userID int64) (domain.LimitAlert, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "take_limit_alert_storage")
+	defer span.Finish()
+
+	var alert domain.LimitAlert
+
+	user, err := s.UsersDB.GetLimitAlerts(ctx, domain.User{UserID: userID})
+	if err != nil {
+		logger.Warn("TakeLimitAlert storage error:", zap.Error(err))
+		return alert, err
+	}
+
+	alert.Overspend, err = s.overspend(ctx, user)
+	if err != nil {
+		logger.Warn("TakeLimitAlert storage error:", zap.Error(err))
+		return alert, err
+	}
+
+	threshold := reachedLimitAlert(user)
+	if threshold == 0 {
+		return alert, nil
+	}
+
+	// уведомление о пороге могла уже забрать параллельно добавленная трата
+	fired, err := s.UsersDB.FireLimitAlert(ctx, domain.User{UserID: userID, LimitAlertFired: threshold})
+	if err != nil {
+		logger.Warn("TakeLimitAlert storage error:", zap.Error(err))
+		return alert, err
+	}
+	if fired {
+		alert.Threshold = threshold
+	}
+	return alert, nil
+}
+
+// GetOverspend - на сколько превышены лимиты календарных месяцев, которые затрагивает период [startTs, endTs),
+// в базовой валюте; 0 - не превышены
+func (s *Storage) GetOverspend(ctx context.Context, userID int64, startTs time.Time, endTs time.Time) (int64, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_overspend_storage")
+	defer span.Finish()
+
+	user, _ := s.GetUserBudget(ctx, userID)
+	overspend, err := s.UsersDB.GetOverspend(ctx, domain.User{UserID: userID}, helpers.StartOfMonth(startTs), endTs, helpers.LimitMonth(time.Now(), user.Timezone))
+	if err != nil {
+		logger.Warn("GetOverspend storage error:", zap.Error(err))
+		return 0, err
+	}
+	if overspend == 0 {
+		return 0, nil
+	}
+
+	// лимиты хранятся в системной валюте, курс - как у остального отчёта
+	baseCurrency, err := s.getCurrency(ctx, userID, "", reportRateDate(endTs))
+	if err != nil {
+		logger.Warn("GetOverspend storage error:", zap.Error(err))
+		return 0, err
+	}
+	return int64(float64(overspend) * baseCurrency.Rate), nil
+}
+
+// overspend - превышение лимита месяца в базовой валюте; лимиты хранятся в системной валюте,
+// поэтому курс запрашивается только при превышении
+func (s *Storage) overspend(ctx context.Context, user domain.User) (int64, error) {
+	if user.DefaultMonthLimit <= 0 || user.CurrentMonthLimit >= 0 {
+		return 0, nil
+	}
+
+	baseCurrency, err := s.getCurrency(ctx, user.UserID, "", time.Now())
+	if err != nil {
+		return 0, err
+	}
+	return int64(float64(-user.CurrentMonthLimit) * baseCurrency.Rate), nil
+}
+
+// GetLimitMode - true - мягкий режим лимита, траты сверх лимита сохраняются с предупреждением
+func (s *Storage) GetLimitMode(ctx context.Context, userID int64) (bool, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "get_limit_mode_storage")
+	defer span.Finish()
+
+	user, err := s.UsersDB.GetLimitMode(ctx, domain.User{UserID: userID})
+	if err != nil {
+		logger.Warn("GetLimitMode storage error:", zap.Error(err))
+		return false, err
+	}
+	return user.SoftLimit, nil
+}
+
+func (s *Storage) SetLimitMode(ctx context.Context, userID int64, soft bool) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "set_limit_mode_storage")
+	defer span.Finish()
+
+	if err := s.UsersDB.SetLimitMode(ctx, domain.User{UserID: userID, SoftLimit: soft}); err != nil {
+		logger.Warn("SetLimitMode storage error:", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// reachedLimitAlert - наибольший достигнутый порог, о котором ещё не уведомляли; 0 - такого нет
+func reachedLimitAlert(user domain.User) int64 {
+	if user.DefaultMonthLimit <= 0 {
+		return 0
+	}
+	spent := (user.DefaultMonthLimit - user.CurrentMonthLimit) * 100 / user.DefaultMonthLimit
+
+	var rv int64
+	for _, threshold := range user.LimitAlerts {
+		if threshold > user.LimitAlertFired && threshold <= spent && threshold > rv {
+			rv = threshold
+		}
+	}
+	return rv
+}
+
+// addOperation - запись изменения в журнал для /undo; без записи изменение остаётся в силе,
+// поэтому ошибка журнала только логируется
+func (s *Storage) addOperation(ctx context.Context, operation domain.Operation) {
+	if err := s.OperationsDB.AddOperation(ctx, operation); err != nil {
+		logger.Warn("AddOperation storage error:", zap.Error(err))
+	}
+}
+
+// Undo - отмена последнего изменения бюджета userID, сделанного участником memberID, по журналу: добавления траты
+// или категории, изменения лимита месяца или смены валюты. Возвращает отменённое изменение
+func (s *Storage) Undo(ctx context.Context, userID int64, memberID int64) (domain.Operation, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "undo_storage")
+	defer span.Finish()
+
+	operation, err := s.OperationsDB.GetLastOperation(ctx, domain.Operation{UserID: userID, MemberID: memberID})
+	if err != nil {
+		if err != common.ErrNothingToUndo {
+			logger.Warn("Undo storage error:", zap.Error(err))
+		}
+		return operation, err
+	}
+
+	switch operation.Kind {
+	case domain.OperationAddExpence:
+		// остаток лимита месяца и категории возвращается вместе с удалением траты
+		err = s.ExpencesDB.DeleteExpence(ctx, domain.Expence{ID: operation.ObjectID, UserID: userID})
+	case domain.OperationAddCategory:
+		err = s.CategoriesDB.DeleteCategory(ctx, domain.ExpenceCategory{ID: operation.ObjectID, UserID: userID})
+	case domain.OperationMonthLimit:
+		err = s.UsersDB.UndoUserLimit(ctx, operation)
+	case domain.OperationChangeCurrency:
+		err = s.UsersDB.ChangeCurrency(ctx, domain.User{UserID: userID}, domain.Currency{ID: int(operation.ObjectID)})
+	}
+	// трату уже удалили, либо в категорию уже добавили траты или расписания - такое изменение не отменить,
+	// запись всё равно убирается из журнала, чтобы следующий /undo отменял предыдущее изменение
+	if err != nil && err != common.ErrExpenceNotFound && err != common.ErrCategoryNotEmpty && err != common.ErrCategoryHasRecurring {
+		logger.Warn("Undo storage error:", zap.Error(err))
+		return operation, err
+	}
+	undoErr := err
+
+	if err := s.OperationsDB.DeleteOperation(ctx, operation); err != nil {
+		logger.Warn("Undo storage error:", zap.Error(err))
+		return operation, err
+	}
+
+	if err := s.ReportCDB.DeleteUserReports(ctx, domain.User{UserID: userID}); err != nil {
+		logger.Warn("Undo storage error:", zap.Error(err))
+	}
+
+	return operation, undoErr
+}
+
+func (s *Storage) WaitNewExchangeRates(ctx context.Context, wg *sync.WaitGroup, ch <-chan []domain.Currency) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		for {
+			select {
+			case res := <-ch:
+				func() {
+					storageCtx, cancel := context.WithCancel(ctx)
+					defer cancel()
+
+					if err := s.CurrunciesDB.UpdateRates(storageCtx, res); err != nil {
+						logger.Error("SetUserLimit storage error:", zap.Error(err))
+					}
+				}()
+			case <-ctx.Done():
+				logger.Info("Stopping listening to rate service...")
+				return
+			}
+		}
+	}()
+}
+
+func (s *Storage) WaitNewMonthLimit(ctx context.Context, wg *sync.WaitGroup, ch <-chan struct{}) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		for {
+			select {
+			case <-ch:
+				func() {
+					storageCtx, cancel := context.WithCancel(ctx)
+					defer cancel()
+
+					s.updateMonthLimits(storageCtx, time.Now())
+				}()
+			case <-ctx.Done():
+				logger.Info("Stopping listening to limit updater service...")
+				return
+			}
+		}
+	}()
+}
+
+// updateMonthLimits - сброс лимитов у пользователей, у которых по их времени начался новый месяц
+func (s *Storage) updateMonthLimits(ctx context.Context, now time.Time) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "update_month_limits_storage")
+	defer span.Finish()
+
+	timezones, err := s.UsersDB.GetTimezones(ctx)
+	if err != nil {
+		logger.Error("Update month limits error:", zap.Error(err))
+		return
+	}
+
+	for _, timezone := range timezones {
+		if err := s.UsersDB.UpdateMonthLimits(ctx, timezone, helpers.LimitMonth(now, timezone)); err != nil {
+			logger.Error("Update month limits error:", zap.Error(err), zap.String("timezone", timezone))
+		}
+	}
 }
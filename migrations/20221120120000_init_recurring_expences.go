@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigration(upInitRecurringExpences, downInitRecurringExpences)
+}
+
+func upInitRecurringExpences(tx *sql.Tx) error {
+	const query = `
+	CREATE TABLE recurring_expences 
+	(
+		id bigint PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+		user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+		category_id bigint NOT NULL REFERENCES expence_category (id) ON DELETE CASCADE,
+		total bigint NOT NULL,
+		schedule text NOT NULL,
+		paused boolean NOT NULL DEFAULT false,
+		next_ts timestamp NOT NULL
+	);
+	-- планировщик раз в минуту выбирает активные расписания, срок которых наступил
+	CREATE INDEX recurring_next_ts_idx ON recurring_expences (next_ts) WHERE NOT paused;
+	`
+
+	_, err := tx.Exec(query)
+
+	return err
+}
+
+func downInitRecurringExpences(tx *sql.Tx) error {
+	const query = `
+	DROP INDEX recurring_next_ts_idx;
+	DROP TABLE recurring_expences;
+	`
+	_, err := tx.Exec(query)
+	return err
+}
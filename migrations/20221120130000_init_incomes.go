@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigration(upInitIncomes, downInitIncomes)
+}
+
+func upInitIncomes(tx *sql.Tx) error {
+	const query = `
+	CREATE TABLE incomes 
+	(
+		id bigint PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+		user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+		source text NOT NULL,
+		ts timestamp NOT NULL,
+		total bigint NOT NULL
+	);
+	CREATE INDEX incomes_ts_idx ON incomes (user_id, ts) INCLUDE (total);
+	`
+
+	_, err := tx.Exec(query)
+
+	return err
+}
+
+func downInitIncomes(tx *sql.Tx) error {
+	const query = `
+	DROP INDEX incomes_ts_idx;
+	DROP TABLE incomes;
+	`
+	_, err := tx.Exec(query)
+	return err
+}
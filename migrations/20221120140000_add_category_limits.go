@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigration(upAddCategoryLimits, downAddCategoryLimits)
+}
+
+func upAddCategoryLimits(tx *sql.Tx) error {
+	// NULL - лимит для категории не установлен
+	const query = `
+	ALTER TABLE expence_category
+		ADD COLUMN default_month_limit bigint,
+		ADD COLUMN current_month_limit bigint;
+	`
+
+	_, err := tx.Exec(query)
+
+	return err
+}
+
+func downAddCategoryLimits(tx *sql.Tx) error {
+	const query = `
+	ALTER TABLE expence_category
+		DROP COLUMN default_month_limit,
+		DROP COLUMN current_month_limit;
+	`
+	_, err := tx.Exec(query)
+	return err
+}
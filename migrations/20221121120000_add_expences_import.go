@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigration(upAddExpencesImport, downAddExpencesImport)
+}
+
+func upAddExpencesImport(tx *sql.Tx) error {
+	// import_hash NULL у трат, добавленных вручную; NULL не участвуют в уникальности
+	const query = `
+	ALTER TABLE expences ADD COLUMN import_hash text;
+	CREATE UNIQUE INDEX expences_import_hash_idx ON expences (user_id, import_hash);
+
+	CREATE TABLE category_keywords
+	(
+		id bigint PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+		category_id bigint NOT NULL REFERENCES expence_category (id) ON DELETE CASCADE,
+		keyword text NOT NULL,
+		UNIQUE (category_id, keyword)
+	);
+	`
+
+	_, err := tx.Exec(query)
+
+	return err
+}
+
+func downAddExpencesImport(tx *sql.Tx) error {
+	const query = `
+	DROP TABLE category_keywords;
+	DROP INDEX expences_import_hash_idx;
+	ALTER TABLE expences DROP COLUMN import_hash;
+	`
+	_, err := tx.Exec(query)
+	return err
+}
@@ -0,0 +1,44 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigration(upInitBudgets, downInitBudgets)
+}
+
+func upInitBudgets(tx *sql.Tx) error {
+	// id бюджета - id владельца; у владельца и пользователей без общего бюджета budget_id NULL
+	const query = `
+	CREATE TABLE budgets
+	(
+		id bigint PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+		invite_code text NOT NULL UNIQUE
+	);
+
+	ALTER TABLE users
+		ADD COLUMN budget_id bigint REFERENCES budgets (id) ON DELETE SET NULL,
+		ADD COLUMN name text;
+
+	ALTER TABLE expences ADD COLUMN added_by bigint REFERENCES users (id) ON DELETE SET NULL;
+	`
+
+	_, err := tx.Exec(query)
+
+	return err
+}
+
+func downInitBudgets(tx *sql.Tx) error {
+	const query = `
+	ALTER TABLE expences DROP COLUMN added_by;
+	ALTER TABLE users
+		DROP COLUMN budget_id,
+		DROP COLUMN name;
+	DROP TABLE budgets;
+	`
+	_, err := tx.Exec(query)
+	return err
+}
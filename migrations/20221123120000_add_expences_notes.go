@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigration(upAddExpencesNotes, downAddExpencesNotes)
+}
+
+func upAddExpencesNotes(tx *sql.Tx) error {
+	// GIN-индекс для отчётов по метке: tags @> ARRAY['vacation']
+	const query = `
+	ALTER TABLE expences
+		ADD COLUMN note text NOT NULL DEFAULT '',
+		ADD COLUMN tags text[] NOT NULL DEFAULT '{}';
+	CREATE INDEX expences_tags_idx ON expences USING GIN (tags);
+	`
+
+	_, err := tx.Exec(query)
+
+	return err
+}
+
+func downAddExpencesNotes(tx *sql.Tx) error {
+	const query = `
+	DROP INDEX expences_tags_idx;
+	ALTER TABLE expences
+		DROP COLUMN note,
+		DROP COLUMN tags;
+	`
+	_, err := tx.Exec(query)
+	return err
+}
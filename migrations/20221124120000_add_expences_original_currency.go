@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigration(upAddExpencesOriginalCurrency, downAddExpencesOriginalCurrency)
+}
+
+func upAddExpencesOriginalCurrency(tx *sql.Tx) error {
+	// валюта и сумма, в которых трата была введена; total остаётся в системной валюте.
+	// NULL у трат, добавленных до появления колонок
+	const query = `
+	ALTER TABLE expences
+		ADD COLUMN currency_id smallint REFERENCES currency (id),
+		ADD COLUMN original_total bigint;
+	`
+
+	_, err := tx.Exec(query)
+
+	return err
+}
+
+func downAddExpencesOriginalCurrency(tx *sql.Tx) error {
+	const query = `
+	ALTER TABLE expences
+		DROP COLUMN currency_id,
+		DROP COLUMN original_total;
+	`
+	_, err := tx.Exec(query)
+	return err
+}
@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigration(upInitCurrencyRates, downInitCurrencyRates)
+}
+
+func upInitCurrencyRates(tx *sql.Tx) error {
+	// история курсов по дням; currency.rate остаётся последним известным курсом.
+	// текущие курсы становятся первой записью истории
+	const query = `
+	CREATE TABLE currency_rates
+	(
+		currency_id smallint NOT NULL REFERENCES currency (id),
+		date date NOT NULL,
+		rate real NOT NULL,
+		PRIMARY KEY (currency_id, date)
+	);
+
+	INSERT INTO currency_rates (currency_id, date, rate)
+	SELECT id, CURRENT_DATE, rate FROM currency;
+	`
+
+	_, err := tx.Exec(query)
+
+	return err
+}
+
+func downInitCurrencyRates(tx *sql.Tx) error {
+	const query = `
+	drop table currency_rates;
+	`
+	_, err := tx.Exec(query)
+	return err
+}
@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigration(upAddUsersTimezone, downAddUsersTimezone)
+}
+
+func upAddUsersTimezone(tx *sql.Tx) error {
+	// timezone - часовой пояс пользователя (Europe/Berlin), пустой - время сервера.
+	// limit_month - первый день месяца по времени пользователя, за который считается current_month_limit;
+	// у существующих пользователей - текущий месяц, чтобы лимиты не сбросились после миграции
+	const query = `
+	ALTER TABLE users
+		ADD COLUMN timezone text NOT NULL DEFAULT '',
+		ADD COLUMN limit_month date;
+
+	UPDATE users SET limit_month = date_trunc('month', now())::date;
+	`
+
+	_, err := tx.Exec(query)
+
+	return err
+}
+
+func downAddUsersTimezone(tx *sql.Tx) error {
+	const query = `
+	ALTER TABLE users
+		DROP COLUMN timezone,
+		DROP COLUMN limit_month;
+	`
+	_, err := tx.Exec(query)
+	return err
+}
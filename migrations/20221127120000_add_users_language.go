@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigration(upAddUsersLanguage, downAddUsersLanguage)
+}
+
+func upAddUsersLanguage(tx *sql.Tx) error {
+	// language - язык ответов бота (en, ru), пустой - язык из настроек Telegram
+	const query = `
+	ALTER TABLE users
+		ADD COLUMN language text NOT NULL DEFAULT '';
+	`
+
+	_, err := tx.Exec(query)
+
+	return err
+}
+
+func downAddUsersLanguage(tx *sql.Tx) error {
+	const query = `
+	ALTER TABLE users
+		DROP COLUMN language;
+	`
+	_, err := tx.Exec(query)
+	return err
+}
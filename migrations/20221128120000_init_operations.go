@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigration(upInitOperations, downInitOperations)
+}
+
+func upInitOperations(tx *sql.Tx) error {
+	// журнал изменений бюджета для /undo: object_id - добавленная трата или категория, либо прежняя валюта;
+	// month_limit - прежний лимит месяца, month_limit_delta - на сколько изменился остаток лимита
+	const query = `
+	CREATE TABLE operations
+	(
+		id bigint PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+		user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+		kind text NOT NULL,
+		object_id bigint NOT NULL DEFAULT 0,
+		month_limit bigint NOT NULL DEFAULT 0,
+		month_limit_delta bigint NOT NULL DEFAULT 0,
+		ts timestamp NOT NULL DEFAULT now()
+	);
+	CREATE INDEX operations_user_idx ON operations (user_id, id);
+	`
+
+	_, err := tx.Exec(query)
+
+	return err
+}
+
+func downInitOperations(tx *sql.Tx) error {
+	const query = `
+	DROP INDEX operations_user_idx;
+	DROP TABLE operations;
+	`
+	_, err := tx.Exec(query)
+	return err
+}
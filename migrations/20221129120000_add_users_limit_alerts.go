@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigration(upAddUsersLimitAlerts, downAddUsersLimitAlerts)
+}
+
+func upAddUsersLimitAlerts(tx *sql.Tx) error {
+	// limit_alerts - пороги уведомлений в процентах лимита месяца;
+	// limit_alert_fired - наибольший порог, о котором уже уведомили в текущем месяце
+	const query = `
+	ALTER TABLE users
+		ADD COLUMN limit_alerts integer[] NOT NULL DEFAULT '{50,80,100}',
+		ADD COLUMN limit_alert_fired integer NOT NULL DEFAULT 0;
+	`
+
+	_, err := tx.Exec(query)
+
+	return err
+}
+
+func downAddUsersLimitAlerts(tx *sql.Tx) error {
+	const query = `
+	ALTER TABLE users
+		DROP COLUMN limit_alerts,
+		DROP COLUMN limit_alert_fired;
+	`
+	_, err := tx.Exec(query)
+	return err
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigration(upAddUsersSoftLimit, downAddUsersSoftLimit)
+}
+
+func upAddUsersSoftLimit(tx *sql.Tx) error {
+	// soft_limit - мягкий режим лимита: траты сверх лимита сохраняются с предупреждением, а не отклоняются
+	const query = `
+	ALTER TABLE users
+		ADD COLUMN soft_limit boolean NOT NULL DEFAULT false;
+	`
+
+	_, err := tx.Exec(query)
+
+	return err
+}
+
+func downAddUsersSoftLimit(tx *sql.Tx) error {
+	const query = `
+	ALTER TABLE users
+		DROP COLUMN soft_limit;
+	`
+	_, err := tx.Exec(query)
+	return err
+}
@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigration(upAddOperationsMember, downAddOperationsMember)
+}
+
+func upAddOperationsMember(tx *sql.Tx) error {
+	// member_id - участник бюджета, сделавший изменение; /undo отменяет только свои изменения.
+	// Прежние записи считаются изменениями владельца бюджета
+	const query = `
+	ALTER TABLE operations ADD COLUMN member_id bigint;
+	UPDATE operations SET member_id = user_id;
+	ALTER TABLE operations ALTER COLUMN member_id SET NOT NULL;
+	DROP INDEX operations_user_idx;
+	CREATE INDEX operations_member_idx ON operations (user_id, member_id, id);
+	`
+
+	_, err := tx.Exec(query)
+
+	return err
+}
+
+func downAddOperationsMember(tx *sql.Tx) error {
+	const query = `
+	DROP INDEX operations_member_idx;
+	CREATE INDEX operations_user_idx ON operations (user_id, id);
+	ALTER TABLE operations DROP COLUMN member_id;
+	`
+	_, err := tx.Exec(query)
+	return err
+}
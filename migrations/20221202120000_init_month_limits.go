@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigration(upInitMonthLimits, downInitMonthLimits)
+}
+
+func upInitMonthLimits(tx *sql.Tx) error {
+	// история лимитов закончившихся месяцев: month - первый день месяца по времени пользователя,
+	// month_limit - лимит месяца, remainder - остаток на конец месяца (отрицательный - лимит превышен)
+	const query = `
+	CREATE TABLE month_limits
+	(
+		user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+		month date NOT NULL,
+		month_limit bigint NOT NULL,
+		remainder bigint NOT NULL,
+		PRIMARY KEY (user_id, month)
+	);
+	`
+
+	_, err := tx.Exec(query)
+
+	return err
+}
+
+func downInitMonthLimits(tx *sql.Tx) error {
+	const query = `
+	DROP TABLE month_limits;
+	`
+	_, err := tx.Exec(query)
+	return err
+}